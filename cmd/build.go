@@ -24,12 +24,18 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var (
+	maxParallel int
+	buildArgs   []string
+)
+
 func init() {
 	buildPr.Flags().StringVar(&src, "src", "", "Source branch")
 	buildPr.Flags().StringVar(&dst, "dst", "", "Destination branch")
 
 	buildDiff.Flags().StringVar(&from, "from", "", "From commit")
 	buildDiff.Flags().StringVar(&to, "to", "", "To commit")
+	buildDiff.Flags().StringVar(&sinceTag, "since-tag", "", "Use the latest tag satisfying this version constraint as from commit")
 
 	buildLocal.Flags().BoolVarP(&all, "all", "a", false, "All modules")
 	buildLocal.Flags().StringVarP(&name, "name", "n", "", "Build modules with a name that matches this value. Multiple names can be specified as a comma separated string.")
@@ -45,6 +51,10 @@ func init() {
 	buildHead.Flags().StringVarP(&name, "name", "n", "", "Build modules with a name that matches this value. Multiple names can be specified as a comma separated string.")
 	buildHead.Flags().BoolVarP(&fuzzy, "fuzzy", "f", false, "Use fuzzy match when filtering")
 
+	buildCommand.PersistentFlags().IntVar(&maxParallel, "max-parallel", 1, "Maximum number of modules to build in parallel")
+	buildCommand.PersistentFlags().BoolVar(&prefixOutput, "prefix-output", false, "Prefix each line of the build output with the module name")
+	buildCommand.PersistentFlags().StringArrayVar(&buildArgs, "build-arg", nil, "Argument appended to the build command of each module (can be repeated)")
+
 	buildCommand.AddCommand(buildBranch)
 	buildCommand.AddCommand(buildPr)
 	buildCommand.AddCommand(buildDiff)
@@ -57,7 +67,7 @@ func init() {
 var buildHead = &cobra.Command{
 	Use: "head",
 	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
-		return summarise(system.BuildCurrentBranch(&lib.FilterOptions{Name: name, Fuzzy: fuzzy}, lib.CmdOptionsWithStdIO(buildStageCB)))
+		return summarise(system.BuildCurrentBranch(&lib.FilterOptions{Name: name, Fuzzy: fuzzy}, buildCmdOptions()))
 	}),
 }
 
@@ -69,7 +79,7 @@ var buildBranch = &cobra.Command{
 			branch = args[0]
 		}
 
-		return summarise(system.BuildBranch(branch, &lib.FilterOptions{Name: name, Fuzzy: fuzzy}, lib.CmdOptionsWithStdIO(buildStageCB)))
+		return summarise(system.BuildBranch(branch, &lib.FilterOptions{Name: name, Fuzzy: fuzzy}, buildCmdOptions()))
 	}),
 }
 
@@ -84,14 +94,18 @@ var buildPr = &cobra.Command{
 			return errors.New("requires dest")
 		}
 
-		return summarise(system.BuildPr(src, dst, lib.CmdOptionsWithStdIO(buildStageCB)))
+		return summarise(system.BuildPr(src, dst, buildCmdOptions()))
 	}),
 }
 
 var buildDiff = &cobra.Command{
 	Use: "diff --from <sha> --to <sha>",
 	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
-		if from == "" {
+		if from != "" && sinceTag != "" {
+			return errors.New("from commit and since-tag cannot be used together")
+		}
+
+		if from == "" && sinceTag == "" {
 			return errors.New("requires from commit")
 		}
 
@@ -99,7 +113,11 @@ var buildDiff = &cobra.Command{
 			return errors.New("requires to commit")
 		}
 
-		return summarise(system.BuildDiff(from, to, lib.CmdOptionsWithStdIO(buildStageCB)))
+		if sinceTag != "" {
+			return summarise(system.BuildSinceTag(sinceTag, to, buildCmdOptions()))
+		}
+
+		return summarise(system.BuildDiff(from, to, buildCmdOptions()))
 	}),
 }
 
@@ -113,9 +131,9 @@ var buildCommit = &cobra.Command{
 		commit := args[0]
 
 		if content {
-			return summarise(system.BuildCommitContent(commit, lib.CmdOptionsWithStdIO(buildStageCB)))
+			return summarise(system.BuildCommitContent(commit, buildCmdOptions()))
 		}
-		return summarise(system.BuildCommit(commit, &lib.FilterOptions{Name: name, Fuzzy: fuzzy}, lib.CmdOptionsWithStdIO(buildStageCB)))
+		return summarise(system.BuildCommit(commit, &lib.FilterOptions{Name: name, Fuzzy: fuzzy}, buildCmdOptions()))
 	}),
 }
 
@@ -123,10 +141,10 @@ var buildLocal = &cobra.Command{
 	Use: "local [--all]",
 	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
 		if all || name != "" {
-			return summarise(system.BuildWorkspace(&lib.FilterOptions{Name: name, Fuzzy: fuzzy}, lib.CmdOptionsWithStdIO(buildStageCB)))
+			return summarise(system.BuildWorkspace(&lib.FilterOptions{Name: name, Fuzzy: fuzzy}, buildCmdOptions()))
 		}
 
-		return summarise(system.BuildWorkspaceChanges(lib.CmdOptionsWithStdIO(buildStageCB)))
+		return summarise(system.BuildWorkspaceChanges(buildCmdOptions()))
 	}),
 }
 
@@ -139,6 +157,16 @@ func buildStageCB(a *lib.Module, s lib.CmdStage, err error) {
 	}
 }
 
+func buildCmdOptions() *lib.CmdOptions {
+	options := lib.CmdOptionsWithStdIO(buildStageCB)
+	options.MaxParallel = maxParallel
+	options.ExtraArgs = buildArgs
+	if prefixOutput {
+		options.OutputPrefix = lib.ModuleNamePrefix
+	}
+	return options
+}
+
 func summarise(summary *lib.BuildSummary, err error) error {
 	if err == nil {
 		logrus.Infof("Modules: %v Built: %v Skipped: %v",
@@ -27,14 +27,21 @@ import (
 )
 
 var (
-	toJSON     bool
-	toGraph    bool
-	dependents bool
+	toJSON             bool
+	toGraph            bool
+	toGitHubMatrix     bool
+	toMermaid          bool
+	mermaidDirection   string
+	matrixSkipSentinel bool
+	dependents         bool
+	buildableOnly      bool
+	entryPoints        bool
 )
 
 func init() {
 	describePrCmd.Flags().StringVar(&src, "src", "", "Source branch")
 	describePrCmd.Flags().StringVar(&dst, "dst", "", "Destination branch")
+	describePrCmd.Flags().BoolVar(&entryPoints, "entry-points", false, "Output just the modules changed directly, excluding the modules requiring them")
 
 	describeIntersectionCmd.Flags().StringVar(&kind, "kind", "", "Kind of input for first and second args (available options are 'branch' and 'commit')")
 	describeIntersectionCmd.Flags().StringVar(&first, "first", "", "First item")
@@ -42,6 +49,8 @@ func init() {
 
 	describeDiffCmd.Flags().StringVar(&from, "from", "", "From commit")
 	describeDiffCmd.Flags().StringVar(&to, "to", "", "To commit")
+	describeDiffCmd.Flags().BoolVar(&entryPoints, "entry-points", false, "Output just the modules changed directly, excluding the modules requiring them")
+	describeDiffCmd.Flags().StringVar(&sinceTag, "since-tag", "", "Use the latest tag satisfying this version constraint as from commit")
 
 	describeLocalCmd.Flags().BoolVarP(&all, "all", "a", false, "Describe all")
 
@@ -52,7 +61,12 @@ func init() {
 
 	describeCmd.PersistentFlags().BoolVar(&toJSON, "json", false, "Format output as json")
 	describeCmd.PersistentFlags().BoolVar(&toGraph, "graph", false, "Format output as dot graph")
+	describeCmd.PersistentFlags().BoolVar(&toMermaid, "mermaid", false, "Format output as a mermaid flowchart")
+	describeCmd.PersistentFlags().StringVar(&mermaidDirection, "direction", "LR", "Direction of the mermaid flowchart (available options are 'TB', 'TD', 'BT', 'RL' and 'LR')")
+	describeCmd.PersistentFlags().BoolVar(&toGitHubMatrix, "github-matrix", false, "Format output as a GitHub Actions matrix")
+	describeCmd.PersistentFlags().BoolVar(&matrixSkipSentinel, "matrix-skip-sentinel", false, "Output a matrix entry with skip set to true when there are no modules")
 	describeCmd.PersistentFlags().BoolVar(&dependents, "dependents", false, "Output dependents on potential change")
+	describeCmd.PersistentFlags().BoolVar(&buildableOnly, "buildable", false, "Output only the modules that can be built in current operating system")
 
 	describeCmd.AddCommand(describeCommitCmd)
 	describeCmd.AddCommand(describeBranchCmd)
@@ -61,6 +75,7 @@ func init() {
 	describeCmd.AddCommand(describePrCmd)
 	describeCmd.AddCommand(describeIntersectionCmd)
 	describeCmd.AddCommand(describeDiffCmd)
+	describeCmd.AddCommand(describeBlastRadiusCmd)
 
 	RootCmd.AddCommand(describeCmd)
 }
@@ -83,7 +98,7 @@ var describeBranchCmd = &cobra.Command{
 			return err
 		}
 
-		m, err = m.ApplyFilters(&lib.FilterOptions{Name: name, Fuzzy: fuzzy, Dependents: dependents})
+		m, err = m.ApplyFilters(&lib.FilterOptions{Name: name, Fuzzy: fuzzy, Dependents: dependents, BuildableOnly: buildableOnly})
 
 		if err != nil {
 			return err
@@ -101,7 +116,7 @@ var describeHeadCmd = &cobra.Command{
 			return err
 		}
 
-		m, err = m.ApplyFilters(&lib.FilterOptions{Name: name, Fuzzy: fuzzy, Dependents: dependents})
+		m, err = m.ApplyFilters(&lib.FilterOptions{Name: name, Fuzzy: fuzzy, Dependents: dependents, BuildableOnly: buildableOnly})
 
 		if err != nil {
 			return err
@@ -121,12 +136,6 @@ var describeLocalCmd = &cobra.Command{
 
 		if all {
 			m, err = system.ManifestByWorkspace()
-
-			if err != nil {
-				return err
-			}
-
-			m, err = m.ApplyFilters(&lib.FilterOptions{Name: name, Fuzzy: fuzzy, Dependents: dependents})
 		} else {
 			m, err = system.ManifestByWorkspaceChanges()
 		}
@@ -135,6 +144,12 @@ var describeLocalCmd = &cobra.Command{
 			return err
 		}
 
+		m, err = m.ApplyFilters(&lib.FilterOptions{Name: name, Fuzzy: fuzzy, Dependents: dependents, BuildableOnly: buildableOnly})
+
+		if err != nil {
+			return err
+		}
+
 		return output(m.Modules)
 	}),
 }
@@ -155,13 +170,13 @@ var describePrCmd = &cobra.Command{
 			return err
 		}
 
-		m, err = m.ApplyFilters(&lib.FilterOptions{Name: name, Fuzzy: fuzzy, Dependents: dependents})
+		m, err = m.ApplyFilters(&lib.FilterOptions{Name: name, Fuzzy: fuzzy, Dependents: dependents, BuildableOnly: buildableOnly})
 
 		if err != nil {
 			return err
 		}
 
-		return output(m.Modules)
+		return output(changedModules(m))
 	}),
 }
 
@@ -189,7 +204,7 @@ var describeCommitCmd = &cobra.Command{
 			return err
 		}
 
-		m, err = m.ApplyFilters(&lib.FilterOptions{Name: name, Fuzzy: fuzzy, Dependents: dependents})
+		m, err = m.ApplyFilters(&lib.FilterOptions{Name: name, Fuzzy: fuzzy, Dependents: dependents, BuildableOnly: buildableOnly})
 
 		if err != nil {
 			return err
@@ -237,7 +252,11 @@ var describeIntersectionCmd = &cobra.Command{
 var describeDiffCmd = &cobra.Command{
 	Use: "diff --from <commit> --to <commit>",
 	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
-		if from == "" {
+		if from != "" && sinceTag != "" {
+			return errors.New("from commit and since-tag cannot be used together")
+		}
+
+		if from == "" && sinceTag == "" {
 			return errors.New("requires from commit")
 		}
 
@@ -245,39 +264,74 @@ var describeDiffCmd = &cobra.Command{
 			return errors.New("requires to commit")
 		}
 
-		m, err := system.ManifestByDiff(from, to)
+		var m *lib.Manifest
+		var err error
+		if sinceTag != "" {
+			m, err = system.ManifestSinceTag(sinceTag, to)
+		} else {
+			m, err = system.ManifestByDiff(from, to)
+		}
 		if err != nil {
 			return err
 		}
 
-		m, err = m.ApplyFilters(&lib.FilterOptions{Name: name, Fuzzy: fuzzy, Dependents: dependents})
+		m, err = m.ApplyFilters(&lib.FilterOptions{Name: name, Fuzzy: fuzzy, Dependents: dependents, BuildableOnly: buildableOnly})
 
 		if err != nil {
 			return err
 		}
 
-		return output(m.Modules)
+		return output(changedModules(m))
+	}),
+}
+
+var describeBlastRadiusCmd = &cobra.Command{
+	Use: "blast-radius <sha> <path>",
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		if len(args) < 2 {
+			return errors.New("requires the commit sha and the file path")
+		}
+
+		mods, err := system.BlastRadius(args[0], args[1])
+		if err != nil {
+			return err
+		}
+
+		return output(mods)
 	}),
 }
 
 const columnWidth = 30
 
+// changedModules returns the modules of a manifest created from
+// changes to be described, considering the entry points option.
+func changedModules(m *lib.Manifest) lib.Modules {
+	if entryPoints {
+		return m.EntryPoints()
+	}
+
+	return m.Modules
+}
+
 func output(mods lib.Modules) error {
 	if toJSON {
-		m := make(map[string]map[string]interface{})
-		for _, a := range mods {
-			v := make(map[string]interface{})
-			v["Name"] = a.Name()
-			v["Path"] = a.Path()
-			v["Version"] = a.Version()
-			v["Properties"] = a.Properties()
-			m[a.Name()] = v
-		}
-		buff, err := json.MarshalIndent(m, "", "  ")
+		buff, err := json.MarshalIndent(lib.NewModuleList(mods), "", "  ")
 		if err != nil {
 			return err
 		}
 		fmt.Println(string(buff))
+	} else if toGitHubMatrix {
+		buff, err := mods.ToGitHubMatrixWithOptions(&lib.GitHubMatrixOptions{SkipSentinel: matrixSkipSentinel})
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(buff))
+	} else if toMermaid {
+		out, err := mods.ToMermaid(mermaidDirection)
+		if err != nil {
+			return err
+		}
+		fmt.Print(out)
 	} else if toGraph {
 		if dependents {
 			fmt.Println(mods.GroupedSerializeAsDot())
@@ -31,24 +31,48 @@ A collection of modules stored in a repository is called a manifest.
 
 You can turn any directory into a module by placing a spec file called {{c ".mbt.yml"}}.
 Spec file is written in {{c "yaml" }} following the schema specified below.
+Alternatively, the spec can be written in {{c "json"}} in a file called {{c ".mbt.json"}}
+using the same schema. A directory cannot contain both files.
 
 {{c "" }}
 name: Unique module name (required)
+id: Unique immutable module id that survives renames (optional, defaults to name)
 build: Dictionary of build commands specific to a platform (optional)
   default: (optional)
     cmd: Default command to run when os specific command is not found (required)
     args: Array of arguments to default build command (optional)
+    successCodes: Array of exit codes treated as success (optional, default [0])
+    skipCodes: Array of exit codes treated as skipped (optional)
   linux|darwin|windows:
     cmd: Operating system specific command name (required)
     args: Array of arguments (optional)
-dependencies: An array of modules that this module's build depend on (optional)
+    successCodes: Array of exit codes treated as success (optional, default [0])
+    skipCodes: Array of exit codes treated as skipped (optional)
+dependencies: An array of modules (names or paths) that this module's build depend on (optional)
+dependenciesFile: Path to a file listing additional dependencies relative to the module directory (optional)
 fileDependencies: An array of file names that this module's build depend on (optional)
 commands: Optional dictionary of custom commands (optional)
-  name:
+  name: Command name other than build, which is reserved for the build section
     cmd: Command name (required)
     args: Array of arguments (optional)
     os: Array of os identifiers where this command should run (optional)
+    successCodes: Array of exit codes treated as success (optional, default [0])
+    skipCodes: Array of exit codes treated as skipped (optional)
 properties: Custom dictionary to hold any module specific information (optional)
+inherit: An array of property keys inherited from the parent module when not defined (optional)
+parent: Dependency to inherit the properties from (optional, required with multiple dependencies)
+resource: Name of a shared resource used by the build (optional)
+versionExtensions: An array of file extensions contributing to the version (optional)
+envFile: Env file loaded into the environment of commands (optional)
+  path: Path to the file relative to the module directory (required)
+  required: Fail if the file does not exist (optional)
+deprecated: Warn when the module is built or changed (optional)
+deprecationMessage: Message included in the deprecation warning (optional)
+conflictsWith: An array of modules that must not be changed along with this module (optional)
+artifacts: An array of files expected to be produced by the build (optional)
+  - path: Path to the file relative to the module directory (required)
+    sha256: Expected SHA256 checksum of the file (optional)
+enabledWhen: Omit the module unless this value is true after expanding environment variables (optional)
 {{c ""}}
 
 {{h2 "Build Command"}}
@@ -61,6 +85,172 @@ found {{link "here" "https://golang.org/doc/install/source#environment"}}
 When the command is applicable for multiple operating systems, you could list it as
 the default command. Operating system specific commands take precedence.
 
+Commands are executed directly with the specified arguments and not through a
+shell. Therefore, shell features such as pipes and redirections are not available
+unless the command explicitly invokes a shell (e.g. {{c "cmd: sh"}} with
+{{c "args: [-c, 'make | tee build.log']"}}).
+
+Other steps (e.g. a {{c "healthcheck"}} used by deployment tools) can be
+declared in the {{c "commands"}} section under any name. They are executed
+with {{c "mbt run-in"}} and are available to the tools using mbt as a library
+alongside the build commands.
+
+{{h2 "Exit Codes"}}
+The exit code of a command is classified as success, skipped or failed.
+By default, 0 is a success and any other code is a failure. Commands can
+override this behaviour by declaring {{c "successCodes"}} and {{c "skipCodes"}}.
+
+{{c ""}}
+build:
+  default:
+    cmd: ./build.sh
+    skipCodes: [2]
+{{c ""}}
+
+Modules exiting with a skip code are reported as skipped in the same way as
+modules without a command for the host platform.
+
+{{h2 "Artifacts"}}
+Files listed in {{c "artifacts"}} are verified after the build command of the
+module succeeds. Build fails if any of them does not exist or its SHA256
+checksum does not match the optional {{c "sha256"}} value.
+Paths are relative to the module directory and must not point outside of it.
+
+{{c ""}}
+artifacts:
+  - path: bin/app
+  - path: dist/app.tar.gz
+    sha256: 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+{{c ""}}
+
+{{h2 "Feature Flags"}}
+Modules under development can be kept out of all commands by declaring
+an {{c "enabledWhen"}} value referring to an environment variable.
+
+{{c ""}}
+name: app-a
+enabledWhen: ${FEATURE_APP_A}
+{{c ""}}
+
+The module is discovered only when the value expands to {{c "true"}}
+(or any other value accepted by Go's {{c "strconv.ParseBool"}}).
+It is an error for an enabled module to depend on a disabled module.
+
+{{h2 "Parallel Builds"}}
+Modules can be built in parallel by specifying the {{c "--max-parallel"}} option
+of {{c "mbt build"}} commands. A module is built only after all of its dependencies
+are built.
+
+Some builds contend for the same resource (e.g. a shared integration database).
+Modules declaring the same {{c "resource"}} are never built at the same time
+even when the dependency graph would allow it.
+
+When several modules are ready to be built, the ones with a lower
+{{c "priority"}} property (an integer, defaults to 0) are started first.
+
+Resource intensive modules (e.g. compilation) can declare a {{c "weight"}}
+(an integer, defaults to 1). {{c "--max-parallel"}} is then the maximum total
+weight of the modules built at the same time. A module heavier than
+{{c "--max-parallel"}} is built once no other module is running.
+
+{{h2 "Discovery Roots"}}
+In a large repository, discovery can be restricted to a set of directories
+by specifying the {{c "--root"}} option (e.g. {{c "--root services"}}).
+Modules and changes outside those directories are ignored.
+The option can be repeated to specify multiple roots.
+
+{{c "--max-discovery-depth"}} option ignores the modules nested in more than
+the specified number of directories from the repository root (e.g. with
+{{c "--max-discovery-depth 2"}}, {{c "services/app-a"}} is discovered but
+{{c "services/legacy/app-b"}} is not). Use {{c "0"}} to discover just the module
+at the repository root. Modules at any depth are discovered by default
+({{c "-1"}}).
+
+{{h2 "Change Scope"}}
+Use {{c "--scope"}} option to consider just the changes in a set of directories
+(e.g. {{c "--scope services"}}). Unlike {{c "--root"}}, it does not restrict
+discovery. Therefore, a module outside the scope is still impacted when a
+module it depends on is changed within the scope.
+The option can be repeated to specify multiple directories.
+
+{{h2 "Merge Base"}}
+Commands comparing two branches or commits ({{c "pr"}}, {{c "diff"}} and
+{{c "intersection"}}) consider the changes since the merge base of them.
+By default, merge base is the best common ancestor as reported by
+{{c "git merge-base"}}. When the base branch contains merges, that could be
+a commit merged into the base branch rather than a commit of the base branch itself.
+
+Specify {{c "--first-parent"}} to find the merge base along the first parent
+history of the base branch instead. That is the most recent commit of the
+base branch (ignoring the commits brought in by merges) contained in the
+other branch.
+
+For example, consider a {{c "release"}} branch created from {{c "C1"}}
+of {{c "master"}}. A {{c "side"}} branch is also created from {{c "C1"}} and
+it has a commit {{c "S1"}} which is later merged into {{c "release"}}.
+A {{c "feature"}} branch is then created from {{c "S1"}} and it has a commit {{c "F1"}}.
+
+{{c ""}}
+release: C1 -- R1 -- M
+                    /
+side:    C1 ------ S1 -- F1 (feature)
+{{c ""}}
+
+{{c "mbt build pr --src feature --dst release"}} uses {{c "S1"}} as the merge base
+and builds the modules changed in {{c "F1"}}. With {{c "--first-parent"}}, merge
+base is {{c "C1"}} because that is the last commit of {{c "release"}} the
+{{c "feature"}} branch diverged from. Therefore, the modules changed in
+{{c "S1"}} are also built.
+
+Merge base cannot be found in a shallow clone (e.g. {{c "git clone --depth 1"}})
+when the history is truncated before it. mbt fails with an error suggesting
+to fetch the missing history in that case. Specify {{c "--unshallow"}} to run
+{{c "git fetch --unshallow"}} automatically and retry instead.
+
+{{h2 "Renames"}}
+By default, a file moved from one module to another is reported as a deletion
+in the first and an addition in the second. Specify {{c "--rename-threshold"}}
+with a similarity percentage (e.g. {{c "50"}}) to detect such changes as renames
+and {{c "--copy-threshold"}} to detect copies of modified files.
+Both source and destination of a rename or a copy are considered changed.
+
+{{h2 "Fan Out Limit"}}
+A change in a foundational module could trigger the build of a large number
+of modules requiring it. Use {{c "--max-fan-out"}} option to get a warning
+when a changed module impacts more than the specified number of modules
+(including itself). Specify {{c "--strict-fan-out"}} to fail instead.
+
+Use {{c "--max-impact-depth"}} option to restrict the impacted modules to the
+ones within the specified number of dependencies from a changed module. For
+example, {{c "--max-impact-depth 1"}} includes just the modules directly
+requiring a changed module and {{c "--max-impact-depth 0"}} includes just the
+changed modules. Default value {{c "-1"}} includes all modules requiring it
+transitively.
+
+{{h2 "Command Changes"}}
+By default, a change in the spec of a module impacts the modules requiring it.
+Specify {{c "--isolate-command-changes"}} to build just the module itself when
+its spec is the only file changed and the changes are limited to the
+{{c "build"}} and {{c "commands"}} sections (e.g. a new build argument).
+This applies to {{c "diff"}} and {{c "pr"}} commands. Versions of the modules
+requiring it still change.
+
+{{h2 "Excluded Directories"}}
+All changes mark the modules containing them as changed by default.
+Changes in generated directories (e.g. {{c "node_modules"}} or {{c "dist"}})
+can be ignored by specifying one or more {{c "--exclude"}} options.
+A pattern without a slash matches any directory with that name. Other patterns
+match the leading directories of the changed path (e.g. {{c "app-a/build"}}).
+Be careful when excluding directories with source code such as {{c "vendor"}},
+since changes to them no longer trigger builds.
+
+Changes to the file dependencies of a module are never excluded.
+
+{{h2 "Mutually Exclusive Modules"}}
+Modules listed in {{c "conflictsWith"}} of a module must never be built together
+with it (e.g. two implementations of the same service). Commands evaluating
+changes (e.g. {{c "mbt build diff"}}) fail when both modules are impacted.
+
 {{h2 "Dependencies"}}
 {{ c "mbt"}} comes with a set of primitives to manage build dependencies. Current build
 tools do a good job in managing dependencies between source files/projects.
@@ -79,10 +269,29 @@ It accepts an array of module names.
 For example, {{ c "module-a" }} could define a dependency on {{c "module-b" }},
 so that any time {{c "module-b"}} is changed, build command for {{c "module-a" }} is also executed.
 
+Dependencies containing a {{c "/"}} are treated as paths relative to the root
+of the repository (e.g. {{c "libs/module-b"}}) and refer to the module in that
+directory. Names and paths can be mixed in the same list.
+
 An example of where this could be useful is, shared libraries. Shared library
 could be developed independently of its consumers. However, all consumers
 are automatically built whenever the shared library is modified.
 
+Modules in other repositories can be declared under {{c "externalDependencies"}}
+property. They are ignored when a single repository is discovered and resolved
+by name when the modules of multiple repositories are merged into one graph
+with {{c "lib.MergeGraphs"}}, which recalculates the versions with the
+specified version hash algorithm. It is an error for the same module name to be
+defined in more than one of the merged repositories.
+
+Dependencies generated by other tools (e.g. from the lock file of a build
+system) can be listed in a file specified with {{c "dependenciesFile"}}
+property. Each line of the file contains the name or the path of a module.
+Blank lines and the lines starting with {{c "#"}} are ignored. These dependencies
+are merged with the ones in {{c "dependencies"}} property. The file is also
+treated as a file dependency, therefore, changing it changes the version of
+the module.
+
 {{h2 "File Dependencies"}}
 File dependencies are useful in situations where a module should be built
 when a file(s) stored outside the module directory is modified. For instance,
@@ -92,6 +301,17 @@ dependency in order to trigger the build whenever there's a change in build.
 File dependencies should specify the path of the file relative to the root
 of the repository.
 
+{{h2 "Property Inheritance"}}
+Modules can inherit properties from one of their dependencies by listing the
+keys in {{c "inherit"}} property. A module with a single dependency inherits from
+that module. Otherwise, the dependency should be specified in {{c "parent"}}
+property, and it is an error not to.
+
+Properties defined in the module always take precedence over the inherited
+ones. Parent properties include the properties it inherits, therefore, a key
+is inherited transitively as long as it is listed in {{c "inherit"}} property
+of each module in between.
+
 {{h2 "Module Version"}}
 For each module stored within a repository, {{c "mbt"}} generates a unique
 stable version string. It is calculated based on three source attributes in
@@ -105,6 +325,19 @@ As a result, version of a module is changed only when any of those attributes
 are changed making it a safe attribute to use for tagging the 
 build artifacts (i.e. tar balls, container images).
 
+Versions are sha1 hashes by default. Specify {{c "--version-hash sha256"}} to
+calculate them with sha256 instead. Such versions are prefixed with {{c "sha256-"}}.
+
+Modules containing assets or documentation alongside the code can restrict
+the files contributing to the version by specifying {{c "versionExtensions"}}
+(e.g. {{c "[.go, .proto]"}}). Changes to other files within the module
+directory neither change the version nor trigger a build.
+Spec file is always considered.
+
+Submodules within the module directory contribute the commit they point to.
+Therefore, updating a submodule changes the version of the module even though
+no file in the repository is changed.
+
 {{h2 "Document Generation"}}
 {{ c "mbt" }} has a powerful feature that exposes the module state inferred from
 the repository to a template engine. This could be quite useful for generating
@@ -205,6 +438,12 @@ match by using {{c "--fuzzy"}} option.
 Build modules changed between {{c "from"}} and {{c "to"}} commits.
 In this mode, mbt works out the merge base between {{c "from"}} and {{c "to"}} and
 evaluates the modules changed between the merge base and {{c "to"}}.
+Commits can be specified with revision expressions such as {{c "HEAD~1"}},
+{{c "HEAD^"}} or {{c "master~2"}}.
+Use {{c "--since-tag <constraint>"}} instead of {{c "--from"}} to diff against
+the latest tag reachable from {{c "to"}} with a semantic version satisfying the
+constraint (e.g. {{c ">=1.0.0"}}). Prerelease tags such as {{c "v1.0.0-rc1"}} are
+ignored unless the constraint refers to a prerelease.
 
 {{c "mbt build head [--content] [--name <name>] [--fuzzy]"}}{{br}}
 Build modules in current head.
@@ -224,19 +463,40 @@ Build just the modules matching the {{c "--name"}} filter if specified.
 Default {{c "--name"}} filter is a prefix match. You can change this to a subsequence
 match by using {{c "--fuzzy"}} option.
 
+{{c "--max-parallel <n>"}} option can be used with any of the build commands above
+to build up to {{c "n"}} modules at the same time.
+
+{{c "--prefix-output"}} option prefixes each line of the build output with the
+name of the module producing it (e.g. {{c "[app-a] "}}). Lines of a module are
+never interleaved with the output of another module.
+
+{{c "--build-arg <arg>"}} option appends an argument to the build command of
+each module (e.g. {{c "--build-arg=--no-cache"}}). It can be repeated to append
+several arguments in order. These arguments are not part of the module
+specification and therefore do not change the module versions.
+
 {{h2 "Build Environment"}}
 
 When executing build, following environment variables are initialised and can be
 used by the command being executed.
 
 - {{c "MBT_MODULE_NAME"}} Name of the module
+- {{c "MBT_MODULE_ID"}} Stable id of the module (defaults to the name)
 - {{c "MBT_MODULE_PATH"}} Relative path to the module directory
 - {{c "MBT_MODULE_VERSION"}} Module version
 - {{c "MBT_BUILD_COMMIT"}} Git commit SHA of the commit being built
 - {{c "MBT_REPO_PATH"}} Absolute path to the repository directory
+- {{c "MBT_REPO_DIRTY"}} {{c "true"}} if the workspace had uncommitted changes when the build started
+- {{c "MBT_MODULE_CHANGE"}} {{c "direct"}} if the module is changed, {{c "dependency"}} if it is built
+only because a module it depends on is changed or {{c "command"}} if only its commands are changed
+(see {{c "--isolate-command-changes"}}, not set when building all modules)
 
 In addition to the variables listed above, module properties are also populated 
 in the form of {{c "MBT_MODULE_PROPERTY_XXX"}} where {{c "XXX"}} denotes the key.
+
+Variables in the {{c "envFile"}} of a module are also populated. They are layered
+under the environment of the {{c "mbt"}} process, therefore, a variable set in
+both places takes the value from the process environment.
 `,
 	"describe-summary": `Describe repository manifest`,
 	"describe": `{{cli "Describe repository manifest \n"}}
@@ -253,10 +513,24 @@ Describe just the modules matching the {{c "--name"}} filter if specified.
 Default {{c "--name"}} filter is a prefix match. You can change this to a subsequence
 match by using {{c "--fuzzy"}} option.
 
-{{c "mbt describe diff --from <commit> --to <commit> [--graph] [--json]"}}{{br}}
+{{c "mbt describe blast-radius <sha> <path> [--graph] [--json]"}}{{br}}
+Describe modules impacted if the file in {{c "path"}} is changed in commit {{c "sha"}}.
+That is the module containing the file or depending on it via {{c "fileDependencies"}}
+and the modules depending on that module.
+Full commit sha is required.
+
+{{c "mbt describe diff --from <commit> --to <commit> [--entry-points] [--buildable] [--graph] [--json]"}}{{br}}
 Describe modules changed between {{c "from"}} and {{c "to"}} commits.
 In this mode, mbt works out the merge base between {{c "from"}} and {{c "to"}} and
 evaluates the modules changed between the merge base and {{c "to"}}.
+Commits can be specified with revision expressions such as {{c "HEAD~1"}},
+{{c "HEAD^"}} or {{c "master~2"}}.
+Use {{c "--since-tag <constraint>"}} instead of {{c "--from"}} to diff against
+the latest tag reachable from {{c "to"}} with a semantic version satisfying the
+constraint (e.g. {{c ">=1.0.0"}}). Prerelease tags such as {{c "v1.0.0-rc1"}} are
+ignored unless the constraint refers to a prerelease.
+Use {{c "--entry-points"}} to describe just the modules changed directly,
+leaving out the modules described only because they require a changed module.
 
 {{c "mbt describe head [--content] [--name <name>] [--fuzzy] [--graph] [--json]"}}{{br}}
 Describe modules in current head.
@@ -264,23 +538,46 @@ Describe just the modules matching the {{c "--name"}} filter if specified.
 Default {{c "--name"}} filter is a prefix match. You can change this to a subsequence
 match by using {{c "--fuzzy"}} option.
 
-{{c "mbt describe pr --src <name> --dst <name> [--graph] [--json]"}}{{br}}
+{{c "mbt describe pr --src <name> --dst <name> [--entry-points] [--buildable] [--graph] [--json]"}}{{br}}
 Describe modules changed between {{c "--src"}} and {{c "--dst"}} branches.
 In this mode, mbt works out the merge base between {{c "--src"}} and {{c "--dst"}} and
 evaluates the modules changed between the merge base and {{c "--src"}}.
+{{c "--entry-points"}} option works as in {{c "describe diff"}}.
 
 {{c "mbt describe local [--all] [--content] [--name <name>] [--fuzzy] [--graph] [--json]"}}{{br}}
 Describe modules modified in current workspace. All modules in the workspace are
 described if {{c "--all"}} option is specified.
+Untracked files (e.g. build outputs) are considered unless {{c "--tracked-only"}}
+option is specified.
 Describe just the modules matching the {{c "--name"}} filter if specified.
 Default {{c "--name"}} filter is a prefix match. You can change this to a subsequence
 match by using {{c "--fuzzy"}} option.
 
+Use {{c "--buildable"}} option to omit the modules without a build command for
+current operating system (e.g. shared libraries). Modules requiring a changed
+library are still described.
+
 {{h2 "Output Formats"}}
 Use {{c "--graph"}} option to output the manifest in graphviz dot format. This can
 be useful to visualise build dependencies.
 
+Use {{c "--mermaid"}} option to output the manifest as a mermaid flowchart
+(i.e. {{c "graph LR"}}) that can be embedded in markdown documents.
+Use {{c "--direction"}} option to change the orientation of the flowchart
+(available options are {{c "TB"}}, {{c "TD"}}, {{c "BT"}}, {{c "RL"}} and
+{{c "LR"}}). For example, {{c "--direction TB"}} lays out the flowchart from
+top to bottom instead of left to right.
+Node ids are derived from the module names and the names are used as labels.
+
 Use {{c "--json"}} option to output the manifest in json format.
+The document includes {{c "apiVersion"}} and {{c "kind"}} fields
+identifying its schema. Modules are listed under {{c "modules"}} field.
+
+Use {{c "--github-matrix"}} option to output the modules as a GitHub Actions
+matrix (i.e. {{c "{\"include\":[{\"app\":...,\"path\":...,\"version\":...}]}"}}).
+GitHub Actions fails jobs with an empty matrix. Use {{c "--matrix-skip-sentinel"}}
+option to output a single entry with {{c "skip"}} set to {{c "true"}} instead
+and guard the steps with {{c "if: ${{ !matrix.skip }}"}}.
 
 `,
 	"run-in-summary": `Run user defined command`,
@@ -302,6 +599,12 @@ match by using {{c "--fuzzy"}} option.
 Run user defined command in modules changed between {{c "from"}} and {{c "to"}} commits.
 In this mode, mbt works out the merge base between {{c "from"}} and {{c "to"}} and
 evaluates the modules changed between the merge base and {{c "to"}}.
+Commits can be specified with revision expressions such as {{c "HEAD~1"}},
+{{c "HEAD^"}} or {{c "master~2"}}.
+Use {{c "--since-tag <constraint>"}} instead of {{c "--from"}} to diff against
+the latest tag reachable from {{c "to"}} with a semantic version satisfying the
+constraint (e.g. {{c ">=1.0.0"}}). Prerelease tags such as {{c "v1.0.0-rc1"}} are
+ignored unless the constraint refers to a prerelease.
 
 {{c "mbt run-in head [--content] [--name <name>] [--fuzzy]"}}{{br}}
 Run user defined command in modules in current head.
@@ -321,17 +624,31 @@ Consider just the modules matching the {{c "--name"}} filter if specified.
 Default {{c "--name"}} filter is a prefix match. You can change this to a subsequence
 match by using {{c "--fuzzy"}} option.
 
+Command name {{c "build"}} refers to the build commands of the modules.
+
+{{c "--prefix-output"}} option prefixes each line of the command output with
+the name of the module producing it.
+
+{{c "--summary <file>"}} option writes a JSON document with the number of
+modules succeeded, failed and skipped along with the status, duration (in
+nanoseconds) and error of each module.
+
 {{h2 "Execution Environment"}}
 
 When executing a command, following environment variables are initialised and can be
 used by the command being executed.
 
 {{c "MBT_MODULE_NAME"}} Name of the module
+{{c "MBT_MODULE_ID"}} Stable id of the module (defaults to the name)
 {{c "MBT_MODULE_VERSION"}} Module version
 {{c "MBT_BUILD_COMMIT"}} Git commit SHA of the commit being built
 
 In addition to the variables listed above, module properties are also populated 
 in the form of {{c "MBT_MODULE_PROPERTY_XXX"}} where {{c "XXX"}} denotes the key.
+
+Variables in the {{c "envFile"}} of a module are also populated. They are layered
+under the environment of the {{c "mbt"}} process, therefore, a variable set in
+both places takes the value from the process environment.
 `,
 }
 
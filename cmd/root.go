@@ -17,6 +17,7 @@ package cmd
 
 import (
 	"os"
+	"os/exec"
 
 	"github.com/mbtproject/mbt/e"
 	"github.com/mbtproject/mbt/lib"
@@ -26,27 +27,57 @@ import (
 
 // Flags available to all commands.
 var (
-	in       string
-	src      string
-	dst      string
-	from     string
-	to       string
-	first    string
-	second   string
-	kind     string
-	name     string
-	command  string
-	all      bool
-	debug    bool
-	content  bool
-	fuzzy    bool
-	failFast bool
-	system   lib.System
+	in           string
+	src          string
+	dst          string
+	from         string
+	to           string
+	sinceTag     string
+	first        string
+	second       string
+	kind         string
+	name         string
+	command      string
+	all          bool
+	debug        bool
+	content      bool
+	fuzzy        bool
+	failFast     bool
+	prefixOutput bool
+	roots        []string
+	scope        []string
+	excludes     []string
+	maxFanOut    int
+	strictFanOut bool
+	maxImpact    int
+	maxDiscovery int
+	versionHash  string
+	firstParent  bool
+	renames      int
+	copies       int
+	unshallow    bool
+	isolateCmds  bool
+	trackedOnly  bool
+	system       lib.System
 )
 
 func init() {
 	RootCmd.PersistentFlags().StringVar(&in, "in", "", "Path to repo")
 	RootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "Enable debug output")
+	RootCmd.PersistentFlags().StringArrayVar(&roots, "root", nil, "Restrict discovery to this path relative to the repo root (can be repeated)")
+	RootCmd.PersistentFlags().StringArrayVar(&scope, "scope", nil, "Consider just the changes in this path relative to the repo root (can be repeated)")
+	RootCmd.PersistentFlags().IntVar(&maxFanOut, "max-fan-out", 0, "Warn when a change impacts more than this number of modules")
+	RootCmd.PersistentFlags().BoolVar(&strictFanOut, "strict-fan-out", false, "Fail instead of warning when --max-fan-out is exceeded")
+	RootCmd.PersistentFlags().IntVar(&maxImpact, "max-impact-depth", lib.UnlimitedImpactDepth, "Maximum number of dependencies followed from a changed module to the modules requiring it (0 for just the changed modules, -1 for unlimited)")
+	RootCmd.PersistentFlags().IntVar(&maxDiscovery, "max-discovery-depth", lib.UnlimitedDiscoveryDepth, "Ignore the modules nested in more than this number of directories from the repo root (0 for just the root module, -1 for unlimited)")
+	RootCmd.PersistentFlags().StringVar(&versionHash, "version-hash", lib.VersionHashSHA1, "Algorithm used to calculate module versions (available options are 'sha1' and 'sha256')")
+	RootCmd.PersistentFlags().BoolVar(&firstParent, "first-parent", false, "Find the merge base along the first parent history of the base branch")
+	RootCmd.PersistentFlags().IntVar(&renames, "rename-threshold", 0, "Similarity percentage required to detect renames in diffs (disabled when 0)")
+	RootCmd.PersistentFlags().IntVar(&copies, "copy-threshold", 0, "Similarity percentage required to detect copies in diffs (disabled when 0)")
+	RootCmd.PersistentFlags().BoolVar(&isolateCmds, "isolate-command-changes", false, "Do not impact the modules requiring a module with changes only in its commands")
+	RootCmd.PersistentFlags().BoolVar(&trackedOnly, "tracked-only", false, "Ignore untracked files when discovering modules and changes in the workspace")
+	RootCmd.PersistentFlags().BoolVar(&unshallow, "unshallow", false, "Fetch the missing history when a merge base cannot be found in a shallow clone")
+	RootCmd.PersistentFlags().StringArrayVar(&excludes, "exclude", nil, "Ignore changes in directories matching this glob pattern (can be repeated)")
 }
 
 // RootCmd is the main command.
@@ -85,8 +116,36 @@ var RootCmd = &cobra.Command{
 			level = lib.LogLevelDebug
 		}
 
+		var fetch lib.FetchFunc
+		if unshallow {
+			fetch = fetchUnshallow
+		}
+
 		var err error
-		system, err = lib.NewSystem(in, level)
+		system, err = lib.NewSystemWithOptions(in, level, &lib.SystemOptions{
+			Roots:                 roots,
+			Scope:                 scope,
+			Excludes:              excludes,
+			MaxFanOut:             maxFanOut,
+			StrictFanOut:          strictFanOut,
+			MaxImpactDepth:        &maxImpact,
+			VersionHash:           versionHash,
+			MaxDiscoveryDepth:     &maxDiscovery,
+			FirstParentMergeBase:  firstParent,
+			RenameThreshold:       renames,
+			CopyThreshold:         copies,
+			Fetch:                 fetch,
+			IsolateCommandChanges: isolateCmds,
+			TrackedOnly:           trackedOnly,
+		})
 		return err
 	},
 }
+
+func fetchUnshallow(path string) error {
+	cmd := exec.Command("git", "fetch", "--unshallow")
+	cmd.Dir = path
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -16,7 +16,9 @@ limitations under the License.
 package cmd
 
 import (
+	"encoding/json"
 	"errors"
+	"io/ioutil"
 
 	"github.com/sirupsen/logrus"
 
@@ -25,15 +27,20 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var summaryFile string
+
 func init() {
+	runIn.PersistentFlags().StringVar(&summaryFile, "summary", "", "Write a JSON summary of the run to this file")
 	runIn.PersistentFlags().StringVarP(&command, "command", "m", "", "Command to execute")
 	runIn.PersistentFlags().BoolVarP(&failFast, "fail-fast", "", false, "Fail fast on command failure")
+	runIn.PersistentFlags().BoolVar(&prefixOutput, "prefix-output", false, "Prefix each line of the command output with the module name")
 
 	runInPr.Flags().StringVar(&src, "src", "", "Source branch")
 	runInPr.Flags().StringVar(&dst, "dst", "", "Destination branch")
 
 	runInDiff.Flags().StringVar(&from, "from", "", "From commit")
 	runInDiff.Flags().StringVar(&to, "to", "", "To commit")
+	runInDiff.Flags().StringVar(&sinceTag, "since-tag", "", "Use the latest tag satisfying this version constraint as from commit")
 
 	runInLocal.Flags().BoolVarP(&all, "all", "a", false, "All modules")
 	runInLocal.Flags().StringVarP(&name, "name", "n", "", "Build modules with a name that matches this value. Multiple names can be specified as a comma separated string.")
@@ -95,7 +102,11 @@ var runInPr = &cobra.Command{
 var runInDiff = &cobra.Command{
 	Use: "diff --from <sha> --to <sha>",
 	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
-		if from == "" {
+		if from != "" && sinceTag != "" {
+			return errors.New("from commit and since-tag cannot be used together")
+		}
+
+		if from == "" && sinceTag == "" {
 			return errors.New("requires from commit")
 		}
 
@@ -103,6 +114,10 @@ var runInDiff = &cobra.Command{
 			return errors.New("requires to commit")
 		}
 
+		if sinceTag != "" {
+			return summariseRun(system.RunInSinceTag(command, sinceTag, to, runInCmdOptions()))
+		}
+
 		return summariseRun(system.RunInDiff(command, from, to, runInCmdOptions()))
 	}),
 }
@@ -155,6 +170,18 @@ func summariseRun(summary *lib.RunResult, err error) error {
 
 		logrus.Infof("Build finished for commit %v", summary.Manifest.Sha)
 
+		if summaryFile != "" {
+			content, err := json.MarshalIndent(summary.Summary, "", "  ")
+			if err != nil {
+				return e.Wrap(lib.ErrClassInternal, err)
+			}
+
+			err = ioutil.WriteFile(summaryFile, content, 0644)
+			if err != nil {
+				return e.Wrapf(lib.ErrClassUser, err, "Failed to write the summary to '%v'", summaryFile)
+			}
+		}
+
 		if len(summary.Failures) > 0 && failFast {
 			return e.NewError(lib.ErrClassUser, "One or more commands failed to run")
 		}
@@ -165,6 +192,9 @@ func summariseRun(summary *lib.RunResult, err error) error {
 func runInCmdOptions() *lib.CmdOptions {
 	options := lib.CmdOptionsWithStdIO(runCmdStageCB)
 	options.FailFast = failFast
+	if prefixOutput {
+		options.OutputPrefix = lib.ModuleNamePrefix
+	}
 	return options
 }
 
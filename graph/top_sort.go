@@ -67,7 +67,7 @@ func TopSort(nodeProvider NodeProvider, graph ...interface{}) ([]interface{}, er
 	results := make([]interface{}, 0)
 
 	for _, node := range graph {
-		err := dfsVisit(nodeProvider, node, traversalState, &results, make([]interface{}, 0))
+		err := dfsVisit(nodeProvider, node, traversalState, &results)
 		if err != nil {
 			return nil, err
 		}
@@ -76,31 +76,56 @@ func TopSort(nodeProvider NodeProvider, graph ...interface{}) ([]interface{}, er
 	return results, nil
 }
 
-func dfsVisit(nodeProvider NodeProvider, node interface{}, traversalState map[interface{}]tState, sorted *[]interface{}, path []interface{}) error {
-	id := nodeProvider.ID(node)
-	if traversalState[id] == stateOpen {
-		return &CycleError{Path: append(path, node)}
-	}
+// frame is an entry in the explicit stack used by dfsVisit.
+type frame struct {
+	node interface{}
+	id   interface{}
+	next int
+}
 
+// dfsVisit performs a depth first traversal starting from node.
+// Traversal uses an explicit stack instead of recursion so that
+// deep graphs do not exhaust the call stack.
+func dfsVisit(nodeProvider NodeProvider, node interface{}, traversalState map[interface{}]tState, sorted *[]interface{}) error {
+	id := nodeProvider.ID(node)
 	if traversalState[id] == stateClosed {
 		return nil
 	}
 
 	traversalState[id] = stateOpen
-	path = append(path, node)
+	stack := []*frame{{node: node, id: id}}
+
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		if top.next >= nodeProvider.ChildCount(top.node) {
+			traversalState[top.id] = stateClosed
+			*sorted = append(*sorted, top.node)
+			stack = stack[:len(stack)-1]
+			continue
+		}
 
-	for i := 0; i < nodeProvider.ChildCount(node); i++ {
-		c, err := nodeProvider.Child(node, i)
+		c, err := nodeProvider.Child(top.node, top.next)
 		if err != nil {
 			return err
 		}
-		err = dfsVisit(nodeProvider, c, traversalState, sorted, path)
-		if err != nil {
-			return err
+		top.next++
+
+		cid := nodeProvider.ID(c)
+		switch traversalState[cid] {
+		case stateOpen:
+			// Nodes in the stack form the path from the root to c.
+			path := make([]interface{}, 0, len(stack)+1)
+			for _, f := range stack {
+				path = append(path, f.node)
+			}
+			return &CycleError{Path: append(path, c)}
+		case stateClosed:
+			continue
 		}
+
+		traversalState[cid] = stateOpen
+		stack = append(stack, &frame{node: c, id: cid})
 	}
 
-	traversalState[id] = stateClosed
-	*sorted = append(*sorted, node)
 	return nil
 }
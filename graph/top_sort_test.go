@@ -17,6 +17,7 @@ package graph
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -262,3 +263,39 @@ func TestComplexCycle(t *testing.T) {
 	assert.Equal(t, f, cErr.Path[4])
 	assert.Equal(t, c, cErr.Path[5])
 }
+
+func TestDeepChain(t *testing.T) {
+	nodes := make([]*node, 10000)
+	for i := range nodes {
+		nodes[i] = newNode(fmt.Sprintf("n%d", i))
+		if i > 0 {
+			nodes[i-1].children = []*node{nodes[i]}
+		}
+	}
+
+	s, err := TopSort(&testNodeProvider{}, nodes[0])
+	assert.NoError(t, err)
+
+	assert.Len(t, s, len(nodes))
+	assert.Equal(t, nodes[len(nodes)-1], s[0])
+	assert.Equal(t, nodes[0], s[len(s)-1])
+}
+
+func TestDeepChainWithCycle(t *testing.T) {
+	nodes := make([]*node, 10000)
+	for i := range nodes {
+		nodes[i] = newNode(fmt.Sprintf("n%d", i))
+		if i > 0 {
+			nodes[i-1].children = []*node{nodes[i]}
+		}
+	}
+	nodes[len(nodes)-1].children = []*node{nodes[0]}
+
+	s, err := TopSort(&testNodeProvider{}, nodes[0])
+	cErr := err.(*CycleError)
+
+	assert.Nil(t, s)
+	assert.Len(t, cErr.Path, len(nodes)+1)
+	assert.Equal(t, nodes[0], cErr.Path[0])
+	assert.Equal(t, nodes[0], cErr.Path[len(nodes)])
+}
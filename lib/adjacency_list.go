@@ -0,0 +1,60 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"sort"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// Directions of the dependency edges between modules.
+const (
+	// DirectionRequires follows the edges from a module to the modules it requires.
+	DirectionRequires = "requires"
+	// DirectionRequiredBy follows the edges from a module to the modules requiring it.
+	DirectionRequiredBy = "requiredBy"
+)
+
+// AdjacencyList converts specified modules into an adjacency list
+// keyed by the module name. Each entry contains the sorted names of
+// the modules adjacent in the specified direction.
+// Returns an error if the direction is not one of DirectionRequires
+// or DirectionRequiredBy.
+func (mods Modules) AdjacencyList(direction string) (map[string][]string, error) {
+	if direction != DirectionRequires && direction != DirectionRequiredBy {
+		return nil, e.NewErrorf(ErrClassUser, msgUnknownDirection, direction)
+	}
+
+	list := make(map[string][]string)
+
+	for _, m := range mods {
+		adjacent := m.Requires()
+		if direction == DirectionRequiredBy {
+			adjacent = m.RequiredBy()
+		}
+
+		names := make([]string, 0, len(adjacent))
+		for _, a := range adjacent {
+			names = append(names, a.Name())
+		}
+		sort.Strings(names)
+
+		list[m.Name()] = names
+	}
+
+	return list, nil
+}
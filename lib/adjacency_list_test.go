@@ -0,0 +1,63 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/stretchr/testify/assert"
+)
+
+func adjacencyTestModules(t *testing.T) Modules {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-c", "app-b"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b", Dependencies: []string{"app-c"}}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c"}, nil),
+	})
+	check(t, err)
+	return mods
+}
+
+func TestAdjacencyListOfRequires(t *testing.T) {
+	list, err := adjacencyTestModules(t).AdjacencyList(DirectionRequires)
+	check(t, err)
+
+	assert.Equal(t, map[string][]string{
+		"app-a": {"app-b", "app-c"},
+		"app-b": {"app-c"},
+		"app-c": {},
+	}, list)
+}
+
+func TestAdjacencyListOfRequiredBy(t *testing.T) {
+	list, err := adjacencyTestModules(t).AdjacencyList(DirectionRequiredBy)
+	check(t, err)
+
+	assert.Equal(t, map[string][]string{
+		"app-a": {},
+		"app-b": {"app-a"},
+		"app-c": {"app-a", "app-b"},
+	}, list)
+}
+
+func TestAdjacencyListOfUnknownDirection(t *testing.T) {
+	_, err := adjacencyTestModules(t).AdjacencyList("sideways")
+
+	assert.EqualError(t, err, fmt.Sprintf(msgUnknownDirection, "sideways"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
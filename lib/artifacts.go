@@ -0,0 +1,77 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// verifyArtifacts checks that the artifacts declared by the module
+// exist and match their expected checksums.
+// Artifacts must be in the module directory.
+// Returns the checksums of the artifacts keyed by their paths.
+func verifyArtifacts(manifest *Manifest, mod *Module) (map[string]string, error) {
+	artifacts := mod.Artifacts()
+	if len(artifacts) == 0 {
+		return nil, nil
+	}
+
+	r := make(map[string]string)
+	for _, a := range artifacts {
+		p, ok := cleanModulePath(a.Path)
+		if !ok {
+			return nil, e.NewErrorf(ErrClassUser, msgArtifactOutsideModule, a.Path, mod.Name())
+		}
+
+		sum, err := sha256File(path.Join(manifest.Dir, mod.Path(), p))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, e.NewErrorf(ErrClassUser, msgArtifactNotFound, a.Path, mod.Name())
+			}
+			return nil, e.Wrapf(ErrClassUser, err, msgFailedReadFile, a.Path)
+		}
+
+		if a.SHA256 != "" && !strings.EqualFold(a.SHA256, sum) {
+			return nil, e.NewErrorf(ErrClassUser, msgArtifactChecksumMismatch, a.Path, mod.Name(), sum, a.SHA256)
+		}
+
+		r[a.Path] = sum
+	}
+
+	return r, nil
+}
+
+func sha256File(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyArtifacts(t *testing.T) {
+	clean()
+	check(t, os.MkdirAll(".tmp/repo/app-a/out", 0755))
+	check(t, ioutil.WriteFile(".tmp/repo/app-a/out/app", []byte("test"), 0644))
+
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Artifacts: []*Artifact{{Path: "out/app"}}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b", Artifacts: []*Artifact{{Path: "out/app"}}}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c"}, nil),
+	})
+	check(t, err)
+
+	m := mods.indexByName()
+	manifest := &Manifest{Dir: ".tmp/repo"}
+
+	artifacts, err := verifyArtifacts(manifest, m["app-a"])
+	check(t, err)
+	assert.Equal(t, map[string]string{"out/app": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"}, artifacts)
+
+	_, err = verifyArtifacts(manifest, m["app-b"])
+	assert.EqualError(t, err, fmt.Sprintf(msgArtifactNotFound, "out/app", "app-b"))
+
+	artifacts, err = verifyArtifacts(manifest, m["app-c"])
+	check(t, err)
+	assert.Nil(t, artifacts)
+}
+
+func TestVerifyArtifactsOutsideModule(t *testing.T) {
+	clean()
+	check(t, os.MkdirAll(".tmp/repo/app-a", 0755))
+	check(t, ioutil.WriteFile(".tmp/repo/secret", []byte("test"), 0644))
+
+	for _, p := range []string{"../secret", "out/../../secret", "/etc/passwd"} {
+		mods, err := toModules(moduleMetadataSet{
+			newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Artifacts: []*Artifact{{Path: p}}}, nil),
+		})
+		check(t, err)
+
+		_, err = verifyArtifacts(&Manifest{Dir: ".tmp/repo"}, mods[0])
+
+		assert.EqualError(t, err, fmt.Sprintf(msgArtifactOutsideModule, p, "app-a"))
+		assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+	}
+}
@@ -17,6 +17,10 @@ package lib
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 )
 
@@ -78,3 +82,49 @@ func BenchmarkReduceToDiff1000(b *testing.B) {
 func BenchmarkReduceToDiff10000(b *testing.B) {
 	benchmarkReduceToDiff(10000, 10000, b)
 }
+
+func benchmarkHashModuleDirs(modulesCount, workers int, b *testing.B) {
+	clean()
+	defer clean()
+
+	set := moduleMetadataSet{}
+	for i := 0; i < modulesCount; i++ {
+		dir := fmt.Sprintf("app-%v", i)
+		for j := 0; j < 20; j++ {
+			p := filepath.Join(".tmp/dir", dir, "src", fmt.Sprintf("file-%v", j))
+			if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+				b.Fatalf("%v", err)
+			}
+			if err := ioutil.WriteFile(p, []byte(fmt.Sprintf("sample content %v", j)), 0644); err != nil {
+				b.Fatalf("%v", err)
+			}
+		}
+		set = append(set, newModuleMetadata(dir, "", &Spec{Name: dir}, nil))
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := hashModuleDirs(".tmp/dir", set, workers); err != nil {
+			b.Fatalf("%v", err)
+		}
+	}
+
+	b.StopTimer()
+}
+
+func BenchmarkHashModuleDirsSerial100(b *testing.B) {
+	benchmarkHashModuleDirs(100, 1, b)
+}
+
+func BenchmarkHashModuleDirsParallel100(b *testing.B) {
+	benchmarkHashModuleDirs(100, runtime.NumCPU(), b)
+}
+
+func BenchmarkHashModuleDirsSerial1000(b *testing.B) {
+	benchmarkHashModuleDirs(1000, 1, b)
+}
+
+func BenchmarkHashModuleDirsParallel1000(b *testing.B) {
+	benchmarkHashModuleDirs(1000, runtime.NumCPU(), b)
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+func (s *stdSystem) BlastRadius(commit, filePath string) (Modules, error) {
+	p, err := normalizeFilePath(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := s.Repo.GetCommit(commit)
+	if err != nil {
+		return nil, err
+	}
+
+	modules, err := s.Discover.ModulesInCommit(c)
+	if err != nil {
+		return nil, err
+	}
+
+	// Treat the file as a single change so that the outcome is
+	// consistent with the diff based manifests.
+	impacted, err := s.Reducer.Reduce(modules, []*DiffDelta{{NewFile: p, OldFile: p}})
+	if err != nil {
+		return nil, err
+	}
+
+	return impacted.expandRequiredByDependencies()
+}
+
+// normalizeFilePath converts a file path to the repository relative
+// form used in git diffs.
+func normalizeFilePath(filePath string) (string, error) {
+	p := path.Clean(filepath.ToSlash(filePath))
+	if p == "." || p == "/" || strings.HasPrefix(p, "../") || p == ".." {
+		return "", e.NewErrorf(ErrClassUser, msgInvalidFilePath, filePath)
+	}
+
+	return strings.TrimPrefix(p, "/"), nil
+}
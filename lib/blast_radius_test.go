@@ -0,0 +1,85 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlastRadiusOfModuleFile(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{Name: "app-a", Dependencies: []string{"lib-a"}}))
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.InitModule("lib-a"))
+	check(t, repo.WriteContent("lib-a/foo", "hello"))
+	check(t, repo.Commit("first"))
+
+	mods, err := NewWorld(t, ".tmp/repo").System.BlastRadius(repo.LastCommit.String(), "./lib-a/foo")
+	check(t, err)
+
+	assert.Len(t, mods, 2)
+	assert.Equal(t, "lib-a", mods[0].Name())
+	assert.Equal(t, "app-a", mods[1].Name())
+}
+
+func TestBlastRadiusOfFileDependency(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{Name: "app-a", FileDependencies: []string{"shared/config"}}))
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.WriteContent("shared/config", "hello"))
+	check(t, repo.Commit("first"))
+
+	mods, err := NewWorld(t, ".tmp/repo").System.BlastRadius(repo.LastCommit.String(), "shared/config")
+	check(t, err)
+
+	assert.Len(t, mods, 1)
+	assert.Equal(t, "app-a", mods[0].Name())
+}
+
+func TestBlastRadiusOfFileOutsideModules(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.WriteContent("README.md", "hello"))
+	check(t, repo.Commit("first"))
+
+	mods, err := NewWorld(t, ".tmp/repo").System.BlastRadius(repo.LastCommit.String(), "README.md")
+	check(t, err)
+
+	assert.Len(t, mods, 0)
+}
+
+func TestBlastRadiusOfInvalidPath(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+
+	_, err := NewWorld(t, ".tmp/repo").System.BlastRadius(repo.LastCommit.String(), "../app-a/foo")
+
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidFilePath, "../app-a/foo"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
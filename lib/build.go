@@ -16,7 +16,11 @@ limitations under the License.
 package lib
 
 import (
+	"bytes"
+	"io"
+	"reflect"
 	"runtime"
+	"sync"
 
 	git "github.com/libgit2/git2go/v28"
 	"github.com/mbtproject/mbt/e"
@@ -58,6 +62,15 @@ func (s *stdSystem) BuildDiff(from, to string, options *CmdOptions) (*BuildSumma
 	return s.checkoutAndBuildManifest(m, options)
 }
 
+func (s *stdSystem) BuildSinceTag(constraint, to string, options *CmdOptions) (*BuildSummary, error) {
+	m, err := s.ManifestSinceTag(constraint, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.checkoutAndBuildManifest(m, options)
+}
+
 func (s *stdSystem) BuildCurrentBranch(filterOptions *FilterOptions, options *CmdOptions) (*BuildSummary, error) {
 	m, err := s.ManifestByCurrentBranch()
 	if err != nil {
@@ -131,10 +144,26 @@ func (s *stdSystem) checkoutAndBuildManifest(m *Manifest, options *CmdOptions) (
 }
 
 func (s *stdSystem) buildManifest(m *Manifest, options *CmdOptions) (*BuildSummary, error) {
+	if options.MaxParallel > 1 {
+		return s.buildManifestParallel(m, options)
+	}
+
 	completed := make([]*BuildResult, 0)
 	skipped := make([]*Module, 0)
 
-	for _, a := range m.Modules {
+	// Modules are built in the order of their build stages so that
+	// priority decides the order of the modules in the same stage.
+	stages, err := m.Modules.BuildStages()
+	if err != nil {
+		return nil, err
+	}
+
+	ordered := Modules{}
+	for _, stage := range stages {
+		ordered = append(ordered, stage...)
+	}
+
+	for _, a := range ordered {
 		cmd, ok := s.canBuildHere(a)
 		if !ok {
 			skipped = append(skipped, a)
@@ -143,31 +172,307 @@ func (s *stdSystem) buildManifest(m *Manifest, options *CmdOptions) (*BuildSumma
 		}
 
 		options.Callback(a, CmdStageBeforeBuild, nil)
-		err := s.execBuild(cmd, m, a, options)
+		result, artifacts, err := s.execBuild(cmd, m, a, options)
 		if err != nil {
 			return nil, err
 		}
+
+		if result == CmdResultSkipped {
+			skipped = append(skipped, a)
+			options.Callback(a, CmdStageSkipBuild, nil)
+			continue
+		}
+
 		options.Callback(a, CmdStageAfterBuild, nil)
-		completed = append(completed, &BuildResult{Module: a})
+		completed = append(completed, &BuildResult{Module: a, Artifacts: artifacts})
+	}
+
+	return &BuildSummary{Manifest: m, Completed: completed, Skipped: skipped}, nil
+}
+
+// buildManifestParallel builds modules concurrently while their total
+// weight is within options.MaxParallel.
+// A module is started only after all of its dependencies in the manifest
+// are built and no other running module holds the same resource.
+// Modules heavier than the whole budget are started once nothing else
+// is running.
+// Callbacks are always invoked from the calling goroutine.
+func (s *stdSystem) buildManifestParallel(m *Manifest, options *CmdOptions) (*BuildSummary, error) {
+	type buildResult struct {
+		mod       *Module
+		result    CmdResult
+		artifacts map[string]string
+		err       error
+	}
+
+	completed := make([]*BuildResult, 0)
+	skipped := make([]*Module, 0)
+
+	inManifest := make(map[string]bool)
+	for _, a := range m.Modules {
+		inManifest[a.Name()] = true
+	}
+
+	// Concurrent builds share the output streams.
+	execOptions := *options
+	if options.Stdout != nil {
+		execOptions.Stdout = &syncWriter{w: options.Stdout}
+	}
+	if options.Stderr != nil && sameWriter(options.Stderr, options.Stdout) {
+		execOptions.Stderr = execOptions.Stdout
+	} else if options.Stderr != nil {
+		execOptions.Stderr = &syncWriter{w: options.Stderr}
+	}
+
+	done := make(map[string]bool)
+	busy := make(map[string]bool)
+	// Modules are considered in the order of their build stages so that
+	// priority breaks the ties between the modules ready to be built.
+	stages, err := m.Modules.BuildStages()
+	if err != nil {
+		return nil, err
+	}
+
+	pending := Modules{}
+	for _, stage := range stages {
+		pending = append(pending, stage...)
+	}
+	results := make(chan *buildResult)
+	running := 0
+	weight := 0
+
+	var buildErr error
+
+	ready := func(a *Module) bool {
+		if a.Resource() != "" && busy[a.Resource()] {
+			return false
+		}
+		for _, r := range a.Requires() {
+			if inManifest[r.Name()] && !done[r.Name()] {
+				return false
+			}
+		}
+		return true
+	}
+
+	for len(pending) > 0 || running > 0 {
+		progressed := false
+		// Once a ready module does not fit in the budget, no other module
+		// is started so that it is not starved by lighter modules.
+		full := false
+		remaining := make(Modules, 0, len(pending))
+		for _, a := range pending {
+			if buildErr != nil || full || !ready(a) {
+				remaining = append(remaining, a)
+				continue
+			}
+
+			if running > 0 && weight+a.Weight() > options.MaxParallel {
+				full = true
+				remaining = append(remaining, a)
+				continue
+			}
+
+			cmd, ok := s.canBuildHere(a)
+			if !ok {
+				skipped = append(skipped, a)
+				options.Callback(a, CmdStageSkipBuild, nil)
+				done[a.Name()] = true
+				progressed = true
+				continue
+			}
+
+			options.Callback(a, CmdStageBeforeBuild, nil)
+			if a.Resource() != "" {
+				busy[a.Resource()] = true
+			}
+			running++
+			weight += a.Weight()
+			progressed = true
+			go func(cmd *Cmd, a *Module) {
+				result, artifacts, err := s.execBuild(cmd, m, a, &execOptions)
+				results <- &buildResult{mod: a, result: result, artifacts: artifacts, err: err}
+			}(cmd, a)
+		}
+		pending = remaining
+
+		if running == 0 {
+			if buildErr != nil || len(pending) == 0 {
+				break
+			}
+			if !progressed {
+				return nil, e.NewErrorf(ErrClassInternal, "unable to schedule the build of module %s", pending[0].Name())
+			}
+			// Skipped modules may have unblocked the rest of the queue.
+			continue
+		}
+
+		r := <-results
+		running--
+		weight -= r.mod.Weight()
+		if r.mod.Resource() != "" {
+			delete(busy, r.mod.Resource())
+		}
+
+		if r.err != nil {
+			if buildErr == nil {
+				buildErr = r.err
+			}
+			continue
+		}
+
+		done[r.mod.Name()] = true
+		if r.result == CmdResultSkipped {
+			skipped = append(skipped, r.mod)
+			options.Callback(r.mod, CmdStageSkipBuild, nil)
+			continue
+		}
+
+		options.Callback(r.mod, CmdStageAfterBuild, nil)
+		completed = append(completed, &BuildResult{Module: r.mod, Artifacts: r.artifacts})
+	}
+
+	if buildErr != nil {
+		return nil, buildErr
 	}
 
 	return &BuildSummary{Manifest: m, Completed: completed, Skipped: skipped}, nil
 }
 
-func (s *stdSystem) execBuild(buildCmd *Cmd, manifest *Manifest, module *Module, options *CmdOptions) error {
-	err := s.ProcessManager.Exec(manifest, module, options, buildCmd.Cmd, buildCmd.Args...)
+func (s *stdSystem) execBuild(buildCmd *Cmd, manifest *Manifest, module *Module, options *CmdOptions) (CmdResult, map[string]string, error) {
+	if !manifest.deprecationsReported {
+		Modules{module}.warnDeprecated(s.Log)
+	}
+	options, flush := prefixOutput(options, module)
+	args := append(append([]string{}, buildCmd.Args...), options.ExtraArgs...)
+	result, err := classifyExec(buildCmd, s.ProcessManager.Exec(manifest, module, options, buildCmd.Cmd, args...))
+	flush()
+	if err != nil {
+		return result, nil, e.Wrapf(ErrClassUser, err, msgFailedBuild, module.Name())
+	}
+
+	if result != CmdResultSuccess {
+		return result, nil, nil
+	}
+
+	artifacts, err := verifyArtifacts(manifest, module)
 	if err != nil {
-		return e.Wrapf(ErrClassUser, err, msgFailedBuild, module.Name())
+		return CmdResultFailed, nil, e.Wrapf(ErrClassUser, err, msgFailedBuild, module.Name())
 	}
-	return nil
+
+	return result, artifacts, nil
 }
 
 func (s *stdSystem) canBuildHere(mod *Module) (*Cmd, bool) {
-	c, ok := mod.Build()[runtime.GOOS]
+	return mod.CommandForOS(CommandSetBuild, runtime.GOOS)
+}
+
+// syncWriter serialises the writes to an underlying writer.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+// prefixWriter writes each line to the underlying writer with a prefix.
+// Partial lines are buffered until they are completed or flushed so that
+// each line is written with a single call to the underlying writer.
+type prefixWriter struct {
+	w      io.Writer
+	prefix []byte
+	buf    []byte
+}
+
+// Write buffers p and writes the complete lines in the buffer.
+// When a line fails to be written, the bytes of p from the start of
+// that line are not consumed and are excluded from the returned count.
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	pending := len(w.buf)
+	w.buf = append(w.buf, p...)
+	written := 0
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+
+		if err := w.writeLine(w.buf[:i+1]); err != nil {
+			if written < pending {
+				w.buf = w.buf[:pending-written]
+				return 0, err
+			}
+			w.buf = nil
+			return written - pending, err
+		}
+		w.buf = w.buf[i+1:]
+		written += i + 1
+	}
+
+	return len(p), nil
+}
+
+// Flush writes the buffered partial line terminated by a new line.
+func (w *prefixWriter) Flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	line := append(w.buf, '\n')
+	w.buf = nil
+	return w.writeLine(line)
+}
+
+func (w *prefixWriter) writeLine(line []byte) error {
+	_, err := w.w.Write(append(append([]byte{}, w.prefix...), line...))
+	return err
+}
+
+// prefixOutput returns a copy of the options with output streams
+// prefixed by options.OutputPrefix for the specified module and a
+// function to flush the partial lines once the command is exited.
+func prefixOutput(options *CmdOptions, mod *Module) (*CmdOptions, func()) {
+	if options.OutputPrefix == nil {
+		return options, func() {}
+	}
+
+	prefix := []byte(options.OutputPrefix(mod))
+	o := *options
+	writers := make([]*prefixWriter, 0, 2)
+	if options.Stdout != nil {
+		stdout := &prefixWriter{w: options.Stdout, prefix: prefix}
+		o.Stdout = stdout
+		writers = append(writers, stdout)
+	}
+
+	// Shared stream must be wrapped once so that the lines
+	// written to stdout and stderr are not mixed up.
+	if options.Stderr != nil && sameWriter(options.Stderr, options.Stdout) {
+		o.Stderr = o.Stdout
+	} else if options.Stderr != nil {
+		stderr := &prefixWriter{w: options.Stderr, prefix: prefix}
+		o.Stderr = stderr
+		writers = append(writers, stderr)
+	}
+
+	return &o, func() {
+		for _, w := range writers {
+			w.Flush()
+		}
+	}
+}
 
-	if !ok {
-		c, ok = mod.Build()["default"]
+// sameWriter returns true if both writers are the same.
+// Writers of types that cannot be compared are never the same.
+func sameWriter(a, b io.Writer) bool {
+	if a == nil || b == nil {
+		return a == b
 	}
 
-	return c, ok
+	t := reflect.TypeOf(a)
+	return t == reflect.TypeOf(b) && t.Comparable() && a == b
 }
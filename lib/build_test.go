@@ -23,7 +23,10 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	git "github.com/libgit2/git2go/v28"
 	"github.com/mbtproject/mbt/e"
@@ -107,13 +110,13 @@ func TestBuildSkip(t *testing.T) {
 	case "linux", "darwin":
 		check(t, repo.InitModuleWithOptions("app-a", &Spec{
 			Name:  "app-a",
-			Build: map[string]*Cmd{"windows": {"powershell", []string{"-ExecutionPolicy", "Bypass", "-File", ".\\build.ps1"}}},
+			Build: map[string]*Cmd{"windows": {Cmd: "powershell", Args: []string{"-ExecutionPolicy", "Bypass", "-File", ".\\build.ps1"}}},
 		}))
 		check(t, repo.WritePowershellScript("app-a/build.ps1", "write-host built app-a"))
 	case "windows":
 		check(t, repo.InitModuleWithOptions("app-a", &Spec{
 			Name:  "app-a",
-			Build: map[string]*Cmd{"darwin": {"./build.sh", []string{}}},
+			Build: map[string]*Cmd{"darwin": {Cmd: "./build.sh", Args: []string{}}},
 		}))
 		check(t, repo.WriteShellScript("app-a/build.sh", "echo built app-a"))
 	}
@@ -202,6 +205,35 @@ func TestBuildDiff(t *testing.T) {
 	assert.Equal(t, "", buff.String())
 }
 
+func TestBuildReportsDeprecatedModulesOnce(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{Name: "app-a", Deprecated: true, Build: map[string]*Cmd{"default": {Cmd: "echo", Args: []string{"built app-a"}}}}))
+	check(t, repo.Commit("first"))
+	c1 := repo.LastCommit
+
+	check(t, repo.WriteContent("app-a/foo", "bar"))
+	check(t, repo.Commit("second"))
+	c2 := repo.LastCommit
+
+	w := NewWorld(t, ".tmp/repo")
+	log := NewTestLog()
+	mb := NewManifestBuilder(w.Repo, w.Reducer, w.Discover, log)
+	s := initSystem(log, w.Repo, mb, w.Discover, w.Reducer, w.WorkspaceManager, w.ProcessManager)
+
+	_, err := s.BuildDiff(c1.String(), c2.String(), stdTestCmdOptions(new(bytes.Buffer)))
+	check(t, err)
+
+	assert.Equal(t, []string{fmt.Sprintf(msgDeprecatedModule, "app-a")}, log.Warnings)
+
+	log.Warnings = nil
+	_, err = s.BuildCurrentBranch(NoFilter, stdTestCmdOptions(new(bytes.Buffer)))
+	check(t, err)
+
+	assert.Equal(t, []string{fmt.Sprintf(msgDeprecatedModule, "app-a")}, log.Warnings)
+}
+
 func TestBuildPr(t *testing.T) {
 	clean()
 	repo := NewTestRepo(t, ".tmp/repo")
@@ -742,3 +774,509 @@ func TestRestorationOnBuildFailure(t *testing.T) {
 	check(t, err)
 	assert.Equal(t, 0, numDeltas)
 }
+
+func TestParallelBuildOfModulesSharingAResource(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	for _, n := range []string{"app-a", "app-b", "app-c"} {
+		spec := &Spec{Name: n, Build: map[string]*Cmd{"default": {Cmd: "echo"}}}
+		if n != "app-c" {
+			spec.Resource = "db"
+		}
+		check(t, repo.InitModuleWithOptions(n, spec))
+	}
+	check(t, repo.Commit("first"))
+
+	var mu sync.Mutex
+	active := make(map[string]int)
+	maxActive := make(map[string]int)
+
+	w := NewWorld(t, ".tmp/repo")
+	w.ProcessManager.Interceptor.Config("Exec").Do(func(args ...interface{}) []interface{} {
+		r := args[1].(*Module).Resource()
+		mu.Lock()
+		active[r]++
+		if active[r] > maxActive[r] {
+			maxActive[r] = active[r]
+		}
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		active[r]--
+		mu.Unlock()
+		return []interface{}{nil}
+	})
+
+	options := stdTestCmdOptions(nil)
+	options.MaxParallel = 3
+	summary, err := w.System.BuildWorkspace(NoFilter, options)
+	check(t, err)
+
+	assert.Len(t, summary.Completed, 3)
+	assert.Equal(t, 1, maxActive["db"])
+}
+
+func TestParallelBuildWithWeights(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	weights := map[string]int{"app-a": 2, "app-b": 2, "app-c": 0, "app-d": 5}
+	for n, weight := range weights {
+		check(t, repo.InitModuleWithOptions(n, &Spec{Name: n, Build: map[string]*Cmd{"default": {Cmd: "echo"}}, Weight: weight}))
+	}
+	check(t, repo.Commit("first"))
+
+	var mu sync.Mutex
+	running := make(map[string]int)
+	maxWeight := 0
+	heavyRanAlone := true
+
+	w := NewWorld(t, ".tmp/repo")
+	w.ProcessManager.Interceptor.Config("Exec").Do(func(args ...interface{}) []interface{} {
+		m := args[1].(*Module)
+		mu.Lock()
+		running[m.Name()] = m.Weight()
+		total := 0
+		for n, weight := range running {
+			if n == "app-d" && len(running) > 1 {
+				heavyRanAlone = false
+			}
+			if n != "app-d" {
+				total += weight
+			}
+		}
+		if total > maxWeight {
+			maxWeight = total
+		}
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		delete(running, m.Name())
+		mu.Unlock()
+		return []interface{}{nil}
+	})
+
+	options := stdTestCmdOptions(nil)
+	options.MaxParallel = 3
+	summary, err := w.System.BuildWorkspace(NoFilter, options)
+	check(t, err)
+
+	assert.Len(t, summary.Completed, 4)
+	assert.Equal(t, 3, maxWeight)
+	assert.True(t, heavyRanAlone)
+}
+
+func TestParallelBuildRespectsDependencies(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{
+		Name:  "app-a",
+		Build: map[string]*Cmd{"default": {Cmd: "echo"}},
+	}))
+	check(t, repo.InitModuleWithOptions("app-b", &Spec{
+		Name:         "app-b",
+		Build:        map[string]*Cmd{"default": {Cmd: "echo"}},
+		Dependencies: []string{"app-a"},
+	}))
+	check(t, repo.Commit("first"))
+
+	var mu sync.Mutex
+	events := make([]string, 0)
+
+	w := NewWorld(t, ".tmp/repo")
+	w.ProcessManager.Interceptor.Config("Exec").Do(func(args ...interface{}) []interface{} {
+		n := args[1].(*Module).Name()
+		mu.Lock()
+		events = append(events, "start "+n)
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		events = append(events, "end "+n)
+		mu.Unlock()
+		return []interface{}{nil}
+	})
+
+	options := stdTestCmdOptions(nil)
+	options.MaxParallel = 2
+	_, err := w.System.BuildWorkspace(NoFilter, options)
+	check(t, err)
+
+	assert.Equal(t, []string{"start app-a", "end app-a", "start app-b", "end app-b"}, events)
+}
+
+func TestBuildWithEnvFile(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{
+		Name: "app-a",
+		Build: map[string]*Cmd{
+			"linux":   {Cmd: "./build.sh"},
+			"darwin":  {Cmd: "./build.sh"},
+			"windows": {Cmd: "powershell", Args: []string{"-ExecutionPolicy", "Bypass", "-File", ".\\build.ps1"}},
+		},
+		EnvFile: &EnvFile{Path: "build.env"},
+	}))
+
+	check(t, repo.WriteContent("app-a/build.env", "# settings\nexport FOO=\"bar\"\nMBT_MODULE_NAME=overridden\n"))
+	check(t, repo.WriteShellScript("app-a/build.sh", "echo $FOO-$MBT_MODULE_NAME"))
+	check(t, repo.WritePowershellScript("app-a/build.ps1", "write-host $Env:FOO-$Env:MBT_MODULE_NAME"))
+	check(t, repo.Commit("first"))
+
+	buff := new(bytes.Buffer)
+	_, err := NewWorld(t, ".tmp/repo").System.BuildCurrentBranch(NoFilter, stdTestCmdOptions(buff))
+	check(t, err)
+
+	assert.Equal(t, "bar-app-a\n", buff.String())
+}
+
+func TestBuildWithMissingRequiredEnvFile(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{
+		Name:    "app-a",
+		Build:   map[string]*Cmd{"default": {Cmd: "echo", Args: []string{"hello"}}},
+		EnvFile: &EnvFile{Path: "build.env", Required: true},
+	}))
+	check(t, repo.Commit("first"))
+
+	buff := new(bytes.Buffer)
+	_, err := NewWorld(t, ".tmp/repo").System.BuildCurrentBranch(NoFilter, stdTestCmdOptions(buff))
+
+	assert.EqualError(t, err, fmt.Sprintf(msgFailedBuild, "app-a"))
+	assert.EqualError(t, (err.(*e.E)).InnerError(), fmt.Sprintf(msgEnvFileNotFound, "build.env", "app-a"))
+}
+
+func TestBuildWithEnvFileOutsideModule(t *testing.T) {
+	for _, p := range []string{"../build.env", "nested/../../build.env", "/etc/build.env"} {
+		clean()
+		repo := NewTestRepo(t, ".tmp/repo")
+		check(t, repo.InitModuleWithOptions("app-a", &Spec{
+			Name:    "app-a",
+			Build:   map[string]*Cmd{"default": {Cmd: "echo", Args: []string{"hello"}}},
+			EnvFile: &EnvFile{Path: p},
+		}))
+		check(t, repo.WriteContent("build.env", "FOO=bar\n"))
+		check(t, repo.Commit("first"))
+
+		buff := new(bytes.Buffer)
+		_, err := NewWorld(t, ".tmp/repo").System.BuildCurrentBranch(NoFilter, stdTestCmdOptions(buff))
+
+		assert.EqualError(t, err, fmt.Sprintf(msgFailedBuild, "app-a"))
+		assert.EqualError(t, (err.(*e.E)).InnerError(), fmt.Sprintf(msgEnvFileOutsideModule, p, "app-a"))
+		assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+	}
+}
+
+func TestBuildWithExitCodes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires sh")
+	}
+
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{
+		Name:  "app-a",
+		Build: map[string]*Cmd{"default": {Cmd: "sh", Args: []string{"-c", "exit 2"}, SkipCodes: []int{2}}},
+	}))
+	check(t, repo.InitModuleWithOptions("app-b", &Spec{
+		Name:  "app-b",
+		Build: map[string]*Cmd{"default": {Cmd: "sh", Args: []string{"-c", "exit 3"}, SuccessCodes: []int{0, 3}}},
+	}))
+	check(t, repo.Commit("first"))
+
+	stages := make(map[string]CmdStage)
+	options := CmdOptionsWithStdIO(func(a *Module, s CmdStage, err error) {
+		stages[a.Name()] = s
+	})
+
+	w := NewWorld(t, ".tmp/repo")
+	summary, err := w.System.BuildCurrentBranch(NoFilter, options)
+	check(t, err)
+
+	assert.Len(t, summary.Completed, 1)
+	assert.Len(t, summary.Skipped, 1)
+	assert.Equal(t, "app-b", summary.Completed[0].Module.Name())
+	assert.Equal(t, "app-a", summary.Skipped[0].Name())
+	assert.Equal(t, CmdStageSkipBuild, stages["app-a"])
+	assert.Equal(t, CmdStageAfterBuild, stages["app-b"])
+}
+
+func TestBuildWithUnexpectedExitCode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires sh")
+	}
+
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{
+		Name:  "app-a",
+		Build: map[string]*Cmd{"default": {Cmd: "sh", Args: []string{"-c", "exit 0"}, SuccessCodes: []int{3}}},
+	}))
+	check(t, repo.Commit("first"))
+
+	w := NewWorld(t, ".tmp/repo")
+	_, err := w.System.BuildCurrentBranch(NoFilter, stdTestCmdOptions(new(bytes.Buffer)))
+
+	assert.EqualError(t, err, fmt.Sprintf(msgFailedBuild, "app-a"))
+	assert.EqualError(t, (err.(*e.E)).InnerError(), fmt.Sprintf(msgUnexpectedExitCode, 0))
+}
+
+func TestBuildWithArtifacts(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires sh")
+	}
+
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{
+		Name:  "app-a",
+		Build: map[string]*Cmd{"default": {Cmd: "sh", Args: []string{"-c", "mkdir -p out && printf test > out/app"}}},
+		Artifacts: []*Artifact{
+			{Path: "out/app", SHA256: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"},
+		},
+	}))
+	check(t, repo.Commit("first"))
+
+	w := NewWorld(t, ".tmp/repo")
+	summary, err := w.System.BuildCurrentBranch(NoFilter, CmdOptionsWithStdIO(noopCb))
+	check(t, err)
+
+	assert.Len(t, summary.Completed, 1)
+	assert.Equal(t, map[string]string{
+		"out/app": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+	}, summary.Completed[0].Artifacts)
+}
+
+func TestBuildWithMissingArtifact(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires sh")
+	}
+
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{
+		Name:      "app-a",
+		Build:     map[string]*Cmd{"default": {Cmd: "sh", Args: []string{"-c", "exit 0"}}},
+		Artifacts: []*Artifact{{Path: "out/app"}},
+	}))
+	check(t, repo.Commit("first"))
+
+	w := NewWorld(t, ".tmp/repo")
+	_, err := w.System.BuildCurrentBranch(NoFilter, CmdOptionsWithStdIO(noopCb))
+
+	assert.EqualError(t, err, fmt.Sprintf(msgFailedBuild, "app-a"))
+	assert.EqualError(t, (err.(*e.E)).InnerError(), fmt.Sprintf(msgArtifactNotFound, "out/app", "app-a"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestBuildWithArtifactChecksumMismatch(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires sh")
+	}
+
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{
+		Name:      "app-a",
+		Build:     map[string]*Cmd{"default": {Cmd: "sh", Args: []string{"-c", "printf test > app"}}},
+		Artifacts: []*Artifact{{Path: "app", SHA256: "abc"}},
+	}))
+	check(t, repo.Commit("first"))
+
+	w := NewWorld(t, ".tmp/repo")
+	_, err := w.System.BuildCurrentBranch(NoFilter, CmdOptionsWithStdIO(noopCb))
+
+	assert.EqualError(t, err, fmt.Sprintf(msgFailedBuild, "app-a"))
+	assert.EqualError(t, (err.(*e.E)).InnerError(), fmt.Sprintf(msgArtifactChecksumMismatch, "app", "app-a", "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", "abc"))
+}
+
+func TestPrefixWriter(t *testing.T) {
+	buff := new(bytes.Buffer)
+	w := &prefixWriter{w: buff, prefix: []byte("[app-a] ")}
+
+	w.Write([]byte("hel"))
+	assert.Equal(t, "", buff.String())
+
+	w.Write([]byte("lo\nwor"))
+	assert.Equal(t, "[app-a] hello\n", buff.String())
+
+	w.Write([]byte("ld\nfoo\nbar"))
+	assert.Equal(t, "[app-a] hello\n[app-a] world\n[app-a] foo\n", buff.String())
+
+	check(t, w.Flush())
+	assert.Equal(t, "[app-a] hello\n[app-a] world\n[app-a] foo\n[app-a] bar\n", buff.String())
+
+	check(t, w.Flush())
+	assert.Equal(t, "[app-a] hello\n[app-a] world\n[app-a] foo\n[app-a] bar\n", buff.String())
+}
+
+// lineLimitWriter fails after writing the specified number of times.
+type lineLimitWriter struct {
+	bytes.Buffer
+	limit int
+}
+
+func (w *lineLimitWriter) Write(p []byte) (int, error) {
+	if w.limit == 0 {
+		return 0, errors.New("limit reached")
+	}
+	w.limit--
+	return w.Buffer.Write(p)
+}
+
+func TestPrefixWriterWithFailingWriter(t *testing.T) {
+	out := &lineLimitWriter{limit: 1}
+	w := &prefixWriter{w: out, prefix: []byte("[app-a] ")}
+
+	n, err := w.Write([]byte("hel"))
+	check(t, err)
+	assert.Equal(t, 3, n)
+
+	n, err = w.Write([]byte("lo\nworld\nfoo"))
+	assert.EqualError(t, err, "limit reached")
+	assert.Equal(t, 3, n)
+	assert.Equal(t, "[app-a] hello\n", out.String())
+
+	n, err = w.Write([]byte("bar\n"))
+	assert.EqualError(t, err, "limit reached")
+	assert.Equal(t, 0, n)
+
+	out.limit = 1
+	n, err = w.Write([]byte("world\n"))
+	check(t, err)
+	assert.Equal(t, 6, n)
+	assert.Equal(t, "[app-a] hello\n[app-a] world\n", out.String())
+}
+
+func TestSameWriter(t *testing.T) {
+	buff := new(bytes.Buffer)
+	assert.True(t, sameWriter(buff, buff))
+	assert.False(t, sameWriter(buff, new(bytes.Buffer)))
+	assert.False(t, sameWriter(buff, nil))
+	assert.True(t, sameWriter(nil, nil))
+
+	type funcWriter struct {
+		io.Writer
+		f func()
+	}
+	assert.False(t, sameWriter(funcWriter{Writer: buff}, funcWriter{Writer: buff}))
+}
+
+func TestPrefixOutputWithoutPrefix(t *testing.T) {
+	options := stdTestCmdOptions(new(bytes.Buffer))
+	o, flush := prefixOutput(options, nil)
+	flush()
+
+	assert.True(t, options == o)
+}
+
+func TestParallelBuildWithOutputPrefix(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	for _, n := range []string{"app-a", "app-b", "app-c"} {
+		check(t, repo.InitModuleWithOptions(n, &Spec{Name: n, Build: map[string]*Cmd{"default": {Cmd: "echo"}}}))
+	}
+	check(t, repo.Commit("first"))
+
+	w := NewWorld(t, ".tmp/repo")
+	w.ProcessManager.Interceptor.Config("Exec").Do(func(args ...interface{}) []interface{} {
+		n := args[1].(*Module).Name()
+		options := args[2].(*CmdOptions)
+		for i := 0; i < 3; i++ {
+			io.WriteString(options.Stdout, n)
+			time.Sleep(time.Millisecond)
+			io.WriteString(options.Stdout, fmt.Sprintf(" line %d\n", i))
+		}
+		io.WriteString(options.Stderr, "done")
+		return []interface{}{nil}
+	})
+
+	buff := new(bytes.Buffer)
+	options := stdTestCmdOptions(buff)
+	options.MaxParallel = 3
+	options.OutputPrefix = ModuleNamePrefix
+	_, err := w.System.BuildWorkspace(NoFilter, options)
+	check(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(buff.String(), "\n"), "\n")
+	assert.Len(t, lines, 12)
+
+	perModule := make(map[string][]string)
+	for _, l := range lines {
+		i := strings.Index(l, "] ")
+		if !assert.True(t, i > 0, "line without a prefix: %s", l) {
+			continue
+		}
+		n := strings.TrimPrefix(l[:i], "[")
+		perModule[n] = append(perModule[n], l[i+2:])
+	}
+
+	for _, n := range []string{"app-a", "app-b", "app-c"} {
+		assert.Equal(t, []string{n + " line 0", n + " line 1", n + " line 2", "done"}, perModule[n])
+	}
+}
+
+func TestBuildWithExtraArgs(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{Name: "app-a", Build: map[string]*Cmd{"default": {Cmd: "echo", Args: []string{"build"}}}}))
+	check(t, repo.Commit("first"))
+
+	w := NewWorld(t, ".tmp/repo")
+	m, err := w.System.ManifestByWorkspace()
+	check(t, err)
+
+	var received []string
+	w.ProcessManager.Interceptor.Config("Exec").Do(func(args ...interface{}) []interface{} {
+		for _, a := range args[4:] {
+			received = append(received, a.(string))
+		}
+		return []interface{}{nil}
+	})
+
+	buff := new(bytes.Buffer)
+	options := stdTestCmdOptions(buff)
+	options.ExtraArgs = []string{"--no-cache", "--verbose"}
+	summary, err := w.System.BuildWorkspace(NoFilter, options)
+	check(t, err)
+
+	assert.Equal(t, []string{"build", "--no-cache", "--verbose"}, received)
+	assert.Equal(t, m.Modules[0].Version(), summary.Manifest.Modules[0].Version())
+}
+
+func TestSerialBuildWithPriority(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	build := map[string]*Cmd{"default": {Cmd: "echo"}}
+	check(t, repo.InitModuleWithOptions("lib-a", &Spec{Name: "lib-a", Build: build, Properties: map[string]interface{}{"priority": 5}}))
+	check(t, repo.InitModuleWithOptions("app-b", &Spec{Name: "app-b", Build: build, Properties: map[string]interface{}{"priority": 1}}))
+	check(t, repo.InitModuleWithOptions("app-c", &Spec{Name: "app-c", Build: build, Dependencies: []string{"lib-a"}, Properties: map[string]interface{}{"priority": -1}}))
+	check(t, repo.Commit("first"))
+
+	w := NewWorld(t, ".tmp/repo")
+	var order []string
+	w.ProcessManager.Interceptor.Config("Exec").Do(func(args ...interface{}) []interface{} {
+		order = append(order, args[1].(*Module).Name())
+		return []interface{}{nil}
+	})
+
+	buff := new(bytes.Buffer)
+	_, err := w.System.BuildWorkspace(NoFilter, stdTestCmdOptions(buff))
+	check(t, err)
+
+	assert.Equal(t, []string{"app-b", "lib-a", "app-c"}, order)
+}
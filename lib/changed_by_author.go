@@ -0,0 +1,79 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"sort"
+	"strings"
+)
+
+func (s *stdSystem) ModulesChangedByAuthor(from, to, authorEmail string) (Modules, error) {
+	f, err := s.Repo.GetCommit(from)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := s.Repo.GetCommit(to)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := make(map[string]*Module)
+	// Modules discovered in the previous commit by the author are
+	// reused since most of the trees are shared between commits.
+	var prior Modules
+	err = s.Repo.WalkCommitsInRange(f, t, func(commit Commit) error {
+		if commit.ParentCount() > 1 || !strings.EqualFold(commit.AuthorEmail(), authorEmail) {
+			return nil
+		}
+
+		mods, err := s.Discover.ModulesInCommitSince(commit, prior)
+		if err != nil {
+			return err
+		}
+		prior = mods
+
+		deltas, err := s.Repo.Changes(commit)
+		if err != nil {
+			return err
+		}
+
+		mods, err = s.Reducer.Reduce(mods, deltas)
+		if err != nil {
+			return err
+		}
+
+		// Commits are visited from the oldest to the newest, therefore,
+		// the most recent state of each module is retained.
+		for _, m := range mods {
+			changed[m.Name()] = m
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	r := make(modulesByNameSorter, 0, len(changed))
+	for _, m := range changed {
+		r = append(r, m)
+	}
+	sort.Sort(r)
+
+	return Modules(r), nil
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModulesChangedByAuthor(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.InitModule("app-c"))
+	check(t, repo.InitModule("app-d"))
+	check(t, repo.Commit("first"))
+	first := repo.LastCommit.String()
+
+	check(t, repo.AppendContent("app-c/foo", "bar"))
+	check(t, repo.CommitAs("second", "bob", "bob@wonderland.com"))
+
+	check(t, repo.AppendContent("app-b/foo", "bar"))
+	check(t, repo.CommitAs("third", "alice", "Alice@Wonderland.com"))
+
+	check(t, repo.AppendContent("app-a/foo", "bar"))
+	check(t, repo.Commit("fourth"))
+	last := repo.LastCommit.String()
+
+	check(t, repo.AppendContent("app-d/foo", "bar"))
+	check(t, repo.Commit("fifth"))
+
+	system := NewWorld(t, ".tmp/repo").System
+
+	mods, err := system.ModulesChangedByAuthor(first, last, "alice@wonderland.com")
+	check(t, err)
+	assert.Equal(t, []string{"app-a", "app-b"}, mods.names())
+
+	mods, err = system.ModulesChangedByAuthor(first, last, "bob@wonderland.com")
+	check(t, err)
+	assert.Equal(t, []string{"app-c"}, mods.names())
+
+	mods, err = system.ModulesChangedByAuthor(first, last, "carol@wonderland.com")
+	check(t, err)
+	assert.Empty(t, mods)
+}
+
+func TestModulesChangedByAuthorIgnoresMergeCommits(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.Commit("first"))
+	first := repo.LastCommit.String()
+
+	check(t, repo.SwitchToBranch("feature"))
+	check(t, repo.AppendContent("app-a/foo", "bar"))
+	check(t, repo.CommitAs("second", "bob", "bob@wonderland.com"))
+
+	check(t, repo.SwitchToBranch("master"))
+	check(t, repo.AppendContent("app-b/foo", "bar"))
+	check(t, repo.CommitAs("third", "bob", "bob@wonderland.com"))
+
+	// Merge commit is authored by alice
+	merge, err := repo.SimpleMerge("feature", "master")
+	check(t, err)
+
+	mods, err := NewWorld(t, ".tmp/repo").System.ModulesChangedByAuthor(first, merge.String(), "alice@wonderland.com")
+	check(t, err)
+
+	assert.Empty(t, mods)
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import "sort"
+
+// ModulesDelta describes the differences between two sets of modules.
+type ModulesDelta struct {
+	// Added modules are only in the head set.
+	Added Modules
+	// Removed modules are only in the base set.
+	Removed Modules
+	// Changed modules are in both sets but are not equal.
+	// See Module.Equal. Modules from the head set are listed.
+	Changed Modules
+}
+
+// DiffModules compares two sets of modules by name.
+func DiffModules(base, head Modules) *ModulesDelta {
+	delta := &ModulesDelta{Added: Modules{}, Removed: Modules{}, Changed: Modules{}}
+	baseIndex := base.indexByName()
+	headIndex := head.indexByName()
+
+	for _, m := range head {
+		b, ok := baseIndex[m.Name()]
+		if !ok {
+			delta.Added = append(delta.Added, m)
+		} else if !b.Equal(m) {
+			delta.Changed = append(delta.Changed, m)
+		}
+	}
+
+	for _, m := range base {
+		if _, ok := headIndex[m.Name()]; !ok {
+			delta.Removed = append(delta.Removed, m)
+		}
+	}
+
+	return delta
+}
+
+// DiffFromDirs compares the modules stored in two directories.
+// This is useful when base and head are checked out into separate
+// directories without a shared git history (e.g. shallow clones).
+func DiffFromDirs(baseDir, headDir string) (*ModulesDelta, error) {
+	return DiffFromDirsWithOptions(baseDir, headDir, &DiscoverOptions{})
+}
+
+// DiffFromDirsWithOptions is similar to DiffFromDirs except the modules
+// are discovered with the specified options (see ModulesInDirWithOptions).
+func DiffFromDirsWithOptions(baseDir, headDir string, options *DiscoverOptions) (*ModulesDelta, error) {
+	base, err := ModulesInDirWithOptions(baseDir, options)
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := ModulesInDirWithOptions(headDir, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return DiffModules(base, head), nil
+}
+
+// DiffCommits compares the modules discovered in two commits.
+// Commits can be specified with revision expressions such as
+// HEAD~1 (see Repo.ResolveRevision). Modules deleted between the
+// commits are listed in Removed.
+func (s *stdSystem) DiffCommits(from, to string) (*ModulesDelta, error) {
+	f, err := s.Repo.ResolveRevision(from)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := s.Repo.ResolveRevision(to)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := s.Discover.ModulesInCommit(f)
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := s.Discover.ModulesInCommit(t)
+	if err != nil {
+		return nil, err
+	}
+
+	return DiffModules(base, head), nil
+}
+
+// Edge is a requires dependency from one module to another.
+type Edge struct {
+	From string
+	To   string
+}
+
+// GraphDelta describes the differences between the dependency
+// graphs of two sets of modules.
+type GraphDelta struct {
+	// Added edges are only in the head graph.
+	Added []Edge
+	// Removed edges are only in the base graph.
+	Removed []Edge
+}
+
+// DiffGraphs compares the requires dependencies of two sets of modules.
+// Edges in the delta are sorted by From and To names.
+func DiffGraphs(base, head Modules) *GraphDelta {
+	baseEdges := edges(base)
+	headEdges := edges(head)
+
+	delta := &GraphDelta{Added: []Edge{}, Removed: []Edge{}}
+	for edge := range headEdges {
+		if !baseEdges[edge] {
+			delta.Added = append(delta.Added, edge)
+		}
+	}
+
+	for edge := range baseEdges {
+		if !headEdges[edge] {
+			delta.Removed = append(delta.Removed, edge)
+		}
+	}
+
+	sortEdges(delta.Added)
+	sortEdges(delta.Removed)
+
+	return delta
+}
+
+func edges(mods Modules) map[Edge]bool {
+	set := make(map[Edge]bool)
+	for _, m := range mods {
+		for _, r := range m.Requires() {
+			set[Edge{From: m.Name(), To: r.Name()}] = true
+		}
+	}
+
+	return set
+}
+
+func sortEdges(edges []Edge) {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+}
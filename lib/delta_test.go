@@ -0,0 +1,162 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeDirModule(t *testing.T, dir, name string, files map[string]string) {
+	p := filepath.Join(dir, name)
+	check(t, os.MkdirAll(p, 0755))
+	check(t, ioutil.WriteFile(filepath.Join(p, ".mbt.yml"), []byte("name: "+name+"\n"), 0644))
+	for f, c := range files {
+		check(t, ioutil.WriteFile(filepath.Join(p, f), []byte(c), 0644))
+	}
+}
+
+func TestDiffFromDirs(t *testing.T) {
+	clean()
+	base := ".tmp/base"
+	head := ".tmp/head"
+
+	writeDirModule(t, base, "app-a", map[string]string{"main.go": "a"})
+	writeDirModule(t, base, "app-b", map[string]string{"main.go": "b"})
+	writeDirModule(t, base, "app-c", map[string]string{"main.go": "c"})
+
+	writeDirModule(t, head, "app-a", map[string]string{"main.go": "a"})
+	writeDirModule(t, head, "app-b", map[string]string{"main.go": "b2"})
+	writeDirModule(t, head, "app-d", map[string]string{"main.go": "d"})
+
+	delta, err := DiffFromDirs(base, head)
+	check(t, err)
+
+	assert.Len(t, delta.Added, 1)
+	assert.Equal(t, "app-d", delta.Added[0].Name())
+	assert.Len(t, delta.Removed, 1)
+	assert.Equal(t, "app-c", delta.Removed[0].Name())
+	assert.Len(t, delta.Changed, 1)
+	assert.Equal(t, "app-b", delta.Changed[0].Name())
+}
+
+func TestModulesInDirMatchesGitVersion(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.WriteContent("app-a/main.go", "package main"))
+	check(t, repo.WriteContent("app-a/src/lib.go", "package lib"))
+	check(t, repo.Commit("first"))
+
+	m, err := NewWorld(t, ".tmp/repo").System.ManifestByCommit(repo.LastCommit.String())
+	check(t, err)
+
+	mods, err := ModulesInDir(".tmp/repo")
+	check(t, err)
+
+	assert.Len(t, mods, 1)
+	assert.Equal(t, m.Modules[0].Version(), mods[0].Version())
+}
+
+func TestDiffFromDirsWithMovedModule(t *testing.T) {
+	clean()
+	writeDirModule(t, ".tmp/base", "app-a", map[string]string{"main.go": "a"})
+	writeDirModule(t, ".tmp/head/services", "app-a", map[string]string{"main.go": "a"})
+
+	delta, err := DiffFromDirs(".tmp/base", ".tmp/head")
+	check(t, err)
+
+	assert.Len(t, delta.Added, 0)
+	assert.Len(t, delta.Removed, 0)
+	assert.Len(t, delta.Changed, 0)
+}
+
+func TestDiffModulesWithChangedCommand(t *testing.T) {
+	base, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Build: map[string]*Cmd{"default": {Cmd: "make"}}}, nil),
+	})
+	check(t, err)
+
+	head, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Build: map[string]*Cmd{"default": {Cmd: "make", Args: []string{"all"}}}}, nil),
+	})
+	check(t, err)
+
+	delta := DiffModules(base, head)
+
+	assert.Len(t, delta.Added, 0)
+	assert.Len(t, delta.Removed, 0)
+	assert.Len(t, delta.Changed, 1)
+	assert.Equal(t, "app-a", delta.Changed[0].Name())
+}
+
+func TestDiffGraphs(t *testing.T) {
+	base, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-b", "app-c"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c"}, nil),
+	})
+	check(t, err)
+
+	head, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-b"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b", Dependencies: []string{"app-d"}}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c", Dependencies: []string{"app-d"}}, nil),
+		newModuleMetadata("app-d", "d", &Spec{Name: "app-d"}, nil),
+	})
+	check(t, err)
+
+	delta := DiffGraphs(base, head)
+
+	assert.Equal(t, []Edge{{From: "app-b", To: "app-d"}, {From: "app-c", To: "app-d"}}, delta.Added)
+	assert.Equal(t, []Edge{{From: "app-a", To: "app-c"}}, delta.Removed)
+}
+
+func TestDiffCommitsWithRemovedModule(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.Commit("first"))
+	first := repo.LastCommit.String()
+
+	check(t, repo.Remove("app-b"))
+	check(t, repo.InitModule("app-c"))
+	check(t, repo.Commit("second"))
+	second := repo.LastCommit.String()
+
+	delta, err := NewWorld(t, ".tmp/repo").System.DiffCommits(first, second)
+	check(t, err)
+
+	assert.Equal(t, []string{"app-c"}, delta.Added.names())
+	assert.Equal(t, []string{"app-b"}, delta.Removed.names())
+	assert.Len(t, delta.Changed, 0)
+}
+
+func TestDiffCommitsWithUnknownRevision(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+
+	_, err := NewWorld(t, ".tmp/repo").System.DiffCommits("unknown", repo.LastCommit.String())
+
+	assert.Error(t, err)
+}
@@ -17,11 +17,20 @@ package lib
 
 import (
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
+	"hash"
 	"io"
 	"io/ioutil"
+	"os"
+	"path"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	yaml "github.com/go-yaml/yaml"
 	"github.com/mbtproject/mbt/e"
@@ -41,92 +50,438 @@ type moduleMetadata struct {
 type moduleMetadataSet []*moduleMetadata
 
 type stdDiscover struct {
-	Repo Repo
-	Log  Log
+	Repo        Repo
+	Log         Log
+	Roots       []string
+	VersionHash string
+	Transform   SpecTransform
+	MaxDepth    *int
 }
 
-const configFileName = ".mbt.yml"
+// SpecTransform modifies the spec of a module found in the specified
+// repository relative directory during discovery.
+type SpecTransform func(dir string, spec *Spec)
+
+const (
+	// VersionHashSHA1 produces module versions compatible with git
+	// object ids. This is the default.
+	VersionHashSHA1 = "sha1"
+	// VersionHashSHA256 produces module versions prefixed with sha256-.
+	VersionHashSHA256 = "sha256"
+	// VersionUnresolved is the version of a module that cannot be
+	// calculated without the rest of the repository (see ModuleAtTree).
+	VersionUnresolved = "unresolved"
+)
+
+// DiscoverOptions specifies the options used to create a Discover.
+type DiscoverOptions struct {
+	// Roots restricts discovery to modules under these repository
+	// relative paths. Entire repository is scanned when Roots is empty.
+	Roots []string
+	// VersionHash is the algorithm used to calculate module versions.
+	// VersionHashSHA1 is used when empty.
+	VersionHash string
+	// Transform is invoked for each spec after it is parsed.
+	// It runs before module versions are calculated and dependencies
+	// are resolved, therefore it can change any field in the spec.
+	Transform SpecTransform
+	// MaxDepth is the maximum number of directories a module can be
+	// nested in from the repository root (e.g. services/app-a is at
+	// depth 2 and 0 is just the module at the root). Modules nested
+	// deeper are ignored. Modules are discovered at any depth when
+	// MaxDepth is nil or UnlimitedDiscoveryDepth.
+	MaxDepth *int
+}
+
+// UnlimitedDiscoveryDepth is the DiscoverOptions.MaxDepth used to
+// discover modules at any depth.
+const UnlimitedDiscoveryDepth = -1
+
+// discoveryDepth returns the maximum depth of the module directories
+// specified in DiscoverOptions.MaxDepth.
+func discoveryDepth(maxDepth *int) (int, error) {
+	if maxDepth == nil {
+		return UnlimitedDiscoveryDepth, nil
+	}
+
+	if *maxDepth < UnlimitedDiscoveryDepth {
+		return 0, e.NewErrorf(ErrClassUser, msgInvalidDiscoveryDepth, *maxDepth)
+	}
+
+	return *maxDepth, nil
+}
+
+const (
+	configFileName     = ".mbt.yml"
+	jsonConfigFileName = ".mbt.json"
+)
+
+// configFileNames are the names of the files accepted as module specs.
+var configFileNames = []string{configFileName, jsonConfigFileName}
+
+func isConfigFile(name string) bool {
+	for _, n := range configFileNames {
+		if name == n {
+			return true
+		}
+	}
+
+	return false
+}
 
 // NewDiscover creates an instance of standard discover implementation.
 func NewDiscover(repo Repo, l Log) Discover {
-	return &stdDiscover{Repo: repo, Log: l}
+	return NewDiscoverWithOptions(repo, l, &DiscoverOptions{})
+}
+
+// NewDiscoverWithOptions creates an instance of standard discover
+// implementation with the specified options.
+func NewDiscoverWithOptions(repo Repo, l Log, options *DiscoverOptions) Discover {
+	return &stdDiscover{
+		Repo:        repo,
+		Log:         l,
+		Roots:       normalizeRoots(options.Roots),
+		VersionHash: options.VersionHash,
+		Transform:   options.Transform,
+		MaxDepth:    options.MaxDepth,
+	}
 }
 
 func (d *stdDiscover) ModulesInCommit(commit Commit) (Modules, error) {
-	repo := d.Repo
+	return d.ModulesInCommitSince(commit, nil)
+}
+
+func (d *stdDiscover) ModulesInCommitSince(commit Commit, prior Modules) (Modules, error) {
+	maxDepth, err := discoveryDepth(d.MaxDepth)
+	if err != nil {
+		return nil, err
+	}
+
 	metadataSet := moduleMetadataSet{}
+	unchanged := make([]string, 0)
 
-	err := repo.WalkBlobs(commit, func(b Blob) error {
-		if b.Name() == configFileName {
-			var (
-				hash string
-				err  error
-			)
-			p := strings.TrimRight(b.Path(), "/")
-			if p != "" {
-				// We are not on the root, take the git sha for parent tree object.
-				hash, err = repo.EntryID(commit, p)
-				if err != nil {
-					return err
-				}
-			} else {
-				// We are on the root, take the commit sha.
-				hash = commit.ID()
-			}
+	for _, m := range prior {
+		meta := m.metadata
+		// Hash of the module at the root is the commit id and the hash
+		// of a module using version extensions is never the id of its
+		// tree. Therefore, they are always discovered from the commit
+		// along with the modules reading their dependencies from a file.
+		if meta.dir == "" || !isInRoots(meta.dir, d.Roots) || !isWithinDepth(meta.dir, maxDepth) {
+			continue
+		}
+
+		// Dependencies file could be outside the module directory.
+		if meta.spec.DependenciesFile != "" {
+			continue
+		}
+
+		// A module is not reusable if its directory is removed or changed.
+		id, err := d.Repo.EntryID(commit, meta.dir)
+		if err != nil || id != meta.hash {
+			continue
+		}
 
-			contents, err := repo.BlobContents(b)
+		dependentFileHashes, err := d.fileDependencyHashes(commit, meta.dir, meta.spec)
+		if err != nil {
+			return nil, err
+		}
+
+		metadataSet = append(metadataSet, newModuleMetadata(meta.dir, meta.hash, meta.spec, dependentFileHashes))
+		unchanged = append(unchanged, meta.dir)
+	}
+
+	err = d.Repo.WalkBlobsExcluding(commit, d.Roots, unchanged, maxDepth, func(b Blob) error {
+		if isConfigFile(b.Name()) {
+			metadata, err := d.metadataFromBlob(commit, b)
 			if err != nil {
 				return err
 			}
 
-			spec, err := newSpec(contents)
-			if err != nil {
-				return e.Wrapf(ErrClassUser, err, "error while parsing the spec at %v", b)
-			}
+			metadataSet = append(metadataSet, metadata)
+		}
+		return nil
+	})
 
-			// Discover the hashes for file dependencies of this module
-			dependentFileHashes := make(map[string]string)
-			for _, f := range spec.FileDependencies {
-				fh, err := repo.EntryID(commit, f)
-				if err != nil {
-					return e.Wrapf(ErrClassUser, err, msgFileDependencyNotFound, f, spec.Name, p)
-				}
+	if err != nil {
+		return nil, err
+	}
 
-				dependentFileHashes[f] = fh
-			}
+	return toModulesWithVersionHash(metadataSet, d.VersionHash)
+}
+
+// metadataFromBlob creates the metadata of the module declared by
+// the spec file in the commit.
+func (d *stdDiscover) metadataFromBlob(commit Commit, b Blob) (*moduleMetadata, error) {
+	var hash string
+	contents, err := d.Repo.BlobContents(b)
+	if err != nil {
+		return nil, err
+	}
 
-			metadataSet = append(metadataSet, newModuleMetadata(p, hash, spec, dependentFileHashes))
+	spec, err := newSpecFromFile(b.Name(), contents)
+	if err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, "error while parsing the spec at %v", b)
+	}
+
+	p := strings.TrimRight(b.Path(), "/")
+	d.transform(p, spec)
+	err = mergeDependenciesFile(p, spec, func(f string) ([]byte, error) {
+		return d.Repo.BlobContentsFromTree(commit, f)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(spec.VersionExtensions) > 0 {
+		hash, err = d.hashFilesWithExtensions(commit, p, spec.VersionExtensions)
+		if err != nil {
+			return nil, err
+		}
+	} else if p != "" {
+		// We are not on the root, take the git sha for parent tree object.
+		hash, err = d.Repo.EntryID(commit, p)
+		if err != nil {
+			return nil, err
 		}
+	} else {
+		// We are on the root, take the commit sha.
+		hash = commit.ID()
+	}
+
+	dependentFileHashes, err := d.fileDependencyHashes(commit, p, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return newModuleMetadata(p, hash, spec, dependentFileHashes), nil
+}
+
+// mergeDependenciesFile adds the dependencies listed in the
+// dependencies file of the spec (if any) to its dependencies.
+// Path of the file is added to the file dependencies so that the
+// changes to the file change the version of the module.
+// Read is invoked with the repository relative path of the file.
+func mergeDependenciesFile(dir string, spec *Spec, read func(p string) ([]byte, error)) error {
+	if spec.DependenciesFile == "" {
 		return nil
+	}
+
+	p := path.Join(dir, spec.DependenciesFile)
+	contents, err := read(p)
+	if err != nil {
+		return e.Wrapf(ErrClassUser, err, msgDependenciesFileNotFound, spec.DependenciesFile, spec.Name, dir)
+	}
+
+	seen := make(map[string]bool)
+	for _, d := range spec.Dependencies {
+		seen[d] = true
+	}
+
+	for _, d := range parseDependenciesFile(contents) {
+		if !seen[d] {
+			seen[d] = true
+			spec.Dependencies = append(spec.Dependencies, d)
+		}
+	}
+
+	for _, f := range spec.FileDependencies {
+		if f == p {
+			return nil
+		}
+	}
+	spec.FileDependencies = append(spec.FileDependencies, p)
+
+	return nil
+}
+
+// parseDependenciesFile returns the dependencies listed in a
+// dependencies file. Each line contains a module name or path.
+// Blank lines and the lines starting with # are ignored.
+func parseDependenciesFile(contents []byte) []string {
+	r := make([]string, 0)
+	for _, line := range strings.Split(string(contents), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			r = append(r, line)
+		}
+	}
+	return r
+}
+
+// fileDependencyHashes discovers the hashes for file dependencies
+// of the module in the commit.
+func (d *stdDiscover) fileDependencyHashes(commit Commit, dir string, spec *Spec) (map[string]string, error) {
+	dependentFileHashes := make(map[string]string)
+	for _, f := range spec.FileDependencies {
+		fh, err := d.Repo.EntryID(commit, f)
+		if err != nil {
+			return nil, e.Wrapf(ErrClassUser, err, msgFileDependencyNotFound, f, spec.Name, dir)
+		}
+
+		dependentFileHashes[f] = fh
+	}
+
+	return dependentFileHashes, nil
+}
+
+// ModuleAtTree returns the module defined by the spec file at the root
+// of the specified tree. Tree alone is not sufficient to resolve the
+// dependencies, therefore returned module does not have any links to
+// its dependencies. Version of the module is calculated in the same
+// way as ModulesInCommit when it does not depend on anything outside
+// the tree (i.e. it has no dependencies, file dependencies or version
+// extensions). Otherwise it is VersionUnresolved.
+// Path of the module is unknown and always empty.
+func (d *stdDiscover) ModuleAtTree(treeID string) (*Module, error) {
+	name := configFileName
+	contents, err := d.Repo.TreeBlobContents(treeID, name)
+	if err != nil {
+		// Fallback to JSON spec and report the original error if
+		// that is not found either.
+		var jsonErr error
+		name = jsonConfigFileName
+		contents, jsonErr = d.Repo.TreeBlobContents(treeID, name)
+		if jsonErr != nil {
+			return nil, err
+		}
+	}
+
+	spec, err := newSpecFromFile(name, contents)
+	if err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgFailedTreeSpecParse, treeID)
+	}
+	d.transform("", spec)
+	err = mergeDependenciesFile("", spec, func(f string) ([]byte, error) {
+		return d.Repo.TreeBlobContents(treeID, f)
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	meta := newModuleMetadata("", treeID, spec, nil)
+	if len(spec.Dependencies) > 0 || len(spec.FileDependencies) > 0 || len(spec.VersionExtensions) > 0 {
+		mod := newModule(meta, nil)
+		mod.version = VersionUnresolved
+		return mod, nil
+	}
 
+	mods, err := linkModules(moduleMetadataSet{meta}, d.versionHash())
 	if err != nil {
 		return nil, err
 	}
 
-	return toModules(metadataSet)
+	return mods[0], nil
+}
+
+func (d *stdDiscover) versionHash() string {
+	if d.VersionHash == "" {
+		return VersionHashSHA1
+	}
+
+	return d.VersionHash
+}
+
+func (d *stdDiscover) transform(dir string, spec *Spec) {
+	if d.Transform != nil {
+		d.Transform(dir, spec)
+	}
+}
+
+// hashFilesWithExtensions calculates a hash of the files under the
+// specified directory having one of the specified extensions.
+func (d *stdDiscover) hashFilesWithExtensions(commit Commit, dir string, extensions []string) (string, error) {
+	var paths []string
+	if dir != "" {
+		paths = []string{dir}
+	}
+
+	entries := make([]string, 0)
+	err := d.Repo.WalkBlobsUnder(commit, paths, func(b Blob) error {
+		if isVersionedFile(b.Name(), extensions) {
+			// Use the path relative to module directory so that the
+			// version is not changed when the module is moved.
+			p := strings.TrimPrefix(b.Path()+b.Name(), dir+"/")
+			entries = append(entries, p+":"+b.ID())
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Submodules are included regardless of the extensions because
+	// their content is only identified by the commit they point to.
+	err = d.Repo.WalkGitlinksUnder(commit, paths, func(b Blob) error {
+		p := strings.TrimPrefix(b.Path()+b.Name(), dir+"/")
+		entries = append(entries, p+":"+b.ID())
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return hashEntries(entries), nil
+}
+
+// hashEntries calculates a hash of the specified entries
+// regardless of their order.
+func hashEntries(entries []string) string {
+	sort.Strings(entries)
+	h := sha1.New()
+	for _, entry := range entries {
+		io.WriteString(h, entry)
+		io.WriteString(h, "\n")
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
 }
 
 func (d *stdDiscover) ModulesInWorkspace() (Modules, error) {
+	maxDepth, err := discoveryDepth(d.MaxDepth)
+	if err != nil {
+		return nil, err
+	}
+
 	metadataSet := moduleMetadataSet{}
 	absRepoPath, err := filepath.Abs(d.Repo.Path())
 	if err != nil {
 		return nil, e.Wrap(ErrClassInternal, err)
 	}
 
-	configFiles, err := d.Repo.FindAllFilesInWorkspace([]string{configFileName, "/**/" + configFileName})
+	pathSpec := make([]string, 0)
+	for _, n := range configFileNames {
+		if len(d.Roots) == 0 {
+			pathSpec = append(pathSpec, n, "/**/"+n)
+		}
+		for _, r := range d.Roots {
+			pathSpec = append(pathSpec, r+"/"+n, r+"/**/"+n)
+		}
+	}
+
+	configFiles, err := d.Repo.FindAllFilesInWorkspace(pathSpec)
 
 	if err != nil {
 		return nil, err
 	}
 
 	for _, entry := range configFiles {
-		if filepath.Base(entry) != configFileName {
+		if !isConfigFile(filepath.Base(entry)) {
 			// Fast path directories that matched path spec
 			// e.g. .mbt.yml/abc/foo
 			continue
 		}
 
+		// Sanitize the module path
+		dir := filepath.ToSlash(filepath.Dir(entry))
+		if dir == "." {
+			dir = ""
+		} else {
+			dir = strings.TrimRight(dir, "/")
+		}
+
+		if !isWithinDepth(dir, maxDepth) {
+			continue
+		}
+
 		path := filepath.Join(absRepoPath, entry)
 
 		contents, err := ioutil.ReadFile(path)
@@ -134,17 +489,17 @@ func (d *stdDiscover) ModulesInWorkspace() (Modules, error) {
 			return nil, e.Wrapf(ErrClassInternal, err, "error whilst reading file contents at path %s", path)
 		}
 
-		spec, err := newSpec(contents)
+		spec, err := newSpecFromFile(filepath.Base(entry), contents)
 		if err != nil {
 			return nil, e.Wrapf(ErrClassUser, err, "error whilst parsing spec at %s", path)
 		}
 
-		// Sanitize the module path
-		dir := filepath.ToSlash(filepath.Dir(entry))
-		if dir == "." {
-			dir = ""
-		} else {
-			dir = strings.TrimRight(dir, "/")
+		d.transform(dir, spec)
+		err = mergeDependenciesFile(dir, spec, func(f string) ([]byte, error) {
+			return ioutil.ReadFile(filepath.Join(absRepoPath, filepath.FromSlash(f)))
+		})
+		if err != nil {
+			return nil, err
 		}
 
 		hash := "local"
@@ -155,7 +510,221 @@ func (d *stdDiscover) ModulesInWorkspace() (Modules, error) {
 		return nil, e.Wrap(ErrClassInternal, err)
 	}
 
-	return toModules(metadataSet)
+	return toModulesWithVersionHash(metadataSet, d.VersionHash)
+}
+
+// ModulesInDir discovers the modules stored in a directory without
+// using git. Module versions are calculated from the directory content
+// in the same way git calculates object ids. Therefore, they match the
+// versions calculated from a commit containing the same content, except
+// for the module at the root directory.
+// Files ignored by git are not excluded and should not be present in
+// the directory.
+// Module directories are hashed concurrently.
+func ModulesInDir(dir string) (Modules, error) {
+	return ModulesInDirWithOptions(dir, &DiscoverOptions{})
+}
+
+// ModulesInDirWithOptions is similar to ModulesInDir except the
+// versions are calculated with the VersionHash in options, each spec
+// is passed to the Transform in options after it is parsed and the
+// directories deeper than the MaxDepth in options are not visited.
+func ModulesInDirWithOptions(dir string, options *DiscoverOptions) (Modules, error) {
+	maxDepth, err := discoveryDepth(options.MaxDepth)
+	if err != nil {
+		return nil, err
+	}
+
+	metadataSet := moduleMetadataSet{}
+
+	err = filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return e.Wrapf(ErrClassUser, err, msgFailedLocalPath, p)
+		}
+
+		if fi.IsDir() && fi.Name() == ".git" {
+			return filepath.SkipDir
+		}
+
+		if fi.IsDir() && p != dir {
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				return e.Wrap(ErrClassInternal, err)
+			}
+			if !isWithinDepth(filepath.ToSlash(rel), maxDepth) {
+				return filepath.SkipDir
+			}
+		}
+
+		if fi.IsDir() || !isConfigFile(fi.Name()) {
+			return nil
+		}
+
+		contents, err := ioutil.ReadFile(p)
+		if err != nil {
+			return e.Wrapf(ErrClassInternal, err, "error whilst reading file contents at path %s", p)
+		}
+
+		spec, err := newSpecFromFile(fi.Name(), contents)
+		if err != nil {
+			return e.Wrapf(ErrClassUser, err, "error whilst parsing spec at %s", p)
+		}
+
+		moduleDir := filepath.Dir(p)
+		rel, err := filepath.Rel(dir, moduleDir)
+		if err != nil {
+			return e.Wrap(ErrClassInternal, err)
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			rel = ""
+		}
+
+		if options.Transform != nil {
+			options.Transform(rel, spec)
+		}
+		err = mergeDependenciesFile(rel, spec, func(f string) ([]byte, error) {
+			return ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(f)))
+		})
+		if err != nil {
+			return err
+		}
+
+		// Hashes are calculated by hashModuleDirs.
+		metadataSet = append(metadataSet, newModuleMetadata(rel, "", spec, nil))
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = hashModuleDirs(dir, metadataSet, runtime.NumCPU())
+	if err != nil {
+		return nil, err
+	}
+
+	return toModulesWithVersionHash(metadataSet, options.VersionHash)
+}
+
+// hashModuleDirs calculates the hashes of the module directories and
+// their file dependencies using up to the specified number of workers.
+// Each worker updates distinct elements in the set. Error of the first
+// module (in the order of the set) failing is returned.
+func hashModuleDirs(dir string, metadataSet moduleMetadataSet, workers int) error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	errs := make([]error, len(metadataSet))
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				errs[i] = hashModuleDir(dir, metadataSet[i])
+			}
+		}()
+	}
+
+	for i := range metadataSet {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func hashModuleDir(dir string, meta *moduleMetadata) error {
+	var err error
+	spec := meta.spec
+	moduleDir := filepath.Join(dir, filepath.FromSlash(meta.dir))
+	if len(spec.VersionExtensions) > 0 {
+		meta.hash, err = hashDirFilesWithExtensions(moduleDir, spec.VersionExtensions)
+	} else {
+		meta.hash, err = hashPath(moduleDir)
+	}
+	if err != nil {
+		return err
+	}
+
+	meta.dependentFileHashes = make(map[string]string)
+	for _, f := range spec.FileDependencies {
+		fh, err := hashPath(filepath.Join(dir, filepath.FromSlash(f)))
+		if err != nil {
+			return e.Wrapf(ErrClassUser, err, msgFileDependencyNotFound, f, spec.Name, meta.dir)
+		}
+
+		meta.dependentFileHashes[f] = fh
+	}
+
+	return nil
+}
+
+// hashDirFilesWithExtensions is the file system equivalent of
+// stdDiscover.hashFilesWithExtensions.
+func hashDirFilesWithExtensions(dir string, extensions []string) (string, error) {
+	entries := make([]string, 0)
+	err := filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return e.Wrapf(ErrClassUser, err, msgFailedLocalPath, p)
+		}
+
+		if fi.IsDir() {
+			if fi.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			if p == dir {
+				return nil
+			}
+
+			id, ok, err := hashGitlink(p)
+			if err != nil || !ok {
+				return err
+			}
+
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				return e.Wrap(ErrClassInternal, err)
+			}
+
+			entries = append(entries, filepath.ToSlash(rel)+":"+hex.EncodeToString(id))
+			return filepath.SkipDir
+		}
+
+		if !isVersionedFile(fi.Name(), extensions) {
+			return nil
+		}
+
+		id, _, err := hashFile(p, fi)
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return e.Wrap(ErrClassInternal, err)
+		}
+
+		entries = append(entries, filepath.ToSlash(rel)+":"+hex.EncodeToString(id))
+		return nil
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return hashEntries(entries), nil
 }
 
 func newModuleMetadata(dir string, hash string, spec *Spec, dependentFileHashes map[string]string) *moduleMetadata {
@@ -180,6 +749,32 @@ func newModuleMetadata(dir string, hash string, spec *Spec, dependentFileHashes
 	}
 }
 
+// ParseSpec parses the contents of a .mbt.yml file.
+// This is useful for validating a spec without a repository.
+func ParseSpec(content []byte) (*Spec, error) {
+	spec, err := newSpec(content)
+	if err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgFailedSpecParse)
+	}
+
+	return spec, nil
+}
+
+// newSpecFromFile parses the contents of the spec file with the
+// specified name. JSON specs are parsed with the same parser used for
+// YAML (which is a superset of JSON) once they are validated, so that
+// the resulting spec is identical regardless of the format.
+func newSpecFromFile(name string, content []byte) (*Spec, error) {
+	if name == jsonConfigFileName {
+		var v interface{}
+		if err := json.Unmarshal(content, &v); err != nil {
+			return nil, err
+		}
+	}
+
+	return newSpec(content)
+}
+
 func newSpec(content []byte) (*Spec, error) {
 	a := &Spec{
 		Properties: make(map[string]interface{}),
@@ -191,27 +786,110 @@ func newSpec(content []byte) (*Spec, error) {
 		return nil, err
 	}
 
+	// Build section is the only source of the build command set.
+	if _, ok := a.Commands[CommandSetBuild]; ok {
+		return nil, e.NewErrorf(ErrClassUser, msgReservedCommandName, CommandSetBuild)
+	}
+
 	a.Properties, err = transformProps(a.Properties)
 	if err != nil {
 		return nil, err
 	}
 
+	raw := &struct {
+		Properties map[string]*rawScalar `yaml:"properties"`
+	}{}
+	if err := yaml.Unmarshal(content, raw); err != nil {
+		return nil, err
+	}
+
+	a.rawProperties = make(map[string]string)
+	for k, v := range raw.Properties {
+		if v != nil && v.ok {
+			a.rawProperties[k] = v.value
+		}
+	}
+
 	return a, nil
 }
 
+// rawScalar is the value of a scalar as written in the spec,
+// before the implicit typing of yaml is applied.
+type rawScalar struct {
+	value string
+	ok    bool
+}
+
+func (s *rawScalar) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// Decoding a scalar into a string preserves its original text.
+	// Other nodes (and nulls) fail to decode and are ignored.
+	if err := unmarshal(&s.value); err == nil {
+		s.ok = true
+	}
+	return nil
+}
+
 // toModules transforms an moduleMetadataSet to Modules structure
 // while establishing the dependency links.
 func toModules(a moduleMetadataSet) (Modules, error) {
+	return toModulesWithVersionHash(a, VersionHashSHA1)
+}
+
+// toModulesWithVersionHash is same as toModules except that the
+// versions are calculated with the specified algorithm.
+func toModulesWithVersionHash(a moduleMetadataSet, versionHash string) (Modules, error) {
+	if versionHash == "" {
+		versionHash = VersionHashSHA1
+	}
+	if versionHash != VersionHashSHA1 && versionHash != VersionHashSHA256 {
+		return nil, e.NewErrorf(ErrClassUser, msgUnknownVersionHash, versionHash)
+	}
+
+	a, err := resolveDependencyPaths(a)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err = omitDisabled(a)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := make(map[string]bool)
+	for _, meta := range a {
+		if dirs[meta.dir] {
+			return nil, e.NewErrorf(ErrClassUser, msgMultipleSpecFiles, meta.dir)
+		}
+		dirs[meta.dir] = true
+	}
+
+	return linkModules(a, versionHash)
+}
+
+// linkModules creates the modules in the set with their dependency
+// links and calculates their versions.
+// Modules are returned in topological order.
+func linkModules(a moduleMetadataSet, versionHash string) (Modules, error) {
 	// Step 1
 	// Index moduleMetadata by the module name and use it to
 	// create a ModuleMetadataProvider that we can use with TopSort fn.
+	// Module ids (which default to the name) must be unique as well.
 	m := make(map[string]*moduleMetadata)
+	ids := make(map[string]*moduleMetadata)
 	nodes := make([]interface{}, 0, len(a))
 	for _, meta := range a {
 		if conflict, ok := m[meta.spec.Name]; ok {
-			return nil, e.NewErrorf(ErrClassUser, "Module name '%s' in directory '%s' conflicts with the module in '%s' directory", meta.spec.Name, meta.dir, conflict.dir)
+			return nil, e.NewErrorf(ErrClassUser, msgDuplicateModuleName, meta.spec.Name, meta.dir, conflict.dir)
+		}
+		id := meta.spec.ID
+		if id == "" {
+			id = meta.spec.Name
+		}
+		if conflict, ok := ids[id]; ok {
+			return nil, e.NewErrorf(ErrClassUser, msgDuplicateModuleID, id, meta.dir, conflict.dir)
 		}
 		m[meta.spec.Name] = meta
+		ids[id] = meta
 		nodes = append(nodes, meta)
 	}
 	provider := newModuleMetadataProvider(m)
@@ -228,7 +906,7 @@ func toModules(a moduleMetadataSet) (Modules, error) {
 				}
 				pathStr = pathStr + v.(*moduleMetadata).spec.Name
 			}
-			return nil, e.NewErrorf(ErrClassUser, "Could not produce the module graph due to a cyclic dependency in path: %s", pathStr)
+			return nil, e.NewErrorf(ErrClassUser, msgCyclicDependency, pathStr)
 		}
 		return nil, e.Wrap(ErrClassInternal, err)
 	}
@@ -240,7 +918,12 @@ func toModules(a moduleMetadataSet) (Modules, error) {
 	modules := make(Modules, len(sortedNodes))
 	i := 0
 	for _, n := range sortedNodes {
-		metadata := n.(*moduleMetadata)
+		// Parents are created before their children, therefore,
+		// inherited properties are propagated transitively.
+		metadata, err := inheritProperties(n.(*moduleMetadata), mModules)
+		if err != nil {
+			return nil, err
+		}
 		spec := metadata.spec
 		deps := Modules{}
 		for _, d := range spec.Dependencies {
@@ -258,15 +941,195 @@ func toModules(a moduleMetadataSet) (Modules, error) {
 		mModules[mod.Name()] = mod
 	}
 
-	return calculateVersion(modules), nil
+	return calculateVersion(modules, versionHash), nil
+}
+
+// inheritProperties returns the metadata with the properties listed in
+// the inherit list of its spec copied from the parent module when they
+// are not defined in the spec. Metadata is copied because it could be
+// shared. Modules are indexed by name.
+func inheritProperties(meta *moduleMetadata, modules map[string]*Module) (*moduleMetadata, error) {
+	if len(meta.spec.Inherit) == 0 {
+		return meta, nil
+	}
+
+	parentName, err := parentOf(meta.spec)
+	if err != nil {
+		return nil, err
+	}
+	parent := modules[parentName]
+
+	spec := *meta.spec
+	spec.Properties = make(map[string]interface{})
+	for k, v := range meta.spec.Properties {
+		spec.Properties[k] = v
+	}
+	spec.rawProperties = make(map[string]string)
+	for k, v := range meta.spec.rawProperties {
+		spec.rawProperties[k] = v
+	}
+
+	for _, k := range meta.spec.Inherit {
+		if _, ok := spec.Properties[k]; ok {
+			continue
+		}
+
+		if v, ok := parent.Properties()[k]; ok {
+			spec.Properties[k] = v
+			if raw, ok := parent.metadata.spec.rawProperties[k]; ok {
+				spec.rawProperties[k] = raw
+			}
+		}
+	}
+
+	return newModuleMetadata(meta.dir, meta.hash, &spec, meta.dependentFileHashes), nil
+}
+
+// parentOf returns the name of the dependency the properties of the
+// module are inherited from.
+func parentOf(spec *Spec) (string, error) {
+	if spec.Parent == "" {
+		if len(spec.Dependencies) != 1 {
+			return "", e.NewErrorf(ErrClassUser, msgAmbiguousParent, spec.Name)
+		}
+		return spec.Dependencies[0], nil
+	}
+
+	for _, d := range spec.Dependencies {
+		if d == spec.Parent {
+			return d, nil
+		}
+	}
+
+	return "", e.NewErrorf(ErrClassUser, msgParentNotDependency, spec.Parent, spec.Name)
+}
+
+// omitDisabled removes the modules disabled by their enabledWhen
+// value. It is an error for an enabled module to require a disabled one.
+func omitDisabled(a moduleMetadataSet) (moduleMetadataSet, error) {
+	enabled := make(moduleMetadataSet, 0, len(a))
+	disabled := make(map[string]bool)
+	for _, meta := range a {
+		ok, err := isEnabled(meta.spec)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			enabled = append(enabled, meta)
+		} else {
+			disabled[meta.spec.Name] = true
+		}
+	}
+
+	if len(disabled) == 0 {
+		return a, nil
+	}
+
+	for _, meta := range enabled {
+		for _, d := range meta.spec.Dependencies {
+			if disabled[d] {
+				return nil, e.NewErrorf(ErrClassUser, msgDisabledDependency, meta.spec.Name, d)
+			}
+		}
+	}
+
+	return enabled, nil
+}
+
+func isEnabled(spec *Spec) (bool, error) {
+	if spec.EnabledWhen == "" {
+		return true, nil
+	}
+
+	v := strings.TrimSpace(os.ExpandEnv(spec.EnabledWhen))
+	if v == "" {
+		return false, nil
+	}
+
+	enabled, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, e.NewErrorf(ErrClassUser, msgInvalidEnabledWhen, v, spec.Name)
+	}
+
+	return enabled, nil
+}
+
+// resolveDependencyPaths replaces the dependencies specified as paths
+// (i.e. containing a slash) with the names of the modules in those
+// directories. Paths are relative to the root of the repository.
+func resolveDependencyPaths(a moduleMetadataSet) (moduleMetadataSet, error) {
+	byDir := make(map[string]*moduleMetadata)
+	for _, meta := range a {
+		byDir[meta.dir] = meta
+	}
+
+	r := make(moduleMetadataSet, 0, len(a))
+	for _, meta := range a {
+		if !hasDependencyPaths(meta.spec) {
+			r = append(r, meta)
+			continue
+		}
+
+		// Spec is copied because metadata could be shared.
+		spec := *meta.spec
+		spec.Dependencies = make([]string, 0, len(meta.spec.Dependencies))
+		seen := make(map[string]bool)
+		for _, d := range meta.spec.Dependencies {
+			name := d
+			if strings.Contains(d, "/") {
+				dep, ok := byDir[normalizeDependencyPath(d)]
+				if !ok {
+					return nil, e.NewErrorf(ErrClassUser, msgDependencyPathNotFound, d, spec.Name)
+				}
+				name = dep.spec.Name
+			}
+
+			if !seen[name] {
+				seen[name] = true
+				spec.Dependencies = append(spec.Dependencies, name)
+			}
+		}
+
+		r = append(r, newModuleMetadata(meta.dir, meta.hash, &spec, meta.dependentFileHashes))
+	}
+
+	return r, nil
+}
+
+func hasDependencyPaths(spec *Spec) bool {
+	for _, d := range spec.Dependencies {
+		if strings.Contains(d, "/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// normalizeDependencyPath converts a dependency path to the
+// form used for module directories.
+func normalizeDependencyPath(p string) string {
+	p = strings.Trim(path.Clean(p), "/")
+	if p == "." {
+		return ""
+	}
+
+	return p
 }
 
 // calculateVersion takes the topologically sorted Modules and
 // initialises their version field.
-func calculateVersion(topSorted Modules) Modules {
+func calculateVersion(topSorted Modules, versionHash string) Modules {
 	for _, a := range topSorted {
 		if a.Hash() == "local" {
 			a.version = "local"
+		} else if versionHash == VersionHashSHA256 {
+			// Module hash is always combined with other attributes here
+			// because git object ids are sha1.
+			h := sha256.New()
+			writeVersionAttributes(h, a)
+			a.version = "sha256-" + hex.EncodeToString(h.Sum(nil))
 		} else {
 			if len(a.Requires()) == 0 && len(a.FileDependencies()) == 0 {
 				// Fast path for modules without any dependencies
@@ -276,22 +1139,7 @@ func calculateVersion(topSorted Modules) Modules {
 				// Version is created by combining the hashes of the module
 				// content, its file dependencies and the hashes of the dependencies.
 				h := sha1.New()
-
-				io.WriteString(h, a.Hash())
-				// Consider the version of all dependencies to compute the version of
-				// current module.
-				// It is unnecessary to traverse the entire dependency graph
-				// here because we are processing the list of modules in topological
-				// order. Therefore, version of a dependency would already contain
-				// the version of its dependencies.
-				for _, r := range a.Requires() {
-					io.WriteString(h, r.Version())
-				}
-
-				for _, f := range a.FileDependencies() {
-					io.WriteString(h, a.metadata.dependentFileHashes[f])
-				}
-
+				writeVersionAttributes(h, a)
 				a.version = hex.EncodeToString(h.Sum(nil))
 			}
 		}
@@ -300,6 +1148,28 @@ func calculateVersion(topSorted Modules) Modules {
 	return topSorted
 }
 
+// writeVersionAttributes writes the attributes contributing to the
+// version of a module to the specified hash.
+func writeVersionAttributes(h hash.Hash, a *Module) {
+	io.WriteString(h, a.Hash())
+	// Consider the version of all dependencies to compute the version of
+	// current module.
+	// It is unnecessary to traverse the entire dependency graph
+	// here because we are processing the list of modules in topological
+	// order. Therefore, version of a dependency would already contain
+	// the version of its dependencies.
+	// Dependencies are visited in the order they are specified
+	// because requires list is sorted by name.
+	requires := a.Requires().indexByName()
+	for _, d := range a.metadata.spec.Dependencies {
+		io.WriteString(h, requires[d].Version())
+	}
+
+	for _, f := range a.FileDependencies() {
+		io.WriteString(h, a.metadata.dependentFileHashes[f])
+	}
+}
+
 // moduleMetadataNodeProvider is an auxiliary type used to build the dependency
 // graph. Acts as an implementation of graph.NodeProvider interface (We use graph
 // library for topological sort).
@@ -328,3 +1198,25 @@ func (n *moduleMetadataNodeProvider) Child(vertex interface{}, index int) (inter
 
 	return nil, e.NewErrorf(ErrClassUser, "dependency not found %s -> %s", spec.Name, d)
 }
+
+// isVersionedFile returns true if the file contributes to the version
+// of a module restricted to the specified extensions.
+// Spec file always contributes to the version. Extension comparison
+// is case insensitive and the leading dot in extensions is optional.
+func isVersionedFile(name string, extensions []string) bool {
+	if isConfigFile(name) {
+		return true
+	}
+
+	ext := path.Ext(name)
+	for _, x := range extensions {
+		if !strings.HasPrefix(x, ".") {
+			x = "." + x
+		}
+		if strings.EqualFold(ext, x) {
+			return true
+		}
+	}
+
+	return false
+}
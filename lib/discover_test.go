@@ -16,8 +16,13 @@ limitations under the License.
 package lib
 
 import (
+	"bytes"
+	"encoding/hex"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path"
+	"strings"
 	"testing"
 
 	"github.com/mbtproject/mbt/e"
@@ -42,6 +47,88 @@ func TestDependencyLinks(t *testing.T) {
 	assert.Equal(t, m["app-a"], m["app-b"].RequiredBy()[0])
 }
 
+func TestDependencyLinksByPath(t *testing.T) {
+	a := newModuleMetadata("services/app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"libs/app-b", "app-c"}}, nil)
+	b := newModuleMetadata("libs/app-b", "b", &Spec{Name: "app-b", Dependencies: []string{"./libs/app-c/"}}, nil)
+	c := newModuleMetadata("libs/app-c", "c", &Spec{Name: "app-c"}, nil)
+
+	mods, err := toModules(moduleMetadataSet{a, b, c})
+	check(t, err)
+	m := mods.indexByName()
+
+	assert.Equal(t, []string{"app-b", "app-c"}, m["app-a"].Requires().names())
+	assert.Equal(t, []string{"app-c"}, m["app-b"].Requires().names())
+	assert.Equal(t, []string{"app-a", "app-b"}, m["app-c"].RequiredBy().names())
+
+	// Spec in the metadata is not modified
+	assert.Equal(t, []string{"libs/app-b", "app-c"}, a.spec.Dependencies)
+}
+
+func TestVersionOfDependencyByPath(t *testing.T) {
+	byName, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-b"}}, nil),
+		newModuleMetadata("libs/app-b", "b", &Spec{Name: "app-b"}, nil),
+	})
+	check(t, err)
+
+	byPath, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"libs/app-b"}}, nil),
+		newModuleMetadata("libs/app-b", "b", &Spec{Name: "app-b"}, nil),
+	})
+	check(t, err)
+
+	assert.Equal(t, byName.indexByName()["app-a"].Version(), byPath.indexByName()["app-a"].Version())
+}
+
+func TestMissingDependencyPath(t *testing.T) {
+	a := newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"libs/app-x"}}, nil)
+	b := newModuleMetadata("libs/app-b", "b", &Spec{Name: "app-b"}, nil)
+
+	_, err := toModules(moduleMetadataSet{a, b})
+
+	assert.EqualError(t, err, fmt.Sprintf(msgDependencyPathNotFound, "libs/app-x", "app-a"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestEnabledWhen(t *testing.T) {
+	os.Setenv("MBT_TEST_FEATURE_A", "true")
+	os.Setenv("MBT_TEST_FEATURE_B", "false")
+	defer os.Unsetenv("MBT_TEST_FEATURE_A")
+	defer os.Unsetenv("MBT_TEST_FEATURE_B")
+
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", EnabledWhen: "${MBT_TEST_FEATURE_A}"}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b", EnabledWhen: "${MBT_TEST_FEATURE_B}"}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c", EnabledWhen: "${MBT_TEST_FEATURE_C}"}, nil),
+		newModuleMetadata("app-d", "d", &Spec{Name: "app-d"}, nil),
+	})
+	check(t, err)
+
+	assert.Equal(t, []string{"app-a", "app-d"}, mods.names())
+}
+
+func TestEnabledModuleRequiringDisabledModule(t *testing.T) {
+	os.Setenv("MBT_TEST_FEATURE_B", "false")
+	defer os.Unsetenv("MBT_TEST_FEATURE_B")
+
+	_, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-b"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b", EnabledWhen: "${MBT_TEST_FEATURE_B}"}, nil),
+	})
+
+	assert.EqualError(t, err, fmt.Sprintf(msgDisabledDependency, "app-a", "app-b"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestInvalidEnabledWhen(t *testing.T) {
+	_, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", EnabledWhen: "maybe"}, nil),
+	})
+
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidEnabledWhen, "maybe", "app-a"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
 func TestVersionCalculation(t *testing.T) {
 	a := newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-b"}}, nil)
 	b := newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil)
@@ -55,6 +142,28 @@ func TestVersionCalculation(t *testing.T) {
 	assert.Equal(t, "da23614e02469a0d7c7bd1bdab5c9c474b1904dc", m["app-a"].Version())
 }
 
+func TestVersionCalculationWithSHA256(t *testing.T) {
+	a := newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-b"}}, nil)
+	b := newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil)
+
+	mods, err := toModulesWithVersionHash(moduleMetadataSet{a, b}, VersionHashSHA256)
+	check(t, err)
+	m := mods.indexByName()
+
+	assert.Equal(t, "sha256-3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d", m["app-b"].Version())
+	assert.Equal(t, "sha256-0b8f49b3c4bf967b4d8f87b3001a13a73f99e7c67ea41477f5328eaf3d31e23d", m["app-a"].Version())
+}
+
+func TestUnknownVersionHash(t *testing.T) {
+	a := newModuleMetadata("app-a", "a", &Spec{Name: "app-a"}, nil)
+
+	mods, err := toModulesWithVersionHash(moduleMetadataSet{a}, "md5")
+
+	assert.Nil(t, mods)
+	assert.EqualError(t, err, fmt.Sprintf(msgUnknownVersionHash, "md5"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
 func TestMalformedSpec(t *testing.T) {
 	clean()
 	repo := NewTestRepo(t, ".tmp/repo")
@@ -74,6 +183,121 @@ func TestMalformedSpec(t *testing.T) {
 	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
 }
 
+func TestParseSpec(t *testing.T) {
+	spec, err := ParseSpec([]byte(`
+name: app-a
+build:
+  default:
+    cmd: make
+    args: [build]
+dependencies: [lib-a]
+properties:
+  foo: bar
+`))
+	check(t, err)
+
+	assert.Equal(t, "app-a", spec.Name)
+	assert.Equal(t, "make", spec.Build["default"].Cmd)
+	assert.Equal(t, []string{"build"}, spec.Build["default"].Args)
+	assert.Equal(t, []string{"lib-a"}, spec.Dependencies)
+	assert.Equal(t, "bar", spec.Properties["foo"])
+}
+
+func TestJSONSpecIsIdenticalToYAMLSpec(t *testing.T) {
+	yamlSpec, err := newSpecFromFile(".mbt.yml", []byte(`
+name: app-a
+build:
+  default:
+    cmd: make
+    args: [build]
+    skipCodes: [2]
+commands:
+  test:
+    cmd: make
+    os: [linux]
+dependencies: [lib-a]
+envFile:
+  path: .env
+properties:
+  foo: bar
+  count: 2
+  nested:
+    list: [a, 1]
+`))
+	check(t, err)
+
+	jsonSpec, err := newSpecFromFile(".mbt.json", []byte(`{
+	"name": "app-a",
+	"build": {"default": {"cmd": "make", "args": ["build"], "skipCodes": [2]}},
+	"commands": {"test": {"cmd": "make", "os": ["linux"]}},
+	"dependencies": ["lib-a"],
+	"envFile": {"path": ".env"},
+	"properties": {"foo": "bar", "count": 2, "nested": {"list": ["a", 1]}}
+}`))
+	check(t, err)
+
+	assert.Equal(t, yamlSpec, jsonSpec)
+}
+
+func TestRawProperty(t *testing.T) {
+	spec, err := newSpecFromFile(".mbt.yml", []byte(`
+name: app-a
+properties:
+  version: 01.10
+  port: 0800
+  enabled: yes
+  name: "quoted"
+  empty:
+  list: [a, b]
+  nested:
+    foo: bar
+`))
+	check(t, err)
+
+	mods, err := toModules(moduleMetadataSet{newModuleMetadata("app-a", "a", spec, nil)})
+	check(t, err)
+	m := mods[0]
+
+	assert.Equal(t, true, m.Properties()["enabled"])
+	for k, expected := range map[string]string{"version": "01.10", "port": "0800", "enabled": "yes", "name": "quoted"} {
+		v, ok := m.RawProperty(k)
+		assert.True(t, ok, k)
+		assert.Equal(t, expected, v)
+	}
+
+	for _, k := range []string{"empty", "list", "nested", "missing"} {
+		_, ok := m.RawProperty(k)
+		assert.False(t, ok, k)
+	}
+}
+
+func TestMalformedJSONSpec(t *testing.T) {
+	_, err := newSpecFromFile(".mbt.json", []byte("name: app-a\n"))
+	assert.Error(t, err)
+
+	_, err = newSpecFromFile(".mbt.json", []byte(`{"name": "app-a",}`))
+	assert.Error(t, err)
+}
+
+func TestMultipleSpecFilesInDirectory(t *testing.T) {
+	_, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a"}, nil),
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-b"}, nil),
+	})
+
+	assert.EqualError(t, err, fmt.Sprintf(msgMultipleSpecFiles, "app-a"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestParseMalformedSpec(t *testing.T) {
+	spec, err := ParseSpec([]byte("blah:blah\nblah::"))
+
+	assert.Nil(t, spec)
+	assert.EqualError(t, err, msgFailedSpecParse)
+	assert.EqualError(t, (err.(*e.E).InnerError()), "yaml: line 1: mapping values are not allowed in this context")
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
 func TestMissingBlobs(t *testing.T) {
 	clean()
 	repo := NewTestRepo(t, ".tmp/repo")
@@ -199,3 +423,622 @@ func TestVersionChangeOnFileDependencyChange(t *testing.T) {
 
 	assert.NotEqual(t, m2[0].Version(), m1[0].Version())
 }
+
+func TestDiscoveryWithRoots(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("services/app-a"))
+	check(t, repo.InitModule("services/nested/app-b"))
+	check(t, repo.InitModule("tools/app-c"))
+	check(t, repo.InitModule("servicesx/app-d"))
+	check(t, repo.Commit("first"))
+
+	world := NewWorld(t, ".tmp/repo")
+	lc, err := world.Repo.GetCommit(repo.LastCommit.String())
+	check(t, err)
+
+	discover := NewDiscoverWithOptions(world.Repo, world.Log, &DiscoverOptions{Roots: []string{"services/"}})
+	mods, err := discover.ModulesInCommit(lc)
+	check(t, err)
+
+	assert.Len(t, mods, 2)
+	assert.Equal(t, "app-a", mods[0].Name())
+	assert.Equal(t, "app-b", mods[1].Name())
+}
+
+func TestDiscoveryWithRootsInWorkspace(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("services/app-a"))
+	check(t, repo.InitModule("tools/app-c"))
+
+	world := NewWorld(t, ".tmp/repo")
+	discover := NewDiscoverWithOptions(world.Repo, world.Log, &DiscoverOptions{Roots: []string{"services"}})
+	mods, err := discover.ModulesInWorkspace()
+	check(t, err)
+
+	assert.Len(t, mods, 1)
+	assert.Equal(t, "app-a", mods[0].Name())
+}
+
+func TestDiscoveryWithMaxDepth(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModuleWithOptions("", &Spec{Name: "root"}))
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModule("services/app-b"))
+	check(t, repo.InitModule("services/nested/app-c"))
+	check(t, repo.InitModule("services/nested/deep/app-d"))
+	check(t, repo.Commit("first"))
+
+	world := NewWorld(t, ".tmp/repo")
+	lc, err := world.Repo.GetCommit(repo.LastCommit.String())
+	check(t, err)
+
+	for depth, expected := range map[int][]string{
+		-1: {"app-a", "app-b", "app-c", "app-d", "root"},
+		0:  {"root"},
+		1:  {"app-a", "root"},
+		2:  {"app-a", "app-b", "root"},
+		3:  {"app-a", "app-b", "app-c", "root"},
+	} {
+		depth := depth
+		discover := NewDiscoverWithOptions(world.Repo, world.Log, &DiscoverOptions{MaxDepth: &depth})
+		mods, err := discover.ModulesInCommit(lc)
+		check(t, err)
+
+		assert.ElementsMatch(t, expected, mods.names(), "depth %d", depth)
+	}
+}
+
+func TestDiscoveryWithInvalidMaxDepth(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+
+	world := NewWorld(t, ".tmp/repo")
+	lc, err := world.Repo.GetCommit(repo.LastCommit.String())
+	check(t, err)
+
+	depth := -2
+	discover := NewDiscoverWithOptions(world.Repo, world.Log, &DiscoverOptions{MaxDepth: &depth})
+
+	_, err = discover.ModulesInCommit(lc)
+
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidDiscoveryDepth, -2))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+
+	_, err = discover.ModulesInWorkspace()
+
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidDiscoveryDepth, -2))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestDiscoveryWithMaxDepthInWorkspace(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModule("services/app-b"))
+	check(t, repo.InitModule("services/nested/app-c"))
+
+	world := NewWorld(t, ".tmp/repo")
+	depth := 2
+	discover := NewDiscoverWithOptions(world.Repo, world.Log, &DiscoverOptions{MaxDepth: &depth})
+	mods, err := discover.ModulesInWorkspace()
+	check(t, err)
+
+	assert.Equal(t, []string{"app-a", "app-b"}, mods.names())
+}
+
+func TestDiscoveryWithTransform(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("payments/app-a"))
+	check(t, repo.InitModule("search/app-b"))
+	check(t, repo.Commit("first"))
+
+	world := NewWorld(t, ".tmp/repo")
+	lc, err := world.Repo.GetCommit(repo.LastCommit.String())
+	check(t, err)
+
+	discover := NewDiscoverWithOptions(world.Repo, world.Log, &DiscoverOptions{
+		Transform: func(dir string, spec *Spec) {
+			if spec.Properties == nil {
+				spec.Properties = make(map[string]interface{})
+			}
+			spec.Properties["team"] = strings.Split(dir, "/")[0]
+			if spec.Name == "app-a" {
+				spec.Dependencies = append(spec.Dependencies, "app-b")
+			}
+		},
+	})
+
+	mods, err := discover.ModulesInCommit(lc)
+	check(t, err)
+
+	m := mods.indexByName()
+	assert.Equal(t, "payments", m["app-a"].Properties()["team"])
+	assert.Equal(t, "search", m["app-b"].Properties()["team"])
+	assert.Equal(t, "app-b", m["app-a"].Requires()[0].Name())
+
+	inWorkspace, err := discover.ModulesInWorkspace()
+	check(t, err)
+
+	assert.Equal(t, "payments", inWorkspace.indexByName()["app-a"].Properties()["team"])
+}
+
+func TestVersionWithVersionExtensions(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{Name: "app-a", VersionExtensions: []string{".go"}}))
+	check(t, repo.WriteContent("app-a/main.go", "package main"))
+	check(t, repo.Commit("first"))
+
+	m1, err := NewWorld(t, ".tmp/repo").System.ManifestByCommit(repo.LastCommit.String())
+	check(t, err)
+
+	check(t, repo.WriteContent("app-a/README.md", "docs"))
+	check(t, repo.Commit("second"))
+
+	m2, err := NewWorld(t, ".tmp/repo").System.ManifestByCommit(repo.LastCommit.String())
+	check(t, err)
+
+	check(t, repo.WriteContent("app-a/main.go", "package main\n"))
+	check(t, repo.Commit("third"))
+
+	m3, err := NewWorld(t, ".tmp/repo").System.ManifestByCommit(repo.LastCommit.String())
+	check(t, err)
+
+	assert.Equal(t, m1.Modules[0].Version(), m2.Modules[0].Version())
+	assert.NotEqual(t, m2.Modules[0].Version(), m3.Modules[0].Version())
+}
+
+func TestVersionWithSubmodule(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModuleWithOptions("app-b", &Spec{Name: "app-b", VersionExtensions: []string{".go"}}))
+	check(t, repo.Commit("first"))
+	c1 := repo.LastCommit
+
+	check(t, repo.AddGitlink("app-a/lib", c1))
+	check(t, repo.AddGitlink("app-b/lib", c1))
+	check(t, repo.Commit("second"))
+	c2 := repo.LastCommit
+
+	m1, err := NewWorld(t, ".tmp/repo").System.ManifestByCommit(c2.String())
+	check(t, err)
+
+	// Bump the submodules without changing any file
+	check(t, repo.AddGitlink("app-a/lib", c2))
+	check(t, repo.AddGitlink("app-b/lib", c2))
+	check(t, repo.Commit("third"))
+	c3 := repo.LastCommit
+
+	m2, err := NewWorld(t, ".tmp/repo").System.ManifestByCommit(c3.String())
+	check(t, err)
+
+	v1 := m1.Modules.indexByName()
+	v2 := m2.Modules.indexByName()
+	assert.NotEqual(t, v1["app-a"].Version(), v2["app-a"].Version())
+	assert.NotEqual(t, v1["app-b"].Version(), v2["app-b"].Version())
+
+	m, err := NewWorld(t, ".tmp/repo").System.ManifestByDiff(c2.String(), c3.String())
+	check(t, err)
+
+	assert.Equal(t, []string{"app-a", "app-b"}, m.Modules.names())
+}
+
+func TestHashDirWithSubmodule(t *testing.T) {
+	clean()
+	sub := NewTestRepo(t, ".tmp/dir/app-a/lib")
+	check(t, sub.WriteContent("lib.go", "package lib"))
+	check(t, sub.Commit("first"))
+	check(t, ioutil.WriteFile(".tmp/dir/app-a/main.go", []byte("package main"), 0644))
+
+	blob := hashObject("blob", []byte("package main"))
+	tree := new(bytes.Buffer)
+	tree.WriteString("160000 lib\x00")
+	tree.Write(sub.LastCommit[:])
+	tree.WriteString("100644 main.go\x00")
+	tree.Write(blob)
+
+	// Submodule is hashed as a gitlink rather than a tree of its files.
+	h, err := hashPath(".tmp/dir/app-a")
+	check(t, err)
+	assert.Equal(t, hex.EncodeToString(hashObject("tree", tree.Bytes())), h)
+
+	h, err = hashDirFilesWithExtensions(".tmp/dir/app-a", []string{".go"})
+	check(t, err)
+	assert.Equal(t, hashEntries([]string{"lib:" + sub.LastCommit.String(), "main.go:" + hex.EncodeToString(blob)}), h)
+}
+
+func TestVersionIsStableAcrossMoves(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.WriteContent("app-a/main.go", "package main"))
+	check(t, repo.InitModuleWithOptions("app-b", &Spec{Name: "app-b", VersionExtensions: []string{".go"}}))
+	check(t, repo.WriteContent("app-b/main.go", "package main"))
+	check(t, repo.Commit("first"))
+
+	m1, err := NewWorld(t, ".tmp/repo").System.ManifestByCommit(repo.LastCommit.String())
+	check(t, err)
+
+	check(t, repo.WriteContent("services/README.md", "services"))
+	check(t, repo.Rename("app-a", "services/app-a"))
+	check(t, repo.Rename("app-b", "services/app-b"))
+	check(t, repo.Commit("second"))
+
+	m2, err := NewWorld(t, ".tmp/repo").System.ManifestByCommit(repo.LastCommit.String())
+	check(t, err)
+
+	assert.Equal(t, "services/app-a", m2.Modules[0].Path())
+	assert.Equal(t, m1.Modules[0].Version(), m2.Modules[0].Version())
+	assert.Equal(t, "services/app-b", m2.Modules[1].Path())
+	assert.Equal(t, m1.Modules[1].Version(), m2.Modules[1].Version())
+}
+
+func TestDependenciesFile(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModule("libs/lib-b"))
+	check(t, repo.InitModuleWithOptions("app-c", &Spec{
+		Name:             "app-c",
+		Dependencies:     []string{"app-a"},
+		DependenciesFile: "deps.txt",
+	}))
+	check(t, repo.WriteContent("app-c/deps.txt", "# generated\napp-a\n\nlibs/lib-b\n"))
+	check(t, repo.Commit("first"))
+	c1 := repo.LastCommit.String()
+
+	m1, err := NewWorld(t, ".tmp/repo").System.ManifestByCommit(c1)
+	check(t, err)
+
+	appC := m1.Modules.indexByName()["app-c"]
+	assert.Equal(t, []string{"app-a", "lib-b"}, appC.Requires().names())
+	assert.Equal(t, []string{"app-c/deps.txt"}, appC.FileDependencies())
+
+	mods, err := ModulesInDir(".tmp/repo")
+	check(t, err)
+	assert.Equal(t, appC.Version(), mods.indexByName()["app-c"].Version())
+	assert.Equal(t, []string{"app-a", "lib-b"}, mods.indexByName()["app-c"].Requires().names())
+
+	check(t, repo.WriteContent("app-c/deps.txt", "app-a\n"))
+	check(t, repo.Commit("second"))
+	c2 := repo.LastCommit.String()
+
+	m2, err := NewWorld(t, ".tmp/repo").System.ManifestByCommit(c2)
+	check(t, err)
+
+	assert.Equal(t, []string{"app-a"}, m2.Modules.indexByName()["app-c"].Requires().names())
+	assert.NotEqual(t, appC.Version(), m2.Modules.indexByName()["app-c"].Version())
+
+	m, err := NewWorld(t, ".tmp/repo").System.ManifestByDiff(c1, c2)
+	check(t, err)
+	assert.Equal(t, []string{"app-c"}, m.Modules.names())
+}
+
+func TestMissingDependenciesFile(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{Name: "app-a", DependenciesFile: "deps.txt"}))
+	check(t, repo.Commit("first"))
+
+	_, err := NewWorld(t, ".tmp/repo").System.ManifestByCommit(repo.LastCommit.String())
+
+	assert.EqualError(t, err, fmt.Sprintf(msgDependenciesFileNotFound, "deps.txt", "app-a", "app-a"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestMergeDependenciesFile(t *testing.T) {
+	spec := &Spec{Name: "app-c", Dependencies: []string{"app-a"}, DependenciesFile: "../shared/deps.txt"}
+	var read string
+	err := mergeDependenciesFile("services/app-c", spec, func(p string) ([]byte, error) {
+		read = p
+		return []byte("  app-b \r\n# app-x\napp-a\n"), nil
+	})
+	check(t, err)
+
+	assert.Equal(t, "services/shared/deps.txt", read)
+	assert.Equal(t, []string{"app-a", "app-b"}, spec.Dependencies)
+	assert.Equal(t, []string{"services/shared/deps.txt"}, spec.FileDependencies)
+
+	check(t, mergeDependenciesFile("app-d", &Spec{Name: "app-d"}, func(p string) ([]byte, error) {
+		panic("dependencies file should not be read")
+	}))
+}
+
+func TestModulesInDirMatchesModulesInCommit(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModuleWithOptions("services/app-a", &Spec{
+		Name:             "app-a",
+		Dependencies:     []string{"lib-a"},
+		FileDependencies: []string{"shared/config.yml"},
+	}))
+	check(t, repo.InitModuleWithOptions("services/app-b", &Spec{Name: "app-b", VersionExtensions: []string{".go"}}))
+	check(t, repo.InitModule("libs/lib-a"))
+	check(t, repo.WriteContent("services/app-a/main.go", "package main"))
+	check(t, repo.WriteContent("services/app-a/src/z.go", "package src"))
+	check(t, repo.WriteContent("services/app-a/src/a.go", "package src"))
+	check(t, repo.WriteContent("services/app-b/main.go", "package main"))
+	check(t, repo.WriteContent("services/app-b/README.md", "docs"))
+	check(t, repo.WriteContent("libs/lib-a/lib.go", "package lib"))
+	check(t, repo.WriteContent("shared/config.yml", "foo: bar"))
+	check(t, repo.Commit("first"))
+
+	m, err := NewWorld(t, ".tmp/repo").System.ManifestByCommit(repo.LastCommit.String())
+	check(t, err)
+
+	mods, err := ModulesInDir(".tmp/repo")
+	check(t, err)
+
+	assert.Len(t, mods, 3)
+	fromGit := m.Modules.indexByName()
+	for _, mod := range mods {
+		assert.Equal(t, fromGit[mod.Name()].Version(), mod.Version(), mod.Name())
+	}
+}
+
+func TestModulesInDirWithVersionHash(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{Name: "app-a", Dependencies: []string{"lib-a"}}))
+	check(t, repo.InitModule("lib-a"))
+	check(t, repo.Commit("first"))
+
+	world := NewWorld(t, ".tmp/repo")
+	lc, err := world.Repo.GetCommit(repo.LastCommit.String())
+	check(t, err)
+
+	options := &DiscoverOptions{VersionHash: VersionHashSHA256}
+	fromGit, err := NewDiscoverWithOptions(world.Repo, world.Log, options).ModulesInCommit(lc)
+	check(t, err)
+
+	mods, err := ModulesInDirWithOptions(".tmp/repo", options)
+	check(t, err)
+
+	assert.Equal(t, fromGit.indexByName()["app-a"].Version(), mods.indexByName()["app-a"].Version())
+
+	_, err = ModulesInDirWithOptions(".tmp/repo", &DiscoverOptions{VersionHash: "md5"})
+
+	assert.EqualError(t, err, fmt.Sprintf(msgUnknownVersionHash, "md5"))
+}
+
+func TestModulesInDirWithTransform(t *testing.T) {
+	clean()
+	check(t, os.MkdirAll(".tmp/dir/payments/app-a", 0755))
+	check(t, os.MkdirAll(".tmp/dir/search/app-b", 0755))
+	check(t, ioutil.WriteFile(".tmp/dir/payments/app-a/.mbt.yml", []byte("name: app-a\n"), 0644))
+	check(t, ioutil.WriteFile(".tmp/dir/search/app-b/.mbt.yml", []byte("name: app-b\n"), 0644))
+
+	mods, err := ModulesInDirWithOptions(".tmp/dir", &DiscoverOptions{
+		Transform: func(dir string, spec *Spec) {
+			spec.Properties = map[string]interface{}{"team": strings.Split(dir, "/")[0]}
+			if spec.Name == "app-a" {
+				spec.Dependencies = append(spec.Dependencies, "app-b")
+			}
+		},
+	})
+	check(t, err)
+
+	m := mods.indexByName()
+	assert.Equal(t, "payments", m["app-a"].Properties()["team"])
+	assert.Equal(t, "search", m["app-b"].Properties()["team"])
+	assert.Equal(t, Modules{m["app-b"]}, m["app-a"].Requires())
+}
+
+func TestModulesInDirWithMaxDepth(t *testing.T) {
+	clean()
+	check(t, os.MkdirAll(".tmp/dir/app-a", 0755))
+	check(t, os.MkdirAll(".tmp/dir/services/app-b", 0755))
+	check(t, os.MkdirAll(".tmp/dir/services/nested/app-c", 0755))
+	check(t, ioutil.WriteFile(".tmp/dir/.mbt.yml", []byte("name: root\n"), 0644))
+	check(t, ioutil.WriteFile(".tmp/dir/app-a/.mbt.yml", []byte("name: app-a\n"), 0644))
+	check(t, ioutil.WriteFile(".tmp/dir/services/app-b/.mbt.yml", []byte("name: app-b\n"), 0644))
+	check(t, ioutil.WriteFile(".tmp/dir/services/nested/app-c/.mbt.yml", []byte("name: app-c\n"), 0644))
+
+	for depth, expected := range map[int][]string{
+		-1: {"app-a", "app-b", "app-c", "root"},
+		0:  {"root"},
+		2:  {"app-a", "app-b", "root"},
+	} {
+		depth := depth
+		mods, err := ModulesInDirWithOptions(".tmp/dir", &DiscoverOptions{MaxDepth: &depth})
+		check(t, err)
+
+		assert.ElementsMatch(t, expected, mods.names(), "depth %d", depth)
+	}
+
+	depth := -2
+	_, err := ModulesInDirWithOptions(".tmp/dir", &DiscoverOptions{MaxDepth: &depth})
+
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidDiscoveryDepth, -2))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestHashModuleDirsWithMultipleWorkers(t *testing.T) {
+	clean()
+	for i := 0; i < 20; i++ {
+		p := fmt.Sprintf(".tmp/dir/app-%v/main.go", i)
+		check(t, os.MkdirAll(path.Dir(p), 0755))
+		check(t, ioutil.WriteFile(p, []byte(fmt.Sprintf("package main // %v", i)), 0644))
+	}
+
+	newSet := func() moduleMetadataSet {
+		set := moduleMetadataSet{}
+		for i := 0; i < 20; i++ {
+			name := fmt.Sprintf("app-%v", i)
+			set = append(set, newModuleMetadata(name, "", &Spec{Name: name}, nil))
+		}
+		return set
+	}
+
+	serial := newSet()
+	check(t, hashModuleDirs(".tmp/dir", serial, 1))
+	parallel := newSet()
+	check(t, hashModuleDirs(".tmp/dir", parallel, 8))
+
+	for i := range serial {
+		assert.NotEmpty(t, serial[i].hash)
+		assert.Equal(t, serial[i].hash, parallel[i].hash)
+	}
+
+	// Error of the first failing module in the set is reported.
+	failing := append(newSet(),
+		newModuleMetadata("app-x", "", &Spec{Name: "app-x", FileDependencies: []string{"x"}}, nil),
+		newModuleMetadata("app-y", "", &Spec{Name: "app-y"}, nil),
+	)
+	check(t, os.MkdirAll(".tmp/dir/app-x", 0755))
+	err := hashModuleDirs(".tmp/dir", failing, 8)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf(msgFileDependencyNotFound, "x", "app-x", "app-x"))
+}
+
+func TestModuleAtTree(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModuleWithOptions("app-b", &Spec{Name: "app-b", Dependencies: []string{"app-a"}}))
+	check(t, repo.WriteContent("docs/readme.md", "hello"))
+	check(t, repo.Commit("first"))
+
+	world := NewWorld(t, ".tmp/repo")
+	lc, err := world.Repo.GetCommit(repo.LastCommit.String())
+	check(t, err)
+
+	mods, err := world.Discover.ModulesInCommit(lc)
+	check(t, err)
+	idx := mods.indexByName()
+
+	a, err := world.Discover.ModuleAtTree(idx["app-a"].Hash())
+	check(t, err)
+	assert.Equal(t, "app-a", a.Name())
+	assert.Equal(t, idx["app-a"].Version(), a.Version())
+	assert.Equal(t, idx["app-a"].Hash(), a.Hash())
+
+	b, err := world.Discover.ModuleAtTree(idx["app-b"].Hash())
+	check(t, err)
+	assert.Equal(t, "app-b", b.Name())
+	assert.Equal(t, VersionUnresolved, b.Version())
+	assert.Equal(t, []string{"app-a"}, b.metadata.spec.Dependencies)
+	assert.Empty(t, b.Requires())
+
+	docs, err := world.Repo.EntryID(lc, "docs")
+	check(t, err)
+	_, err = world.Discover.ModuleAtTree(docs)
+	assert.EqualError(t, err, fmt.Sprintf(msgTreeEntryNotFound, ".mbt.yml", docs))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+
+	_, err = world.Discover.ModuleAtTree("xyz")
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidTreeID, "xyz"))
+}
+
+func TestModuleAtTreeWithVersionHash(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModuleWithOptions("app-b", &Spec{Name: "app-b", DependenciesFile: "deps.txt"}))
+	check(t, repo.WriteContent("app-b/deps.txt", "app-a\n"))
+	check(t, repo.Commit("first"))
+
+	world := NewWorld(t, ".tmp/repo")
+	lc, err := world.Repo.GetCommit(repo.LastCommit.String())
+	check(t, err)
+
+	discover := NewDiscoverWithOptions(world.Repo, world.Log, &DiscoverOptions{VersionHash: VersionHashSHA256})
+	mods, err := discover.ModulesInCommit(lc)
+	check(t, err)
+	idx := mods.indexByName()
+
+	a, err := discover.ModuleAtTree(idx["app-a"].Hash())
+	check(t, err)
+	assert.Equal(t, idx["app-a"].Version(), a.Version())
+
+	b, err := discover.ModuleAtTree(idx["app-b"].Hash())
+	check(t, err)
+	assert.Equal(t, VersionUnresolved, b.Version())
+	assert.Equal(t, []string{"app-a"}, b.metadata.spec.Dependencies)
+}
+
+func TestModuleNameConflictsInCommit(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModuleWithOptions("nested/app-a", &Spec{Name: "app-a"}))
+	check(t, repo.Commit("first"))
+
+	world := NewWorld(t, ".tmp/repo")
+	lc, err := world.Repo.GetCommit(repo.LastCommit.String())
+	check(t, err)
+
+	_, err = world.Discover.ModulesInCommit(lc)
+
+	assert.EqualError(t, err, fmt.Sprintf(msgDuplicateModuleName, "app-a", "nested/app-a", "app-a"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestDiscoveryOfJSONSpec(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.WriteContent("app-b/.mbt.json", `{"name": "app-b", "dependencies": ["app-a"]}`))
+	check(t, repo.Commit("first"))
+
+	world := NewWorld(t, ".tmp/repo")
+	lc, err := world.Repo.GetCommit(repo.LastCommit.String())
+	check(t, err)
+
+	inCommit, err := world.Discover.ModulesInCommit(lc)
+	check(t, err)
+
+	assert.Equal(t, []string{"app-a", "app-b"}, inCommit.names())
+	assert.Equal(t, []string{"app-a"}, inCommit.indexByName()["app-b"].Requires().names())
+
+	inWorkspace, err := world.Discover.ModulesInWorkspace()
+	check(t, err)
+
+	assert.Equal(t, []string{"app-a", "app-b"}, inWorkspace.names())
+
+	inDir, err := ModulesInDir(".tmp/repo")
+	check(t, err)
+
+	assert.Equal(t, inCommit.indexByName()["app-b"].Version(), inDir.indexByName()["app-b"].Version())
+
+	b, err := world.Discover.ModuleAtTree(inCommit.indexByName()["app-b"].Hash())
+	check(t, err)
+
+	assert.Equal(t, "app-b", b.Name())
+}
+
+func TestSpecWithReservedCommandName(t *testing.T) {
+	_, err := newSpec([]byte("name: app-a\ncommands:\n  build:\n    cmd: make\n"))
+
+	assert.EqualError(t, err, fmt.Sprintf(msgReservedCommandName, "build"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+
+	spec, err := newSpec([]byte("name: app-a\ncommands:\n  healthcheck:\n    cmd: curl\n"))
+	check(t, err)
+	assert.Equal(t, "curl", spec.Commands["healthcheck"].Cmd)
+}
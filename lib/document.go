@@ -0,0 +1,229 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import "time"
+
+// APIVersion is the version of the schema used in all structured
+// outputs produced by mbt.
+// Bump this value when the semantics of an existing field change.
+const APIVersion = "mbt/v1"
+
+const (
+	// KindModuleList is the kind of a document describing a set of modules.
+	KindModuleList = "ModuleList"
+	// KindBuildNote is the kind of a build note stored in git notes.
+	KindBuildNote = "BuildNote"
+	// KindRunSummary is the kind of a document summarising a run of
+	// a user defined command.
+	KindRunSummary = "RunSummary"
+	// KindModuleManifest is the kind of a document listing modules
+	// in build order along with the versions of their dependencies.
+	KindModuleManifest = "ModuleManifest"
+	// KindModuleGraph is the kind of a snapshot of a module graph
+	// that can be loaded without discovering the modules again.
+	KindModuleGraph = "ModuleGraph"
+)
+
+const (
+	// RunStatusSucceeded is the status of a module where the command succeeded.
+	RunStatusSucceeded = "succeeded"
+	// RunStatusFailed is the status of a module where the command failed.
+	RunStatusFailed = "failed"
+	// RunStatusSkipped is the status of a module where the command was
+	// not run or exited with a skip code.
+	RunStatusSkipped = "skipped"
+)
+
+// TypeMeta is embedded in every structured output to identify the
+// schema of the document.
+type TypeMeta struct {
+	APIVersion string `json:"apiVersion" yaml:"apiVersion"`
+	Kind       string `json:"kind" yaml:"kind"`
+}
+
+// ModuleDocument is the structured representation of a module.
+type ModuleDocument struct {
+	Name       string                 `json:"Name" yaml:"Name"`
+	ID         string                 `json:"ID" yaml:"ID"`
+	Path       string                 `json:"Path" yaml:"Path"`
+	Version    string                 `json:"Version" yaml:"Version"`
+	Properties map[string]interface{} `json:"Properties" yaml:"Properties"`
+}
+
+// ModuleList is the structured representation of a set of modules.
+type ModuleList struct {
+	TypeMeta `yaml:",inline"`
+	Modules  map[string]*ModuleDocument `json:"modules" yaml:"modules"`
+}
+
+// NewModuleList creates a ModuleList document for the specified modules.
+func NewModuleList(mods Modules) *ModuleList {
+	l := &ModuleList{
+		TypeMeta: TypeMeta{APIVersion: APIVersion, Kind: KindModuleList},
+		Modules:  make(map[string]*ModuleDocument),
+	}
+
+	for _, m := range mods {
+		l.Modules[m.Name()] = &ModuleDocument{
+			Name:       m.Name(),
+			ID:         m.ID(),
+			Path:       m.Path(),
+			Version:    m.Version(),
+			Properties: m.Properties(),
+		}
+	}
+
+	return l
+}
+
+// ManifestEntry is the structured representation of a module in
+// a ModuleManifest.
+type ManifestEntry struct {
+	Name    string `json:"name" yaml:"name"`
+	ID      string `json:"id" yaml:"id"`
+	Path    string `json:"path" yaml:"path"`
+	Version string `json:"version" yaml:"version"`
+	// Stage is the index of the build stage of the module (see BuildStages).
+	Stage int `json:"stage" yaml:"stage"`
+	// Dependencies maps the name of each dependency to its version.
+	// Dependencies that are not in the manifest are included as well.
+	Dependencies map[string]string `json:"dependencies" yaml:"dependencies"`
+}
+
+// ModuleManifest is the structured representation of a set of modules
+// in build order.
+type ModuleManifest struct {
+	TypeMeta `yaml:",inline"`
+	// Partial is true when the manifest describes a subset of the
+	// modules in the repository (e.g. the modules affected by a diff).
+	Partial bool             `json:"partial" yaml:"partial"`
+	Modules []*ManifestEntry `json:"modules" yaml:"modules"`
+}
+
+// NewModuleManifest creates a ModuleManifest document for the specified
+// modules.
+func NewModuleManifest(mods Modules, partial bool) (*ModuleManifest, error) {
+	ordered, err := mods.OrderedWithStages()
+	if err != nil {
+		return nil, err
+	}
+
+	m := &ModuleManifest{
+		TypeMeta: TypeMeta{APIVersion: APIVersion, Kind: KindModuleManifest},
+		Partial:  partial,
+		Modules:  make([]*ManifestEntry, 0, len(ordered)),
+	}
+
+	for _, s := range ordered {
+		deps := make(map[string]string)
+		for _, r := range s.Module.Requires() {
+			deps[r.Name()] = r.Version()
+		}
+
+		m.Modules = append(m.Modules, &ManifestEntry{
+			Name:         s.Module.Name(),
+			ID:           s.Module.ID(),
+			Path:         s.Module.Path(),
+			Version:      s.Module.Version(),
+			Stage:        s.Stage,
+			Dependencies: deps,
+		})
+	}
+
+	return m, nil
+}
+
+// ModuleSnapshot is the serialized form of a module in a ModuleGraph.
+type ModuleSnapshot struct {
+	Name    string `json:"name" yaml:"name"`
+	Path    string `json:"path" yaml:"path"`
+	Hash    string `json:"hash" yaml:"hash"`
+	Version string `json:"version" yaml:"version"`
+	// Spec is the spec of the module serialized as yaml.
+	Spec string `json:"spec" yaml:"spec"`
+	// RawProperties are the scalar properties as written in the
+	// original spec (see Module.RawProperty).
+	RawProperties map[string]string `json:"rawProperties,omitempty" yaml:"rawProperties,omitempty"`
+	// FileDependencyHashes are the hashes of file dependencies
+	// keyed by their paths.
+	FileDependencyHashes map[string]string `json:"fileDependencyHashes,omitempty" yaml:"fileDependencyHashes,omitempty"`
+}
+
+// ModuleGraph is a snapshot of a set of modules with everything
+// required to restore them (see Modules.Save and LoadModules).
+type ModuleGraph struct {
+	TypeMeta `yaml:",inline"`
+	Modules  []*ModuleSnapshot `json:"modules" yaml:"modules"`
+}
+
+// ModuleRunSummary is the structured representation of the result of
+// running a command in a module.
+type ModuleRunSummary struct {
+	Name    string `json:"name" yaml:"name"`
+	Path    string `json:"path" yaml:"path"`
+	Version string `json:"version" yaml:"version"`
+	// Status is one of RunStatusSucceeded, RunStatusFailed or RunStatusSkipped.
+	Status string `json:"status" yaml:"status"`
+	// Duration of the command (serialised in nanoseconds).
+	Duration time.Duration `json:"duration" yaml:"duration"`
+	Error    string        `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// RunSummary is the structured representation of a run of a user
+// defined command across the modules in a manifest.
+type RunSummary struct {
+	TypeMeta  `yaml:",inline"`
+	Total     int `json:"total" yaml:"total"`
+	Succeeded int `json:"succeeded" yaml:"succeeded"`
+	Failed    int `json:"failed" yaml:"failed"`
+	Skipped   int `json:"skipped" yaml:"skipped"`
+	// Duration of the entire run (serialised in nanoseconds).
+	Duration time.Duration       `json:"duration" yaml:"duration"`
+	Modules  []*ModuleRunSummary `json:"modules" yaml:"modules"`
+}
+
+func newRunSummary() *RunSummary {
+	return &RunSummary{
+		TypeMeta: TypeMeta{APIVersion: APIVersion, Kind: KindRunSummary},
+		Modules:  make([]*ModuleRunSummary, 0),
+	}
+}
+
+func (s *RunSummary) add(mod *Module, status string, duration time.Duration, err error) {
+	r := &ModuleRunSummary{
+		Name:     mod.Name(),
+		Path:     mod.Path(),
+		Version:  mod.Version(),
+		Status:   status,
+		Duration: duration,
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+
+	switch status {
+	case RunStatusSucceeded:
+		s.Succeeded++
+	case RunStatusFailed:
+		s.Failed++
+	case RunStatusSkipped:
+		s.Skipped++
+	}
+
+	s.Total++
+	s.Modules = append(s.Modules, r)
+}
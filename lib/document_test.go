@@ -0,0 +1,84 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModuleListApiVersion(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a"}, nil),
+	})
+	check(t, err)
+
+	buff, err := json.Marshal(NewModuleList(mods))
+	check(t, err)
+
+	doc := make(map[string]interface{})
+	check(t, json.Unmarshal(buff, &doc))
+
+	assert.Equal(t, APIVersion, doc["apiVersion"])
+	assert.Equal(t, KindModuleList, doc["kind"])
+	assert.Contains(t, doc["modules"], "app-a")
+}
+
+func TestBuildNoteApiVersion(t *testing.T) {
+	buff, err := json.Marshal(NewBuildNote(Modules{}))
+	check(t, err)
+
+	doc := make(map[string]interface{})
+	check(t, json.Unmarshal(buff, &doc))
+
+	assert.Equal(t, APIVersion, doc["apiVersion"])
+	assert.Equal(t, KindBuildNote, doc["kind"])
+}
+
+func TestRunSummaryApiVersion(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a"}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil),
+	})
+	check(t, err)
+
+	summary := newRunSummary()
+	summary.add(mods[0], RunStatusSucceeded, time.Second, nil)
+	summary.add(mods[1], RunStatusFailed, time.Second, errors.New("boom"))
+
+	buff, err := json.Marshal(summary)
+	check(t, err)
+
+	doc := make(map[string]interface{})
+	check(t, json.Unmarshal(buff, &doc))
+
+	assert.Equal(t, APIVersion, doc["apiVersion"])
+	assert.Equal(t, KindRunSummary, doc["kind"])
+	assert.Equal(t, float64(2), doc["total"])
+	assert.Equal(t, float64(1), doc["succeeded"])
+	assert.Equal(t, float64(1), doc["failed"])
+	assert.Equal(t, float64(0), doc["skipped"])
+
+	modules := doc["modules"].([]interface{})
+	assert.Len(t, modules, 2)
+	assert.Equal(t, "failed", modules[1].(map[string]interface{})["status"])
+	assert.Equal(t, "boom", modules[1].(map[string]interface{})["error"])
+	assert.NotContains(t, modules[0], "error")
+}
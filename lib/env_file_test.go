@@ -0,0 +1,42 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEnvFile(t *testing.T) {
+	env, err := parseEnvFile(".env", []byte(`
+# comment
+FOO=bar
+export BAR = "hello world"
+BAZ='a=b'
+EMPTY=
+`))
+	check(t, err)
+
+	assert.Equal(t, []string{"FOO=bar", "BAR=hello world", "BAZ=a=b", "EMPTY="}, env)
+}
+
+func TestParseMalformedEnvFile(t *testing.T) {
+	_, err := parseEnvFile(".env", []byte("FOO=bar\nBAR\n"))
+
+	assert.EqualError(t, err, fmt.Sprintf(msgMalformedEnvFile, 2, ".env"))
+}
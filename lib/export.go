@@ -0,0 +1,129 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	yaml "github.com/go-yaml/yaml"
+	"github.com/mbtproject/mbt/e"
+)
+
+const (
+	// ExportFormatJSON writes the ModuleList document as json
+	// to modules.json.
+	ExportFormatJSON = "json"
+	// ExportFormatYAML writes the ModuleList document as yaml
+	// to modules.yaml.
+	ExportFormatYAML = "yaml"
+	// ExportFormatDot writes the dependency graph to modules.dot.
+	ExportFormatDot = "dot"
+	// ExportFormatManifest writes a table of module names, paths
+	// and versions to modules.txt.
+	ExportFormatManifest = "manifest"
+)
+
+var exportFileNames = map[string]string{
+	ExportFormatJSON:     "modules.json",
+	ExportFormatYAML:     "modules.yaml",
+	ExportFormatDot:      "modules.dot",
+	ExportFormatManifest: "modules.txt",
+}
+
+// Export writes the modules in each of the specified formats to
+// a file in dir. Directory is created if it does not exist.
+// Formats are validated before writing any file.
+func (l Modules) Export(formats []string, dir string) error {
+	for _, f := range formats {
+		if _, ok := exportFileNames[f]; !ok {
+			return e.NewErrorf(ErrClassUser, msgUnknownExportFormat, f)
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return e.Wrapf(ErrClassUser, err, msgFailedLocalPath, dir)
+	}
+
+	for _, f := range formats {
+		content, err := l.serialize(f)
+		if err != nil {
+			return e.Wrapf(ErrClassInternal, err, msgFailedExport, f, dir)
+		}
+
+		p := filepath.Join(dir, exportFileNames[f])
+		if err := ioutil.WriteFile(p, content, 0644); err != nil {
+			return e.Wrapf(ErrClassUser, err, msgFailedExport, f, p)
+		}
+	}
+
+	return nil
+}
+
+// WriteManifest writes the ModuleManifest document of the modules
+// as json to w.
+// Set partial to true when the list is a subset of the modules in the
+// repository (e.g. the result of a diff) so that consumers do not
+// treat the absence of a module as a removal.
+func (l Modules) WriteManifest(w io.Writer, partial bool) error {
+	m, err := NewModuleManifest(l, partial)
+	if err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return e.Wrap(ErrClassInternal, err)
+	}
+
+	if _, err := w.Write(append(content, '\n')); err != nil {
+		return e.Wrap(ErrClassUser, err)
+	}
+
+	return nil
+}
+
+func (l Modules) serialize(format string) ([]byte, error) {
+	switch format {
+	case ExportFormatJSON:
+		return json.MarshalIndent(NewModuleList(l), "", "  ")
+	case ExportFormatYAML:
+		return yaml.Marshal(NewModuleList(l))
+	case ExportFormatDot:
+		return []byte(l.SerializeAsDot() + "\n"), nil
+	case ExportFormatManifest:
+		buf := new(bytes.Buffer)
+		w := tabwriter.NewWriter(buf, 0, 4, 4, ' ', 0)
+		fmt.Fprintf(w, "NAME\tPATH\tVERSION\n")
+		for _, a := range l {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", a.Name(), a.Path(), a.Version())
+		}
+
+		if err := w.Flush(); err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	}
+
+	return nil, e.NewErrorf(ErrClassUser, msgUnknownExportFormat, format)
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	yaml "github.com/go-yaml/yaml"
+	"github.com/mbtproject/mbt/e"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExport(t *testing.T) {
+	clean()
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-b"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil),
+	})
+	check(t, err)
+
+	check(t, mods.Export([]string{ExportFormatJSON, ExportFormatYAML, ExportFormatDot, ExportFormatManifest}, ".tmp/export"))
+
+	content, err := ioutil.ReadFile(".tmp/export/modules.json")
+	check(t, err)
+	fromJSON := &ModuleList{}
+	check(t, json.Unmarshal(content, fromJSON))
+	assert.Equal(t, NewModuleList(mods), fromJSON)
+
+	content, err = ioutil.ReadFile(".tmp/export/modules.yaml")
+	check(t, err)
+	fromYAML := &ModuleList{}
+	check(t, yaml.Unmarshal(content, fromYAML))
+	assert.Equal(t, fromJSON.TypeMeta, fromYAML.TypeMeta)
+	assert.Equal(t, mods.indexByName()["app-a"].Version(), fromYAML.Modules["app-a"].Version)
+
+	content, err = ioutil.ReadFile(".tmp/export/modules.dot")
+	check(t, err)
+	assert.Equal(t, mods.SerializeAsDot()+"\n", string(content))
+
+	content, err = ioutil.ReadFile(".tmp/export/modules.txt")
+	check(t, err)
+	assert.True(t, strings.HasPrefix(string(content), "NAME"))
+	assert.Contains(t, string(content), "app-a")
+	assert.Contains(t, string(content), mods[0].Version())
+}
+
+func TestExportUnknownFormat(t *testing.T) {
+	clean()
+	err := Modules{}.Export([]string{ExportFormatJSON, "xml"}, ".tmp/export")
+
+	assert.EqualError(t, err, fmt.Sprintf(msgUnknownExportFormat, "xml"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+
+	_, err = os.Stat(".tmp/export")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSerializeUnknownFormat(t *testing.T) {
+	_, err := Modules{}.serialize("xml")
+
+	assert.EqualError(t, err, fmt.Sprintf(msgUnknownExportFormat, "xml"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestWriteManifest(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-b"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil),
+	})
+	check(t, err)
+
+	buf := new(bytes.Buffer)
+	check(t, mods.WriteManifest(buf, false))
+
+	m := &ModuleManifest{}
+	check(t, json.Unmarshal(buf.Bytes(), m))
+
+	assert.Equal(t, TypeMeta{APIVersion: APIVersion, Kind: KindModuleManifest}, m.TypeMeta)
+	assert.False(t, m.Partial)
+	assert.Len(t, m.Modules, 2)
+	assert.Equal(t, "app-b", m.Modules[0].Name)
+	assert.Equal(t, 0, m.Modules[0].Stage)
+	assert.Empty(t, m.Modules[0].Dependencies)
+	assert.Equal(t, "app-a", m.Modules[1].Name)
+	assert.Equal(t, 1, m.Modules[1].Stage)
+	assert.Equal(t, mods.indexByName()["app-a"].Version(), m.Modules[1].Version)
+	assert.Equal(t, map[string]string{"app-b": mods.indexByName()["app-b"].Version()}, m.Modules[1].Dependencies)
+}
+
+func TestWriteManifestForSubset(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-b"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil),
+	})
+	check(t, err)
+
+	a := mods.indexByName()["app-a"]
+	buf := new(bytes.Buffer)
+	check(t, Modules{a}.WriteManifest(buf, true))
+
+	m := &ModuleManifest{}
+	check(t, json.Unmarshal(buf.Bytes(), m))
+
+	assert.True(t, m.Partial)
+	assert.Len(t, m.Modules, 1)
+	assert.Equal(t, "app-a", m.Modules[0].Name)
+	assert.Equal(t, 0, m.Modules[0].Stage)
+	assert.Equal(t, map[string]string{"app-b": mods.indexByName()["app-b"].Version()}, m.Modules[0].Dependencies)
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"encoding/json"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// GitHubMatrixEntry is an element of the include list in a
+// GitHub Actions matrix.
+type GitHubMatrixEntry struct {
+	App     string `json:"app"`
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	// Skip is only set in the sentinel entry emitted for an
+	// empty list (see GitHubMatrixOptions).
+	Skip bool `json:"skip,omitempty"`
+}
+
+// GitHubMatrix is the structured representation of a GitHub Actions
+// matrix with an entry for each module.
+type GitHubMatrix struct {
+	Include []*GitHubMatrixEntry `json:"include"`
+}
+
+// GitHubMatrixOptions defines the options for producing a GitHub
+// Actions matrix.
+type GitHubMatrixOptions struct {
+	// SkipSentinel emits a single entry with skip set to true when
+	// the list is empty. GitHub Actions fails jobs with an empty
+	// matrix, so workflows can use this entry to skip the steps
+	// (e.g. if: ${{ !matrix.skip }}) instead.
+	SkipSentinel bool
+}
+
+// ToGitHubMatrix returns a json document that can be used as the
+// matrix of a GitHub Actions job.
+// An empty list produces a matrix with an empty include list.
+func (l Modules) ToGitHubMatrix() ([]byte, error) {
+	return l.ToGitHubMatrixWithOptions(&GitHubMatrixOptions{})
+}
+
+// ToGitHubMatrixWithOptions returns a json document that can be used
+// as the matrix of a GitHub Actions job with the specified options.
+func (l Modules) ToGitHubMatrixWithOptions(options *GitHubMatrixOptions) ([]byte, error) {
+	m := &GitHubMatrix{Include: make([]*GitHubMatrixEntry, 0, len(l))}
+	for _, a := range l {
+		m.Include = append(m.Include, &GitHubMatrixEntry{
+			App:     a.Name(),
+			Path:    a.Path(),
+			Version: a.Version(),
+		})
+	}
+
+	if len(m.Include) == 0 && options.SkipSentinel {
+		m.Include = append(m.Include, &GitHubMatrixEntry{Skip: true})
+	}
+
+	buff, err := json.Marshal(m)
+	if err != nil {
+		return nil, e.Wrap(ErrClassInternal, err)
+	}
+
+	return buff, nil
+}
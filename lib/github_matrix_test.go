@@ -0,0 +1,65 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToGitHubMatrix(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-b"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil),
+	})
+	check(t, err)
+
+	buff, err := mods.ToGitHubMatrix()
+	check(t, err)
+
+	m := mods.indexByName()
+	expected := `{"include":[` +
+		`{"app":"app-b","path":"app-b","version":"` + m["app-b"].Version() + `"},` +
+		`{"app":"app-a","path":"app-a","version":"` + m["app-a"].Version() + `"}]}`
+	assert.Equal(t, expected, string(buff))
+}
+
+func TestToGitHubMatrixForEmptyList(t *testing.T) {
+	buff, err := Modules{}.ToGitHubMatrix()
+	check(t, err)
+
+	assert.Equal(t, `{"include":[]}`, string(buff))
+}
+
+func TestToGitHubMatrixWithSkipSentinel(t *testing.T) {
+	buff, err := Modules{}.ToGitHubMatrixWithOptions(&GitHubMatrixOptions{SkipSentinel: true})
+	check(t, err)
+
+	assert.Equal(t, `{"include":[{"app":"","path":"","version":"","skip":true}]}`, string(buff))
+}
+
+func TestToGitHubMatrixWithSkipSentinelForNonEmptyList(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a"}, nil),
+	})
+	check(t, err)
+
+	buff, err := mods.ToGitHubMatrixWithOptions(&GitHubMatrixOptions{SkipSentinel: true})
+	check(t, err)
+
+	assert.NotContains(t, string(buff), "skip")
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func moduleVersions(mods Modules) map[string]string {
+	r := make(map[string]string)
+	for _, m := range mods {
+		r[m.Name()] = m.Version()
+	}
+	return r
+}
+
+func TestIncrementalDescribe(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModuleWithOptions("app-b", &Spec{Name: "app-b", Dependencies: []string{"app-a"}}))
+	check(t, repo.InitModule("libs/app-c"))
+	check(t, repo.InitModule("app-d"))
+	check(t, repo.Commit("first"))
+
+	w := NewWorld(t, ".tmp/repo")
+	prior, err := w.System.IncrementalDescribe(repo.LastCommit.String(), nil)
+	check(t, err)
+
+	check(t, repo.AppendContent("app-a/foo", "hello"))
+	check(t, repo.InitModule("libs/app-e"))
+	check(t, repo.Remove("app-d"))
+	check(t, repo.Commit("second"))
+
+	incremental, err := w.System.IncrementalDescribe(repo.LastCommit.String(), prior)
+	check(t, err)
+
+	c, err := w.Repo.GetCommit(repo.LastCommit.String())
+	check(t, err)
+	full, err := w.Discover.ModulesInCommit(c)
+	check(t, err)
+
+	assert.Equal(t, []string{"app-a", "app-b", "app-c", "app-e"}, incremental.names())
+	assert.Equal(t, moduleVersions(full), moduleVersions(incremental))
+
+	p := prior.indexByName()
+	i := incremental.indexByName()
+	assert.NotEqual(t, p["app-a"].Version(), i["app-a"].Version())
+	assert.NotEqual(t, p["app-b"].Version(), i["app-b"].Version())
+	assert.Equal(t, p["app-c"].Version(), i["app-c"].Version())
+	assert.True(t, p["app-c"].metadata.spec == i["app-c"].metadata.spec)
+	assert.False(t, p["app-a"].metadata.spec == i["app-a"].metadata.spec)
+}
+
+func TestIncrementalDescribeWithChangedFileDependency(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{Name: "app-a", FileDependencies: []string{"shared/config"}}))
+	check(t, repo.WriteContent("shared/config", "a"))
+	check(t, repo.Commit("first"))
+
+	w := NewWorld(t, ".tmp/repo")
+	prior, err := w.System.IncrementalDescribe(repo.LastCommit.String(), nil)
+	check(t, err)
+
+	check(t, repo.WriteContent("shared/config", "b"))
+	check(t, repo.Commit("second"))
+
+	incremental, err := w.System.IncrementalDescribe(repo.LastCommit.String(), prior)
+	check(t, err)
+
+	assert.NotEqual(t, prior[0].Version(), incremental[0].Version())
+}
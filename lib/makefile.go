@@ -0,0 +1,94 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// ToMakefile returns a Makefile with a phony target for each module in
+// the list. Target of a module has the modules it requires as its
+// prerequisites and its build command for the specified operating
+// system as the recipe, which is run in the module directory.
+// Therefore, make -j builds the modules in parallel while respecting the
+// dependency graph. Modules without a build command for the operating
+// system have an empty recipe. Prerequisites are restricted to the
+// modules in the list.
+// An additional target called all builds all modules.
+func (l Modules) ToMakefile(goos string) (string, error) {
+	index := l.indexByName()
+	names := make([]string, 0, len(l))
+	for _, m := range l {
+		if strings.ContainsAny(m.Name(), " \t\n:;=#%$|\\") || m.Name() == "all" {
+			return "", e.NewErrorf(ErrClassUser, msgInvalidMakeTarget, m.Name())
+		}
+		names = append(names, m.Name())
+	}
+
+	buff := new(bytes.Buffer)
+	fmt.Fprintln(buff, "# Generated by mbt. Do not edit.")
+	fmt.Fprintf(buff, ".PHONY: %s\n", strings.Join(append([]string{"all"}, names...), " "))
+	fmt.Fprintf(buff, "\nall: %s\n", strings.Join(names, " "))
+
+	for _, m := range l {
+		prerequisites := []string{}
+		for _, r := range m.Requires() {
+			if _, ok := index[r.Name()]; ok {
+				prerequisites = append(prerequisites, r.Name())
+			}
+		}
+
+		fmt.Fprintf(buff, "\n%s:", m.Name())
+		if len(prerequisites) > 0 {
+			fmt.Fprintf(buff, " %s", strings.Join(prerequisites, " "))
+		}
+		fmt.Fprintln(buff)
+
+		c, ok := m.CommandForOS(CommandSetBuild, goos)
+		if !ok || c == nil {
+			continue
+		}
+
+		dir := m.Path()
+		if dir == "" {
+			dir = "."
+		}
+
+		argv := []string{"cd", makeShellQuote(dir), "&&"}
+		for _, a := range c.Argv() {
+			argv = append(argv, makeShellQuote(a))
+		}
+		fmt.Fprintf(buff, "\t%s\n", strings.Join(argv, " "))
+	}
+
+	return buff.String(), nil
+}
+
+// makeShellQuote quotes the argument for the shell running the recipe
+// unless it only contains safe characters and escapes the $ signs
+// for make.
+func makeShellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./+=,@") == "" {
+		return s
+	}
+
+	s = "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+	return strings.Replace(s, "$", "$$", -1)
+}
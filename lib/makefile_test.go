@@ -0,0 +1,84 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToMakefile(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("", "r", &Spec{Name: "root", Build: map[string]*Cmd{"default": {Cmd: "make"}}}, nil),
+		newModuleMetadata("lib-a", "l", &Spec{Name: "lib-a", Build: map[string]*Cmd{"linux": {Cmd: "./build.sh", Args: []string{"--out", "dist dir"}}}}, nil),
+		newModuleMetadata("app a", "a", &Spec{Name: "app-a", Dependencies: []string{"lib-a"}, Build: map[string]*Cmd{"default": {Cmd: "echo", Args: []string{"$HOME", "it's"}}}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b", Dependencies: []string{"lib-a", "app-a"}}, nil),
+	})
+	check(t, err)
+
+	mk, err := mods.ToMakefile("linux")
+	check(t, err)
+
+	assert.Equal(t, `# Generated by mbt. Do not edit.
+.PHONY: all root lib-a app-a app-b
+
+all: root lib-a app-a app-b
+
+root:
+	cd . && make
+
+lib-a:
+	cd lib-a && ./build.sh --out 'dist dir'
+
+app-a: lib-a
+	cd 'app a' && echo '$$HOME' 'it'\''s'
+
+app-b: app-a lib-a
+`, mk)
+
+	mk, err = mods.ToMakefile("darwin")
+	check(t, err)
+
+	assert.Contains(t, mk, "\nlib-a:\n\napp-a: lib-a\n")
+}
+
+func TestToMakefileOfSubset(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("lib-a", "l", &Spec{Name: "lib-a"}, nil),
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"lib-a"}, Build: map[string]*Cmd{"default": {Cmd: "make"}}}, nil),
+	})
+	check(t, err)
+
+	mk, err := mods.Filter(func(m *Module) bool { return m.Name() == "app-a" }).ToMakefile("linux")
+	check(t, err)
+
+	assert.Contains(t, mk, "\napp-a:\n\tcd app-a && make\n")
+}
+
+func TestToMakefileWithInvalidTarget(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app:a"}, nil),
+	})
+	check(t, err)
+
+	_, err = mods.ToMakefile("linux")
+
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidMakeTarget, "app:a"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
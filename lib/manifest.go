@@ -22,12 +22,26 @@ import (
 )
 
 func (s *stdSystem) ManifestByDiff(from, to string) (*Manifest, error) {
-	f, err := s.Repo.GetCommit(from)
+	f, err := s.Repo.ResolveRevision(from)
 	if err != nil {
 		return nil, err
 	}
 
-	t, err := s.Repo.GetCommit(to)
+	t, err := s.Repo.ResolveRevision(to)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.MB.ByDiff(f, t)
+}
+
+func (s *stdSystem) ManifestSinceTag(constraint, to string) (*Manifest, error) {
+	t, err := s.Repo.ResolveRevision(to)
+	if err != nil {
+		return nil, err
+	}
+
+	_, f, err := s.Repo.LatestTag(t, constraint)
 	if err != nil {
 		return nil, err
 	}
@@ -71,6 +85,63 @@ func (s *stdSystem) ManifestByWorkspaceChanges() (*Manifest, error) {
 	return s.MB.ByWorkspaceChanges()
 }
 
+func (s *stdSystem) ModulesAtHead() (Modules, error) {
+	empty, err := s.Repo.IsEmpty()
+	if err != nil {
+		return nil, err
+	}
+
+	if empty {
+		return Modules{}, nil
+	}
+
+	c, err := s.Repo.HeadCommit()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Discover.ModulesInCommit(c)
+}
+
+func (s *stdSystem) RepoState() (*RepoState, error) {
+	return readRepoState(s.Repo)
+}
+
+// readRepoState reads the current state of the repository.
+// Workspace status is read on demand (see RepoState.IsDirty).
+func readRepoState(repo Repo) (*RepoState, error) {
+	state := &RepoState{repo: repo}
+
+	empty, err := repo.IsEmpty()
+	if err != nil {
+		return nil, err
+	}
+
+	if empty {
+		return state, nil
+	}
+
+	head, err := repo.HeadCommit()
+	if err != nil {
+		return nil, err
+	}
+	state.Sha = head.ID()
+
+	state.Detached, err = repo.IsDetached()
+	if err != nil {
+		return nil, err
+	}
+
+	if !state.Detached {
+		state.Branch, err = repo.CurrentBranch()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return state, nil
+}
+
 // FilterByName reduces the modules in a Manifest to the
 // ones that are matching the terms specified in filter.
 // Multiple terms can be specified as a comma separated
@@ -93,7 +164,7 @@ func (m *Manifest) FilterByName(filterOptions *FilterOptions) *Manifest {
 		}
 	}
 
-	return &Manifest{Dir: m.Dir, Modules: filteredModules, Sha: m.Sha}
+	return &Manifest{Dir: m.Dir, Modules: filteredModules, Sha: m.Sha, State: m.State, Commit: m.Commit, Changes: m.Changes, deprecationsReported: m.deprecationsReported}
 }
 
 // ApplyFilters will filter the modules in the manifest to the ones that
@@ -118,9 +189,26 @@ func (m *Manifest) ApplyFilters(filterOptions *FilterOptions) (*Manifest, error)
 		}
 	}
 
+	if filterOptions.BuildableOnly {
+		m = m.FilterBuildable()
+	}
+
 	return m, nil
 }
 
+// FilterBuildable reduces the modules in a Manifest to the ones
+// that can be built in current operating system.
+func (m *Manifest) FilterBuildable() *Manifest {
+	filteredModules := make(Modules, 0)
+	for _, mod := range m.Modules {
+		if mod.Buildable() {
+			filteredModules = append(filteredModules, mod)
+		}
+	}
+
+	return &Manifest{Dir: m.Dir, Modules: filteredModules, Sha: m.Sha, State: m.State, Commit: m.Commit, Changes: m.Changes, deprecationsReported: m.deprecationsReported}
+}
+
 func matches(value string, filters []string, fuzzy bool) bool {
 	match := false
 
@@ -137,3 +225,39 @@ func matches(value string, filters []string, fuzzy bool) bool {
 
 	return match
 }
+
+// changeKindNames are the values of MBT_MODULE_CHANGE variable.
+var changeKindNames = map[ChangeKind]string{
+	DirectlyChanged:   "direct",
+	DependencyChanged: "dependency",
+	CommandChanged:    "command",
+}
+
+// ModulesByChangeKind returns the modules in the manifest classified
+// with the specified kind of change.
+func (m *Manifest) ModulesByChangeKind(kind ChangeKind) Modules {
+	mods := Modules{}
+	for _, a := range m.Modules {
+		if k, ok := m.Changes[a.Name()]; ok && k == kind {
+			mods = append(mods, a)
+		}
+	}
+
+	return mods
+}
+
+// EntryPoints returns the modules in the manifest changed by the
+// changes themselves (i.e. directly changed or with changes only in
+// their commands) excluding the modules impacted only because a
+// module they depend on is changed.
+// Manifests containing all modules do not have entry points.
+func (m *Manifest) EntryPoints() Modules {
+	mods := Modules{}
+	for _, a := range m.Modules {
+		if k, ok := m.Changes[a.Name()]; ok && k != DependencyChanged {
+			mods = append(mods, a)
+		}
+	}
+
+	return mods
+}
@@ -17,11 +17,46 @@ package lib
 
 import (
 	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/mbtproject/mbt/e"
 )
 
+// ManifestBuilderOptions specifies the options used to create a ManifestBuilder.
+type ManifestBuilderOptions struct {
+	// MaxFanOut is the maximum number of modules a single changed module
+	// can impact (including itself) via requiredBy dependencies.
+	// Fan out is not checked when this is less than 1.
+	MaxFanOut int
+	// StrictFanOut fails the manifest creation when MaxFanOut is exceeded.
+	// Otherwise, a warning is logged.
+	StrictFanOut bool
+	// IsolateCommandChanges classifies the modules in a diff with changes
+	// only in the build or user defined commands of their spec as
+	// CommandChanged. Modules requiring them are not impacted by such
+	// changes, although their versions still change.
+	IsolateCommandChanges bool
+	// MaxImpactDepth limits the modules impacted by a change to the
+	// ones within this number of requiredBy dependencies from a changed
+	// module (e.g. 1 for the modules directly requiring it and 0 for
+	// just the changed modules). Depth is unlimited when this is nil
+	// or UnlimitedImpactDepth.
+	MaxImpactDepth *int
+}
+
+// UnlimitedImpactDepth is the MaxImpactDepth used to include all the
+// modules requiring a changed module transitively.
+const UnlimitedImpactDepth = -1
+
 // NewManifestBuilder creates a new ManifestBuilder
 func NewManifestBuilder(repo Repo, reducer Reducer, discover Discover, log Log) ManifestBuilder {
-	return &stdManifestBuilder{Repo: repo, Discover: discover, Log: log, Reducer: reducer}
+	return NewManifestBuilderWithOptions(repo, reducer, discover, log, &ManifestBuilderOptions{})
+}
+
+// NewManifestBuilderWithOptions creates a new ManifestBuilder with the specified options.
+func NewManifestBuilderWithOptions(repo Repo, reducer Reducer, discover Discover, log Log, options *ManifestBuilderOptions) ManifestBuilder {
+	return &stdManifestBuilder{Repo: repo, Discover: discover, Log: log, Reducer: reducer, Options: options}
 }
 
 type stdManifestBuilder struct {
@@ -29,6 +64,7 @@ type stdManifestBuilder struct {
 	Repo     Repo
 	Discover Discover
 	Reducer  Reducer
+	Options  *ManifestBuilderOptions
 }
 
 type manifestBuilder func() (*Manifest, error)
@@ -73,11 +109,30 @@ func (b *stdManifestBuilder) ByDiff(from, to Commit) (*Manifest, error) {
 			return nil, err
 		}
 
-		reduced, err = reduced.expandRequiredByDependencies()
+		direct := reduced
+		commandOnly := make(map[string]bool)
+		if b.Options.IsolateCommandChanges {
+			commandOnly, err = b.commandOnlyChanges(direct, deltas, from, to)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		cascading := direct.Filter(func(m *Module) bool {
+			return !commandOnly[m.Name()]
+		})
+		reduced, err = b.expandImpacted(cascading)
 		if err != nil {
 			return nil, err
 		}
 
+		if len(commandOnly) > 0 {
+			reduced, err = withIsolatedChanges(reduced, direct, commandOnly)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		peerDeps := peerDependencies(reduced, mods)
 		for _, dep := range peerDeps {
 			exists := false
@@ -93,10 +148,111 @@ func (b *stdManifestBuilder) ByDiff(from, to Commit) (*Manifest, error) {
 			reduced = append(reduced, dep)
 		}
 
-		return b.buildManifest(reduced, to.ID())
+		if err := reduced.checkConflicts(); err != nil {
+			return nil, err
+		}
+
+		reduced.warnDeprecated(b.Log)
+
+		m, err := b.buildManifest(reduced, to.ID())
+		if err != nil {
+			return nil, err
+		}
+
+		m.Commit = &CommitInfo{Sha: to.ID(), Author: to.Author(), Message: to.Message()}
+		m.deprecationsReported = true
+		m.Changes = classifyChanges(direct, reduced)
+		for n := range commandOnly {
+			m.Changes[n] = CommandChanged
+		}
+		return m, nil
 	})
 }
 
+// commandOnlyChanges returns the names of the changed modules with
+// changes only in the commands of their spec since the merge base of
+// from and to.
+func (b *stdManifestBuilder) commandOnlyChanges(changed Modules, deltas []*DiffDelta, from, to Commit) (map[string]bool, error) {
+	r := make(map[string]bool)
+	candidates := changed.Filter(func(m *Module) bool {
+		return m.Path() != "" && changesOnlySpec(m, deltas)
+	})
+	if len(candidates) == 0 {
+		return r, nil
+	}
+
+	base, err := b.Repo.MergeBase(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	mods, err := b.Discover.ModulesInCommit(base)
+	if err != nil {
+		return nil, err
+	}
+
+	before := mods.indexByName()
+	for _, m := range candidates {
+		if o, ok := before[m.Name()]; ok && specsEqualExceptCommands(o.metadata.spec, m.metadata.spec) {
+			r[m.Name()] = true
+		}
+	}
+
+	return r, nil
+}
+
+// withIsolatedChanges adds the modules with isolated changes to the
+// impacted modules while preserving the dependency order.
+// Modules impacted by other changes are removed from isolated.
+func withIsolatedChanges(impacted, changed Modules, isolated map[string]bool) (Modules, error) {
+	index := impacted.indexByName()
+	for _, m := range changed {
+		if _, ok := index[m.Name()]; ok {
+			delete(isolated, m.Name())
+			continue
+		}
+		index[m.Name()] = m
+		impacted = append(impacted, m)
+	}
+
+	sorted, err := impacted.expandRequiresDependencies()
+	if err != nil {
+		return nil, err
+	}
+
+	return sorted.Filter(func(m *Module) bool {
+		_, ok := index[m.Name()]
+		return ok
+	}), nil
+}
+
+// changesOnlySpec returns true if the spec file is the only file of
+// the module (including its file dependencies) in the deltas.
+func changesOnlySpec(m *Module, deltas []*DiffDelta) bool {
+	prefix := strings.ToLower(m.Path()) + "/"
+	for _, d := range splitRenames(deltas) {
+		p := strings.ToLower(d.NewFile)
+		for _, f := range m.FileDependencies() {
+			if p == strings.ToLower(f) {
+				return false
+			}
+		}
+
+		if strings.HasPrefix(p, prefix) && !isConfigFile(p[len(prefix):]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func specsEqualExceptCommands(a, b *Spec) bool {
+	ac, bc := *a, *b
+	ac.Build, bc.Build = nil, nil
+	ac.Commands, bc.Commands = nil, nil
+	return reflect.DeepEqual(ac, bc)
+}
+
 func (b *stdManifestBuilder) ByPr(src, dst string) (*Manifest, error) {
 	return b.runManifestBuilder(func() (*Manifest, error) {
 		from, err := b.Repo.BranchCommit(dst)
@@ -136,19 +292,31 @@ func (b *stdManifestBuilder) ByCommitContent(sha Commit) (*Manifest, error) {
 			return nil, err
 		}
 
-		if len(diff) > 0 {
-			mods, err = b.Reducer.Reduce(mods, diff)
-			if err != nil {
-				return nil, err
-			}
+		if len(diff) == 0 {
+			return b.buildManifest(mods, sha.ID())
+		}
 
-			mods, err = mods.expandRequiredByDependencies()
-			if err != nil {
-				return nil, err
-			}
+		direct, err := b.Reducer.Reduce(mods, diff)
+		if err != nil {
+			return nil, err
 		}
 
-		return b.buildManifest(mods, sha.ID())
+		mods, err = b.expandImpacted(direct)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := mods.checkConflicts(); err != nil {
+			return nil, err
+		}
+
+		m, err := b.buildManifest(mods, sha.ID())
+		if err != nil {
+			return nil, err
+		}
+
+		m.Changes = classifyChanges(direct, mods)
+		return m, nil
 	})
 }
 
@@ -193,17 +361,74 @@ func (b *stdManifestBuilder) ByWorkspaceChanges() (*Manifest, error) {
 		return nil, err
 	}
 
-	mods, err = b.Reducer.Reduce(mods, deltas)
+	direct, err := b.Reducer.Reduce(mods, deltas)
 	if err != nil {
 		return nil, err
 	}
 
-	mods, err = mods.expandRequiredByDependencies()
+	mods, err = b.expandImpacted(direct)
 	if err != nil {
 		return nil, err
 	}
 
-	return b.buildManifest(mods, "local")
+	if err := mods.checkConflicts(); err != nil {
+		return nil, err
+	}
+
+	m, err := b.buildManifest(mods, "local")
+	if err != nil {
+		return nil, err
+	}
+
+	m.Changes = classifyChanges(direct, mods)
+	return m, nil
+}
+
+// classifyChanges classifies the impacted modules as directly changed
+// if they are in the list of changed modules or dependency changed
+// otherwise.
+func classifyChanges(changed, impacted Modules) map[string]ChangeKind {
+	changes := make(map[string]ChangeKind)
+	for _, m := range impacted {
+		changes[m.Name()] = DependencyChanged
+	}
+
+	for _, m := range changed {
+		changes[m.Name()] = DirectlyChanged
+	}
+
+	return changes
+}
+
+// expandImpacted expands the changed modules to include the modules
+// requiring them up to the maximum depth while enforcing the fan out
+// limit.
+func (b *stdManifestBuilder) expandImpacted(changed Modules) (Modules, error) {
+	depth := UnlimitedImpactDepth
+	if b.Options.MaxImpactDepth != nil {
+		depth = *b.Options.MaxImpactDepth
+		if depth < UnlimitedImpactDepth {
+			return nil, e.NewErrorf(ErrClassUser, msgInvalidImpactDepth, depth)
+		}
+	}
+
+	if b.Options.MaxFanOut > 0 {
+		for _, m := range changed {
+			impacted, err := Modules{m}.expandRequiredByDependenciesToDepth(depth)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(impacted) > b.Options.MaxFanOut {
+				if b.Options.StrictFanOut {
+					return nil, e.NewErrorf(ErrClassUser, msgFanOutExceeded, m.Name(), len(impacted), b.Options.MaxFanOut)
+				}
+				b.Log.Warnf(msgFanOutExceeded, m.Name(), len(impacted), b.Options.MaxFanOut)
+			}
+		}
+	}
+
+	return changed.expandRequiredByDependenciesToDepth(depth)
 }
 
 func (b *stdManifestBuilder) runManifestBuilder(builder manifestBuilder) (*Manifest, error) {
@@ -228,5 +453,11 @@ func (b *stdManifestBuilder) buildManifest(modules Modules, sha string) (*Manife
 			return nil, err
 		}
 	}
-	return &Manifest{Dir: repoPath, Modules: modules, Sha: sha}, nil
+
+	state, err := readRepoState(b.Repo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Manifest{Dir: repoPath, Modules: modules, Sha: sha, State: state}, nil
 }
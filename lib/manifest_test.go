@@ -342,6 +342,78 @@ func TestManifestByDiff(t *testing.T) {
 	assert.Len(t, m.Modules, 0)
 }
 
+func TestManifestByDiffWithRevisions(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.Commit("first"))
+
+	check(t, repo.WriteContent("app-b/foo", "hello"))
+	check(t, repo.Commit("second"))
+
+	check(t, repo.WriteContent("app-a/foo", "hello"))
+	check(t, repo.Commit("third"))
+
+	m, err := NewWorld(t, ".tmp/repo").System.ManifestByDiff("HEAD~1", "HEAD")
+	check(t, err)
+
+	assert.Equal(t, []string{"app-a"}, m.Modules.names())
+
+	m, err = NewWorld(t, ".tmp/repo").System.ManifestByDiff("master~2", "HEAD^")
+	check(t, err)
+
+	assert.Equal(t, []string{"app-b"}, m.Modules.names())
+}
+
+func TestManifestSinceTag(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.InitModule("app-c"))
+	check(t, repo.Commit("first"))
+	check(t, repo.Tag("v1.0.0"))
+
+	check(t, repo.WriteContent("app-a/foo", "hello"))
+	check(t, repo.Commit("second"))
+	check(t, repo.Tag("v1.1.0-rc1"))
+
+	check(t, repo.WriteContent("app-b/foo", "hello"))
+	check(t, repo.Commit("third"))
+
+	m, err := NewWorld(t, ".tmp/repo").System.ManifestSinceTag(">=0.0.0", "HEAD")
+	check(t, err)
+
+	assert.Equal(t, []string{"app-a", "app-b"}, m.Modules.names())
+
+	m, err = NewWorld(t, ".tmp/repo").System.ManifestSinceTag(">=1.1.0-rc1", "HEAD")
+	check(t, err)
+
+	assert.Equal(t, []string{"app-b"}, m.Modules.names())
+}
+
+func TestManifestByDiffWithoutAffectedModules(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+	c1 := repo.LastCommit
+
+	check(t, repo.WriteContent(".travis.yml", "language: go"))
+	check(t, repo.Commit("second"))
+	c2 := repo.LastCommit
+
+	m, err := NewWorld(t, ".tmp/repo").System.ManifestByDiff(c1.String(), c2.String())
+	check(t, err)
+
+	assert.NotNil(t, m.Modules)
+	assert.True(t, m.Modules.Empty())
+}
+
 func TestManifestByHead(t *testing.T) {
 	repo := NewTestRepo(t, ".tmp/repo")
 
@@ -1224,3 +1296,459 @@ func TestApplyFilter(t *testing.T) {
 	assert.Equal(t, "app-b", m1.Modules[0].Name())
 	assert.Equal(t, "app-a", m1.Modules[1].Name())
 }
+
+func TestModulesAtHead(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+	first := repo.LastCommit
+
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.Commit("second"))
+
+	mods, err := NewWorld(t, ".tmp/repo").System.ModulesAtHead()
+	check(t, err)
+	assert.Len(t, mods, 2)
+
+	check(t, repo.CheckoutAndDetach(first.String()))
+
+	mods, err = NewWorld(t, ".tmp/repo").System.ModulesAtHead()
+	check(t, err)
+	assert.Len(t, mods, 1)
+	assert.Equal(t, "app-a", mods[0].Name())
+}
+
+func TestModulesAtHeadOfEmptyRepo(t *testing.T) {
+	clean()
+	NewTestRepo(t, ".tmp/repo")
+
+	mods, err := NewWorld(t, ".tmp/repo").System.ModulesAtHead()
+	check(t, err)
+	assert.Len(t, mods, 0)
+}
+
+func TestManifestByDiffExceedingFanOut(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModuleWithOptions("app-b", &Spec{Name: "app-b", Dependencies: []string{"app-a"}}))
+	check(t, repo.InitModuleWithOptions("app-c", &Spec{Name: "app-c", Dependencies: []string{"app-a"}}))
+	check(t, repo.Commit("first"))
+	first := repo.LastCommit
+
+	check(t, repo.WriteContent("app-a/foo", "bar"))
+	check(t, repo.Commit("second"))
+	second := repo.LastCommit
+
+	w := NewWorld(t, ".tmp/repo")
+	from, err := w.Repo.GetCommit(first.String())
+	check(t, err)
+	to, err := w.Repo.GetCommit(second.String())
+	check(t, err)
+
+	mb := NewManifestBuilderWithOptions(w.Repo, w.Reducer, w.Discover, w.Log, &ManifestBuilderOptions{MaxFanOut: 2})
+	m, err := mb.ByDiff(from, to)
+	check(t, err)
+	assert.Len(t, m.Modules, 3)
+
+	mb = NewManifestBuilderWithOptions(w.Repo, w.Reducer, w.Discover, w.Log, &ManifestBuilderOptions{MaxFanOut: 2, StrictFanOut: true})
+	m, err = mb.ByDiff(from, to)
+	assert.Nil(t, m)
+	assert.EqualError(t, err, fmt.Sprintf(msgFanOutExceeded, "app-a", 3, 2))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+
+	mb = NewManifestBuilderWithOptions(w.Repo, w.Reducer, w.Discover, w.Log, &ManifestBuilderOptions{MaxFanOut: 3, StrictFanOut: true})
+	m, err = mb.ByDiff(from, to)
+	check(t, err)
+	assert.Len(t, m.Modules, 3)
+}
+
+func TestManifestByDiffWithIsolatedCommandChanges(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModuleWithOptions("lib-a", &Spec{Name: "lib-a", Build: map[string]*Cmd{"default": {Cmd: "make"}}}))
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{Name: "app-a", Dependencies: []string{"lib-a"}}))
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.Commit("first"))
+	first := repo.LastCommit
+
+	check(t, repo.InitModuleWithOptions("lib-a", &Spec{Name: "lib-a", Build: map[string]*Cmd{"default": {Cmd: "make", Args: []string{"all"}}}}))
+	check(t, repo.WriteContent("app-b/foo", "bar"))
+	check(t, repo.Commit("second"))
+	second := repo.LastCommit
+
+	w := NewWorld(t, ".tmp/repo")
+	from, err := w.Repo.GetCommit(first.String())
+	check(t, err)
+	to, err := w.Repo.GetCommit(second.String())
+	check(t, err)
+
+	m, err := w.ManifestBuilder.ByDiff(from, to)
+	check(t, err)
+	assert.Equal(t, []string{"app-a", "app-b", "lib-a"}, m.Modules.names())
+
+	mb := NewManifestBuilderWithOptions(w.Repo, w.Reducer, w.Discover, w.Log, &ManifestBuilderOptions{IsolateCommandChanges: true})
+	m, err = mb.ByDiff(from, to)
+	check(t, err)
+
+	assert.Equal(t, []string{"app-b", "lib-a"}, m.Modules.names())
+	assert.Equal(t, map[string]ChangeKind{"lib-a": CommandChanged, "app-b": DirectlyChanged}, m.Changes)
+	assert.Equal(t, []string{"lib-a"}, m.ModulesByChangeKind(CommandChanged).names())
+}
+
+func TestManifestByDiffWithIsolatedCommandChangesAndOtherChanges(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModuleWithOptions("lib-a", &Spec{Name: "lib-a", Build: map[string]*Cmd{"default": {Cmd: "make"}}}))
+	check(t, repo.InitModuleWithOptions("lib-b", &Spec{Name: "lib-b", Build: map[string]*Cmd{"default": {Cmd: "make"}}}))
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{Name: "app-a", Dependencies: []string{"lib-a"}}))
+	check(t, repo.InitModuleWithOptions("app-b", &Spec{Name: "app-b", Dependencies: []string{"lib-b"}}))
+	check(t, repo.Commit("first"))
+	first := repo.LastCommit
+
+	// Command and source changes in lib-a
+	check(t, repo.InitModuleWithOptions("lib-a", &Spec{Name: "lib-a", Build: map[string]*Cmd{"default": {Cmd: "make", Args: []string{"all"}}}}))
+	check(t, repo.WriteContent("lib-a/foo", "bar"))
+	// Command and property changes in lib-b
+	check(t, repo.InitModuleWithOptions("lib-b", &Spec{Name: "lib-b", Build: map[string]*Cmd{"default": {Cmd: "make", Args: []string{"all"}}}, Properties: map[string]interface{}{"foo": "bar"}}))
+	check(t, repo.Commit("second"))
+	second := repo.LastCommit
+
+	w := NewWorld(t, ".tmp/repo")
+	from, err := w.Repo.GetCommit(first.String())
+	check(t, err)
+	to, err := w.Repo.GetCommit(second.String())
+	check(t, err)
+
+	mb := NewManifestBuilderWithOptions(w.Repo, w.Reducer, w.Discover, w.Log, &ManifestBuilderOptions{IsolateCommandChanges: true})
+	m, err := mb.ByDiff(from, to)
+	check(t, err)
+
+	assert.Equal(t, []string{"app-a", "app-b", "lib-a", "lib-b"}, m.Modules.names())
+	assert.Empty(t, m.ModulesByChangeKind(CommandChanged))
+}
+
+func TestManifestByDiffWithMaxImpactDepth(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModule("lib-a"))
+	check(t, repo.InitModuleWithOptions("lib-b", &Spec{Name: "lib-b", Dependencies: []string{"lib-a"}}))
+	check(t, repo.InitModuleWithOptions("app-c", &Spec{Name: "app-c", Dependencies: []string{"lib-b"}}))
+	check(t, repo.Commit("first"))
+	first := repo.LastCommit
+
+	check(t, repo.WriteContent("lib-a/foo", "bar"))
+	check(t, repo.Commit("second"))
+	second := repo.LastCommit
+
+	w := NewWorld(t, ".tmp/repo")
+	from, err := w.Repo.GetCommit(first.String())
+	check(t, err)
+	to, err := w.Repo.GetCommit(second.String())
+	check(t, err)
+
+	mb := NewManifestBuilderWithOptions(w.Repo, w.Reducer, w.Discover, w.Log, &ManifestBuilderOptions{})
+	m, err := mb.ByDiff(from, to)
+	check(t, err)
+	assert.Equal(t, []string{"app-c", "lib-a", "lib-b"}, m.Modules.names())
+
+	depth := UnlimitedImpactDepth
+	mb = NewManifestBuilderWithOptions(w.Repo, w.Reducer, w.Discover, w.Log, &ManifestBuilderOptions{MaxImpactDepth: &depth})
+	m, err = mb.ByDiff(from, to)
+	check(t, err)
+	assert.Equal(t, []string{"app-c", "lib-a", "lib-b"}, m.Modules.names())
+
+	depth = 1
+	mb = NewManifestBuilderWithOptions(w.Repo, w.Reducer, w.Discover, w.Log, &ManifestBuilderOptions{MaxImpactDepth: &depth})
+	m, err = mb.ByDiff(from, to)
+	check(t, err)
+
+	assert.Equal(t, []string{"lib-a", "lib-b"}, m.Modules.names())
+	assert.Equal(t, map[string]ChangeKind{"lib-a": DirectlyChanged, "lib-b": DependencyChanged}, m.Changes)
+
+	depth = 0
+	mb = NewManifestBuilderWithOptions(w.Repo, w.Reducer, w.Discover, w.Log, &ManifestBuilderOptions{MaxImpactDepth: &depth})
+	m, err = mb.ByDiff(from, to)
+	check(t, err)
+
+	assert.Equal(t, []string{"lib-a"}, m.Modules.names())
+
+	depth = -2
+	mb = NewManifestBuilderWithOptions(w.Repo, w.Reducer, w.Discover, w.Log, &ManifestBuilderOptions{MaxImpactDepth: &depth})
+	_, err = mb.ByDiff(from, to)
+
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidImpactDepth, -2))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestChangesOnlySpec(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", FileDependencies: []string{"scripts/build.sh"}}, nil),
+	})
+	check(t, err)
+	a := mods[0]
+
+	assert.True(t, changesOnlySpec(a, []*DiffDelta{{NewFile: "app-a/.mbt.yml", OldFile: "app-a/.mbt.yml"}, {NewFile: "app-b/foo", OldFile: "app-b/foo"}}))
+	assert.False(t, changesOnlySpec(a, []*DiffDelta{{NewFile: "app-a/.mbt.yml", OldFile: "app-a/.mbt.yml"}, {NewFile: "app-a/foo", OldFile: "app-a/foo"}}))
+	assert.False(t, changesOnlySpec(a, []*DiffDelta{{NewFile: "app-a/.mbt.yml", OldFile: "app-a/.mbt.yml"}, {NewFile: "scripts/build.sh", OldFile: "scripts/build.sh"}}))
+	assert.False(t, changesOnlySpec(a, []*DiffDelta{{NewFile: "app-b/foo", OldFile: "app-a/foo"}}))
+}
+
+func TestSpecsEqualExceptCommands(t *testing.T) {
+	a := &Spec{Name: "app-a", Build: map[string]*Cmd{"default": {Cmd: "make"}}, Properties: map[string]interface{}{"foo": "bar"}}
+	b := &Spec{Name: "app-a", Commands: map[string]*UserCmd{"test": {Cmd: "make"}}, Properties: map[string]interface{}{"foo": "bar"}}
+	c := &Spec{Name: "app-a", Properties: map[string]interface{}{"foo": "baz"}}
+
+	assert.True(t, specsEqualExceptCommands(a, b))
+	assert.False(t, specsEqualExceptCommands(a, c))
+	assert.NotNil(t, a.Build)
+}
+
+func TestRepoState(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+	first := repo.LastCommit
+
+	w := NewWorld(t, ".tmp/repo")
+	state, err := w.System.RepoState()
+	check(t, err)
+
+	assert.Equal(t, first.String(), state.Sha)
+	assert.Equal(t, "master", state.Branch)
+	assert.False(t, state.Detached)
+	dirty, err := state.IsDirty()
+	check(t, err)
+	assert.False(t, dirty)
+
+	check(t, repo.WriteContent("app-a/foo", "bar"))
+
+	m, err := w.System.ManifestByCurrentBranch()
+	check(t, err)
+
+	assert.Equal(t, first.String(), m.State.Sha)
+	assert.Equal(t, "master", m.State.Branch)
+	dirty, err = m.State.IsDirty()
+	check(t, err)
+	assert.True(t, dirty)
+}
+
+func TestRepoStateReadsWorkspaceStatusOnDemand(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+
+	w := NewWorld(t, ".tmp/repo")
+	calls := 0
+	w.Repo.Interceptor.Config("IsDirty").Do(func(args ...interface{}) []interface{} {
+		calls++
+		return []interface{}{true, nil}
+	})
+
+	m, err := w.System.ManifestByCurrentBranch()
+	check(t, err)
+	assert.Equal(t, 0, calls)
+
+	for i := 0; i < 2; i++ {
+		dirty, err := m.State.IsDirty()
+		check(t, err)
+		assert.True(t, dirty)
+	}
+	assert.Equal(t, 1, calls)
+}
+
+func TestRepoStateOfDetachedHead(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+	check(t, repo.CheckoutAndDetach(repo.LastCommit.String()))
+
+	state, err := NewWorld(t, ".tmp/repo").System.RepoState()
+	check(t, err)
+
+	assert.Equal(t, repo.LastCommit.String(), state.Sha)
+	assert.Equal(t, "", state.Branch)
+	assert.True(t, state.Detached)
+}
+
+func TestManifestByDiffWithBuildableOnlyFilter(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModuleWithOptions("lib-a", &Spec{Name: "lib-a"}))
+	check(t, repo.InitModuleWithOptions("app-b", &Spec{
+		Name:         "app-b",
+		Dependencies: []string{"lib-a"},
+		Build:        map[string]*Cmd{"default": {Cmd: "echo"}},
+	}))
+	check(t, repo.Commit("first"))
+	first := repo.LastCommit
+
+	check(t, repo.WriteContent("lib-a/foo", "bar"))
+	check(t, repo.Commit("second"))
+
+	m, err := NewWorld(t, ".tmp/repo").System.ManifestByDiff(first.String(), repo.LastCommit.String())
+	check(t, err)
+	assert.Len(t, m.Modules, 2)
+
+	m, err = m.ApplyFilters(&FilterOptions{BuildableOnly: true})
+	check(t, err)
+
+	assert.Len(t, m.Modules, 1)
+	assert.Equal(t, "app-b", m.Modules[0].Name())
+}
+
+func TestManifestByDiffCommitInfo(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+	first := repo.LastCommit
+
+	check(t, repo.WriteContent("app-a/foo", "bar"))
+	check(t, repo.Commit("update app-a"))
+
+	m, err := NewWorld(t, ".tmp/repo").System.ManifestByDiff(first.String(), repo.LastCommit.String())
+	check(t, err)
+
+	assert.Equal(t, &CommitInfo{
+		Sha:     repo.LastCommit.String(),
+		Author:  "alice <alice@wonderland.com>",
+		Message: "update app-a",
+	}, m.Commit)
+}
+
+func TestDeprecatedModuleWarningInDiff(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{Name: "app-a", Deprecated: true, DeprecationMessage: "use app-b"}))
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.Commit("first"))
+	first := repo.LastCommit
+
+	check(t, repo.WriteContent("app-a/foo", "bar"))
+	check(t, repo.Commit("second"))
+	second := repo.LastCommit
+
+	w := NewWorld(t, ".tmp/repo")
+	from, err := w.Repo.GetCommit(first.String())
+	check(t, err)
+	to, err := w.Repo.GetCommit(second.String())
+	check(t, err)
+
+	log := NewTestLog()
+	m, err := NewManifestBuilder(w.Repo, w.Reducer, w.Discover, log).ByDiff(from, to)
+	check(t, err)
+
+	assert.Len(t, m.Modules, 1)
+	assert.Equal(t, []string{fmt.Sprintf(msgDeprecatedModuleWithMessage, "app-a", "use app-b")}, log.Warnings)
+}
+
+func TestChangeClassificationInDiff(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{Name: "app-a", Dependencies: []string{"lib-a"}}))
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.InitModule("lib-a"))
+	check(t, repo.Commit("first"))
+	first := repo.LastCommit
+
+	check(t, repo.WriteContent("lib-a/foo", "bar"))
+	check(t, repo.WriteContent("app-b/foo", "bar"))
+	check(t, repo.Commit("second"))
+	second := repo.LastCommit
+
+	m, err := NewWorld(t, ".tmp/repo").System.ManifestByDiff(first.String(), second.String())
+	check(t, err)
+
+	assert.Len(t, m.Modules, 3)
+	assert.Equal(t, map[string]ChangeKind{
+		"app-a": DependencyChanged,
+		"app-b": DirectlyChanged,
+		"lib-a": DirectlyChanged,
+	}, m.Changes)
+
+	dependencyChanged := m.ModulesByChangeKind(DependencyChanged)
+	assert.Len(t, dependencyChanged, 1)
+	assert.Equal(t, "app-a", dependencyChanged[0].Name())
+}
+
+func TestModulesByChangeKind(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"lib-a"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil),
+		newModuleMetadata("lib-a", "l", &Spec{Name: "lib-a"}, nil),
+	})
+	check(t, err)
+
+	lib := mods.indexByName()["lib-a"]
+	impacted, err := Modules{lib}.expandRequiredByDependencies()
+	check(t, err)
+
+	m := &Manifest{Modules: impacted, Changes: classifyChanges(Modules{lib}, impacted)}
+
+	direct := m.ModulesByChangeKind(DirectlyChanged)
+	assert.Len(t, direct, 1)
+	assert.Equal(t, "lib-a", direct[0].Name())
+
+	dependency := m.ModulesByChangeKind(DependencyChanged)
+	assert.Len(t, dependency, 1)
+	assert.Equal(t, "app-a", dependency[0].Name())
+
+	assert.Len(t, (&Manifest{Modules: mods}).ModulesByChangeKind(DirectlyChanged), 0)
+}
+
+func TestEntryPoints(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"lib-a"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b", Dependencies: []string{"lib-b"}}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c"}, nil),
+		newModuleMetadata("lib-a", "l", &Spec{Name: "lib-a"}, nil),
+		newModuleMetadata("lib-b", "m", &Spec{Name: "lib-b"}, nil),
+	})
+	check(t, err)
+
+	idx := mods.indexByName()
+	changed := Modules{idx["lib-a"], idx["app-c"]}
+	impacted, err := changed.expandRequiredByDependencies()
+	check(t, err)
+	impacted = append(impacted, idx["lib-b"])
+
+	m := &Manifest{Modules: impacted, Changes: classifyChanges(changed, impacted)}
+	m.Changes["lib-b"] = CommandChanged
+
+	assert.ElementsMatch(t, []string{"app-c", "lib-a", "lib-b"}, m.EntryPoints().names())
+	assert.Equal(t, []string{"app-a"}, m.ModulesByChangeKind(DependencyChanged).names())
+	assert.Len(t, (&Manifest{Modules: mods}).EntryPoints(), 0)
+}
+
+func TestConflictingModulesInDiff(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{Name: "app-a", Dependencies: []string{"lib-a"}}))
+	check(t, repo.InitModuleWithOptions("app-b", &Spec{Name: "app-b", ConflictsWith: []string{"app-a"}}))
+	check(t, repo.InitModule("lib-a"))
+	check(t, repo.Commit("first"))
+	first := repo.LastCommit
+
+	check(t, repo.WriteContent("app-b/foo", "bar"))
+	check(t, repo.Commit("second"))
+	second := repo.LastCommit
+
+	check(t, repo.WriteContent("lib-a/foo", "bar"))
+	check(t, repo.Commit("third"))
+	third := repo.LastCommit
+
+	w := NewWorld(t, ".tmp/repo")
+	m, err := w.System.ManifestByDiff(first.String(), second.String())
+	check(t, err)
+	assert.Len(t, m.Modules, 1)
+
+	m, err = w.System.ManifestByDiff(first.String(), third.String())
+	assert.Nil(t, m)
+	assert.EqualError(t, err, fmt.Sprintf(msgConflictingModules, "app-b", "app-a"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
@@ -15,6 +15,12 @@ limitations under the License.
 
 package lib
 
+import (
+	"errors"
+
+	"github.com/mbtproject/mbt/e"
+)
+
 const (
 	// ErrClassNone Not specified
 	ErrClassNone = iota
@@ -23,3 +29,26 @@ const (
 	// ErrClassInternal is an internal error potentially due to a bug
 	ErrClassInternal
 )
+
+// ErrShallowClone is the inner error of the user errors returned when
+// the history required by an operation is not available because the
+// repository is a shallow clone.
+var ErrShallowClone = errors.New("shallow clone")
+
+// IsShallowCloneError returns true if err (or any error wrapped in it)
+// is ErrShallowClone.
+func IsShallowCloneError(err error) bool {
+	for err != nil {
+		if err == ErrShallowClone {
+			return true
+		}
+
+		wrapped, ok := err.(*e.E)
+		if !ok {
+			return false
+		}
+		err = wrapped.InnerError()
+	}
+
+	return false
+}
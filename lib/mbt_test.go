@@ -41,9 +41,9 @@ func (r *TestRepository) InitModule(p string) error {
 	return r.InitModuleWithOptions(p, &Spec{
 		Name: path.Base(p),
 		Build: map[string]*Cmd{
-			"darwin":  {"./build.sh", []string{}},
-			"linux":   {"./build.sh", []string{}},
-			"windows": {"powershell", []string{"-ExecutionPolicy", "Bypass", "-File", ".\\build.ps1"}},
+			"darwin":  {Cmd: "./build.sh", Args: []string{}},
+			"linux":   {Cmd: "./build.sh", Args: []string{}},
+			"windows": {Cmd: "powershell", Args: []string{"-ExecutionPolicy", "Bypass", "-File", ".\\build.ps1"}},
 		},
 		Properties: map[string]interface{}{"foo": "bar", "jar": "car"},
 	})
@@ -103,6 +103,10 @@ func (r *TestRepository) AppendContent(file, content string) error {
 }
 
 func (r *TestRepository) Commit(message string) error {
+	return r.CommitAs(message, "alice", "alice@wonderland.com")
+}
+
+func (r *TestRepository) CommitAs(message, name, email string) error {
 	idx, err := r.Repo.Index()
 	if err != nil {
 		return err
@@ -131,8 +135,8 @@ func (r *TestRepository) Commit(message string) error {
 	}
 
 	sig := &git.Signature{
-		Email: "alice@wonderland.com",
-		Name:  "alice",
+		Email: email,
+		Name:  name,
 		When:  time.Now(),
 	}
 
@@ -283,6 +287,33 @@ func (r *TestRepository) SimpleMerge(src, dst string) (*git.Oid, error) {
 	return head, err
 }
 
+func (r *TestRepository) Tag(name string) error {
+	commit, err := r.Repo.LookupCommit(r.LastCommit)
+	if err != nil {
+		return err
+	}
+	defer commit.Free()
+
+	_, err = r.Repo.Tags.CreateLightweight(name, commit, false)
+	return err
+}
+
+// AddGitlink records a submodule pointing to the specified commit
+// at path p. The submodule is not checked out.
+func (r *TestRepository) AddGitlink(p string, commit *git.Oid) error {
+	idx, err := r.Repo.Index()
+	if err != nil {
+		return err
+	}
+
+	err = idx.Add(&git.IndexEntry{Mode: git.FilemodeCommit, Id: commit, Path: p})
+	if err != nil {
+		return err
+	}
+
+	return idx.Write()
+}
+
 func (r *TestRepository) Remove(p string) error {
 	return os.RemoveAll(path.Join(r.Dir, p))
 }
@@ -392,6 +423,14 @@ func sModules(e interface{}) Modules {
 	return e.(Modules)
 }
 
+func sModule(e interface{}) *Module {
+	if e == nil {
+		return nil
+	}
+
+	return e.(*Module)
+}
+
 func sBuildSummary(e interface{}) *BuildSummary {
 	if e == nil {
 		return nil
@@ -408,6 +447,30 @@ func sRunResult(e interface{}) *RunResult {
 	return e.(*RunResult)
 }
 
+func sRepoState(e interface{}) *RepoState {
+	if e == nil {
+		return nil
+	}
+
+	return e.(*RepoState)
+}
+
+func sBuildNote(e interface{}) *BuildNote {
+	if e == nil {
+		return nil
+	}
+
+	return e.(*BuildNote)
+}
+
+func sModulesDelta(e interface{}) *ModulesDelta {
+	if e == nil {
+		return nil
+	}
+
+	return e.(*ModulesDelta)
+}
+
 func sReference(e interface{}) Reference {
 	if e == nil {
 		return nil
@@ -425,6 +488,21 @@ func (r *TestRepo) GetCommit(sha string) (Commit, error) {
 	return sCommit(ret[0]), sErr(ret[1])
 }
 
+func (r *TestRepo) ResolveCommit(sha string) (Commit, error) {
+	ret := r.Interceptor.Call("ResolveCommit", sha)
+	return sCommit(ret[0]), sErr(ret[1])
+}
+
+func (r *TestRepo) ResolveRevision(rev string) (Commit, error) {
+	ret := r.Interceptor.Call("ResolveRevision", rev)
+	return sCommit(ret[0]), sErr(ret[1])
+}
+
+func (r *TestRepo) LatestTag(commit Commit, constraint string) (string, Commit, error) {
+	ret := r.Interceptor.Call("LatestTag", commit, constraint)
+	return ret[0].(string), sCommit(ret[1]), sErr(ret[2])
+}
+
 func (r *TestRepo) Path() string {
 	ret := r.Interceptor.Call("Path")
 	return ret[0].(string)
@@ -440,6 +518,11 @@ func (r *TestRepo) DiffMergeBase(from, to Commit) ([]*DiffDelta, error) {
 	return ret[0].([]*DiffDelta), sErr(ret[1])
 }
 
+func (r *TestRepo) PatchMergeBase(from, to Commit, paths []string) (string, error) {
+	ret := r.Interceptor.Call("PatchMergeBase", from, to, paths)
+	return ret[0].(string), sErr(ret[1])
+}
+
 func (r *TestRepo) DiffWorkspace() ([]*DiffDelta, error) {
 	ret := r.Interceptor.Call("DiffWorkspace")
 	return ret[0].([]*DiffDelta), sErr(ret[1])
@@ -450,21 +533,56 @@ func (r *TestRepo) Changes(c Commit) ([]*DiffDelta, error) {
 	return ret[0].([]*DiffDelta), sErr(ret[1])
 }
 
+func (r *TestRepo) WalkBlobsUnder(a Commit, paths []string, callback BlobWalkCallback) error {
+	ret := r.Interceptor.Call("WalkBlobsUnder", a, paths, callback)
+	return sErr(ret[0])
+}
+
+func (r *TestRepo) WalkCommits(commit Commit, callback CommitWalkCallback) error {
+	ret := r.Interceptor.Call("WalkCommits", commit, callback)
+	return sErr(ret[0])
+}
+
+func (r *TestRepo) WalkCommitsInRange(from, to Commit, callback CommitWalkCallback) error {
+	ret := r.Interceptor.Call("WalkCommitsInRange", from, to, callback)
+	return sErr(ret[0])
+}
+
 func (r *TestRepo) WalkBlobs(a Commit, callback BlobWalkCallback) error {
 	ret := r.Interceptor.Call("WalkBlobs", a, callback)
 	return sErr(ret[0])
 }
 
+func (r *TestRepo) WalkBlobsExcluding(a Commit, paths, excluded []string, maxDepth int, callback BlobWalkCallback) error {
+	ret := r.Interceptor.Call("WalkBlobsExcluding", a, paths, excluded, maxDepth, callback)
+	return sErr(ret[0])
+}
+
+func (r *TestRepo) WalkGitlinksUnder(commit Commit, paths []string, callback BlobWalkCallback) error {
+	ret := r.Interceptor.Call("WalkGitlinksUnder", commit, paths, callback)
+	return sErr(ret[0])
+}
+
 func (r *TestRepo) BlobContents(blob Blob) ([]byte, error) {
 	ret := r.Interceptor.Call("BlobContents", blob)
 	return ret[0].([]byte), sErr(ret[1])
 }
 
+func (r *TestRepo) BlobSize(blob Blob) (int64, error) {
+	ret := r.Interceptor.Call("BlobSize", blob)
+	return ret[0].(int64), sErr(ret[1])
+}
+
 func (r *TestRepo) BlobContentsFromTree(commit Commit, path string) ([]byte, error) {
 	ret := r.Interceptor.Call("BlobContentsFromTree", commit, path)
 	return ret[0].([]byte), sErr(ret[1])
 }
 
+func (r *TestRepo) TreeBlobContents(treeID, path string) ([]byte, error) {
+	ret := r.Interceptor.Call("TreeBlobContents", treeID, path)
+	return ret[0].([]byte), sErr(ret[1])
+}
+
 func (r *TestRepo) EntryID(commit Commit, path string) (string, error) {
 	ret := r.Interceptor.Call("EntryID", commit, path)
 	return ret[0].(string), sErr(ret[1])
@@ -485,6 +603,21 @@ func (r *TestRepo) CurrentBranchCommit() (Commit, error) {
 	return sCommit(ret[0]), sErr(ret[1])
 }
 
+func (r *TestRepo) HeadCommit() (Commit, error) {
+	ret := r.Interceptor.Call("HeadCommit")
+	return sCommit(ret[0]), sErr(ret[1])
+}
+
+func (r *TestRepo) IsDetached() (bool, error) {
+	ret := r.Interceptor.Call("IsDetached")
+	return ret[0].(bool), sErr(ret[1])
+}
+
+func (r *TestRepo) IsDirty() (bool, error) {
+	ret := r.Interceptor.Call("IsDirty")
+	return ret[0].(bool), sErr(ret[1])
+}
+
 func (r *TestRepo) IsEmpty() (bool, error) {
 	ret := r.Interceptor.Call("IsEmpty")
 	return ret[0].(bool), sErr(ret[1])
@@ -515,6 +648,19 @@ func (r *TestRepo) MergeBase(a, b Commit) (Commit, error) {
 	return sCommit(ret[0]), sErr(ret[1])
 }
 
+func (r *TestRepo) Note(ref string, commit Commit) ([]byte, error) {
+	ret := r.Interceptor.Call("Note", ref, commit)
+	if ret[0] == nil {
+		return nil, sErr(ret[1])
+	}
+	return ret[0].([]byte), sErr(ret[1])
+}
+
+func (r *TestRepo) WriteNote(ref string, commit Commit, content []byte) error {
+	ret := r.Interceptor.Call("WriteNote", ref, commit, content)
+	return sErr(ret[0])
+}
+
 type TestManifestBuilder struct {
 	Interceptor *intercept.Interceptor
 }
@@ -598,6 +744,11 @@ func (s *TestSystem) BuildDiff(from, to string, options *CmdOptions) (*BuildSumm
 	return sBuildSummary(ret[0]), sErr(ret[1])
 }
 
+func (s *TestSystem) BuildSinceTag(constraint, to string, options *CmdOptions) (*BuildSummary, error) {
+	ret := s.Interceptor.Call("BuildSinceTag", constraint, to, options)
+	return sBuildSummary(ret[0]), sErr(ret[1])
+}
+
 func (s *TestSystem) BuildCurrentBranch(filterOptions *FilterOptions, options *CmdOptions) (*BuildSummary, error) {
 	ret := s.Interceptor.Call("BuildCurrentBranch", filterOptions, options)
 	return sBuildSummary(ret[0]), sErr(ret[1])
@@ -639,6 +790,11 @@ func (s *TestSystem) RunInDiff(command, from, to string, options *CmdOptions) (*
 	return sRunResult(ret[0]), sErr(ret[1])
 }
 
+func (s *TestSystem) RunInSinceTag(command, constraint, to string, options *CmdOptions) (*RunResult, error) {
+	ret := s.Interceptor.Call("RunInSinceTag", command, constraint, to, options)
+	return sRunResult(ret[0]), sErr(ret[1])
+}
+
 func (s *TestSystem) RunInCurrentBranch(command string, filterOptions *FilterOptions, options *CmdOptions) (*RunResult, error) {
 	ret := s.Interceptor.Call("RunInCurrentBranch", command, filterOptions, options)
 	return sRunResult(ret[0]), sErr(ret[1])
@@ -675,11 +831,56 @@ func (s *TestSystem) IntersectionByBranch(first, second string) (Modules, error)
 	return sModules(ret[0]), sErr(ret[1])
 }
 
+func (s *TestSystem) BlastRadius(commit, filePath string) (Modules, error) {
+	ret := s.Interceptor.Call("BlastRadius", commit, filePath)
+	return sModules(ret[0]), sErr(ret[1])
+}
+
+func (s *TestSystem) ModulesChangedByAuthor(from, to, authorEmail string) (Modules, error) {
+	ret := s.Interceptor.Call("ModulesChangedByAuthor", from, to, authorEmail)
+	return sModules(ret[0]), sErr(ret[1])
+}
+
+func (s *TestSystem) ModulesAtCommit(sha string) (Modules, error) {
+	ret := s.Interceptor.Call("ModulesAtCommit", sha)
+	return sModules(ret[0]), sErr(ret[1])
+}
+
+func (s *TestSystem) IncrementalDescribe(commit string, prior Modules) (Modules, error) {
+	ret := s.Interceptor.Call("IncrementalDescribe", commit, prior)
+	return sModules(ret[0]), sErr(ret[1])
+}
+
+func (s *TestSystem) ModuleDiff(from, to, name string) (string, error) {
+	ret := s.Interceptor.Call("ModuleDiff", from, to, name)
+	return ret[0].(string), sErr(ret[1])
+}
+
+func (s *TestSystem) DiffCommits(from, to string) (*ModulesDelta, error) {
+	ret := s.Interceptor.Call("DiffCommits", from, to)
+	return sModulesDelta(ret[0]), sErr(ret[1])
+}
+
+func (s *TestSystem) ModuleFileStats(commit, name string) (int, int64, error) {
+	ret := s.Interceptor.Call("ModuleFileStats", commit, name)
+	return ret[0].(int), ret[1].(int64), sErr(ret[2])
+}
+
+func (s *TestSystem) WalkAffected(branch string, callback AffectedWalkCallback) error {
+	ret := s.Interceptor.Call("WalkAffected", branch, callback)
+	return sErr(ret[0])
+}
+
 func (s *TestSystem) ManifestByDiff(from, to string) (*Manifest, error) {
 	ret := s.Interceptor.Call("ManifestByDiff", from, to)
 	return sManifest(ret[0]), sErr(ret[1])
 }
 
+func (s *TestSystem) ManifestSinceTag(constraint, to string) (*Manifest, error) {
+	ret := s.Interceptor.Call("ManifestSinceTag", constraint, to)
+	return sManifest(ret[0]), sErr(ret[1])
+}
+
 func (s *TestSystem) ManifestByPr(src, dst string) (*Manifest, error) {
 	ret := s.Interceptor.Call("ManifestByPr", src, dst)
 	return sManifest(ret[0]), sErr(ret[1])
@@ -715,6 +916,26 @@ func (s *TestSystem) ManifestByWorkspaceChanges() (*Manifest, error) {
 	return sManifest(ret[0]), sErr(ret[1])
 }
 
+func (s *TestSystem) RepoState() (*RepoState, error) {
+	ret := s.Interceptor.Call("RepoState")
+	return sRepoState(ret[0]), sErr(ret[1])
+}
+
+func (s *TestSystem) ModulesAtHead() (Modules, error) {
+	ret := s.Interceptor.Call("ModulesAtHead")
+	return sModules(ret[0]), sErr(ret[1])
+}
+
+func (s *TestSystem) ReadBuildNote(namespace, commit string) (*BuildNote, error) {
+	ret := s.Interceptor.Call("ReadBuildNote", namespace, commit)
+	return sBuildNote(ret[0]), sErr(ret[1])
+}
+
+func (s *TestSystem) WriteBuildNote(namespace string, manifest *Manifest) error {
+	ret := s.Interceptor.Call("WriteBuildNote", namespace, manifest)
+	return sErr(ret[0])
+}
+
 type TestDiscover struct {
 	Interceptor *intercept.Interceptor
 }
@@ -729,6 +950,16 @@ func (d *TestDiscover) ModulesInWorkspace() (Modules, error) {
 	return sModules(ret[0]), sErr(ret[1])
 }
 
+func (d *TestDiscover) ModulesInCommitSince(commit Commit, prior Modules) (Modules, error) {
+	ret := d.Interceptor.Call("ModulesInCommitSince", commit, prior)
+	return sModules(ret[0]), sErr(ret[1])
+}
+
+func (d *TestDiscover) ModuleAtTree(treeID string) (*Module, error) {
+	ret := d.Interceptor.Call("ModuleAtTree", treeID)
+	return sModule(ret[0]), sErr(ret[1])
+}
+
 type TestReducer struct {
 	Interceptor *intercept.Interceptor
 }
@@ -747,6 +978,20 @@ func (w *TestWorkspaceManager) CheckoutAndRun(commit string, fn func() (interfac
 	return ret[0], sErr(ret[1])
 }
 
+// TestLog records the warnings written to it.
+type TestLog struct {
+	Log
+	Warnings []string
+}
+
+func NewTestLog() *TestLog {
+	return &TestLog{Log: NewStdLog(LogLevelNormal)}
+}
+
+func (l *TestLog) Warnf(format string, args ...interface{}) {
+	l.Warnings = append(l.Warnings, fmt.Sprintf(format, args...))
+}
+
 type TestProcessManager struct {
 	Interceptor *intercept.Interceptor
 }
@@ -0,0 +1,73 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import "github.com/mbtproject/mbt/e"
+
+// MergeGraphs merges the modules discovered in multiple repositories
+// into a single graph. External dependencies (see Spec) are resolved
+// by name across all sets, therefore the versions of the modules
+// requiring a module in another set are recalculated to reflect the
+// version of that module.
+// Dependencies outside each set are included in the result.
+// Versions are recalculated with the specified algorithm (see
+// DiscoverOptions.VersionHash), which should be the one used to
+// discover the sets. VersionHashSHA1 is used when it is empty.
+// Returns an error if a module name is defined in more than one set
+// (identified by their index in the arguments, starting from 0).
+func MergeGraphs(versionHash string, sets ...Modules) (Modules, error) {
+	if versionHash == "" {
+		versionHash = VersionHashSHA1
+	}
+	if versionHash != VersionHashSHA1 && versionHash != VersionHashSHA256 {
+		return nil, e.NewErrorf(ErrClassUser, msgUnknownVersionHash, versionHash)
+	}
+
+	setOf := make(map[string]int)
+	metadataSet := make(moduleMetadataSet, 0)
+
+	for i, set := range sets {
+		all, err := set.expandRequiresDependencies()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range all {
+			if j, ok := setOf[m.Name()]; ok {
+				if j != i {
+					return nil, e.NewErrorf(ErrClassUser, msgModuleInMultipleGraphs, m.Name(), j, i)
+				}
+				continue
+			}
+			setOf[m.Name()] = i
+
+			metadata := m.metadata
+			if len(metadata.spec.ExternalDependencies) > 0 {
+				spec := *metadata.spec
+				spec.Dependencies = append([]string{}, spec.Dependencies...)
+				for _, d := range spec.ExternalDependencies {
+					if !m.dependsOn(d) {
+						spec.Dependencies = append(spec.Dependencies, d)
+					}
+				}
+				metadata = newModuleMetadata(metadata.dir, metadata.hash, &spec, metadata.dependentFileHashes)
+			}
+			metadataSet = append(metadataSet, metadata)
+		}
+	}
+
+	return linkModules(metadataSet, versionHash)
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeGraphs(t *testing.T) {
+	first, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-b"}, ExternalDependencies: []string{"lib-x"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil),
+	})
+	check(t, err)
+
+	// Directories can overlap across repositories.
+	second, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-b", "x", &Spec{Name: "lib-x"}, nil),
+	})
+	check(t, err)
+
+	merged, err := MergeGraphs(VersionHashSHA1, first, second)
+	check(t, err)
+	check(t, merged.VerifyGraphConsistency())
+
+	m := merged.indexByName()
+	assert.Equal(t, []string{"app-a", "app-b", "lib-x"}, merged.names())
+	assert.Equal(t, []string{"app-b", "lib-x"}, m["app-a"].Requires().names())
+	assert.Equal(t, []string{"app-a"}, m["lib-x"].RequiredBy().names())
+	assert.NotEqual(t, first.indexByName()["app-a"].Version(), m["app-a"].Version())
+	assert.Equal(t, first.indexByName()["app-b"].Version(), m["app-b"].Version())
+	assert.Equal(t, second[0].Version(), m["lib-x"].Version())
+
+	// Original graphs are not modified
+	assert.Equal(t, []string{"app-b"}, first.indexByName()["app-a"].Requires().names())
+}
+
+func TestMergeGraphsWithSubsets(t *testing.T) {
+	first, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-b"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil),
+	})
+	check(t, err)
+
+	second, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c", ExternalDependencies: []string{"app-b"}}, nil),
+	})
+	check(t, err)
+
+	merged, err := MergeGraphs("", Modules{first.indexByName()["app-a"]}, second)
+	check(t, err)
+
+	assert.Equal(t, []string{"app-a", "app-b", "app-c"}, merged.names())
+	assert.Equal(t, []string{"app-a", "app-c"}, merged.indexByName()["app-b"].RequiredBy().names())
+}
+
+func TestMergeGraphsWithNameCollision(t *testing.T) {
+	first, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a"}, nil),
+	})
+	check(t, err)
+
+	second, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil),
+		newModuleMetadata("other/app-a", "a", &Spec{Name: "app-a"}, nil),
+	})
+	check(t, err)
+
+	_, err = MergeGraphs(VersionHashSHA1, first, second)
+
+	assert.EqualError(t, err, fmt.Sprintf(msgModuleInMultipleGraphs, "app-a", 0, 1))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestMergeGraphsWithMissingExternalDependency(t *testing.T) {
+	first, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", ExternalDependencies: []string{"lib-x"}}, nil),
+	})
+	check(t, err)
+
+	_, err = MergeGraphs(VersionHashSHA1, first)
+
+	assert.EqualError(t, err, "dependency not found app-a -> lib-x")
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestMergeGraphsWithVersionHash(t *testing.T) {
+	first, err := toModulesWithVersionHash(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", ExternalDependencies: []string{"lib-x"}}, nil),
+	}, VersionHashSHA256)
+	check(t, err)
+
+	second, err := toModulesWithVersionHash(moduleMetadataSet{
+		newModuleMetadata("lib-x", "x", &Spec{Name: "lib-x"}, nil),
+	}, VersionHashSHA256)
+	check(t, err)
+
+	merged, err := MergeGraphs(VersionHashSHA256, first, second)
+	check(t, err)
+
+	assert.Equal(t, second[0].Version(), merged.indexByName()["lib-x"].Version())
+	assert.True(t, strings.HasPrefix(merged.indexByName()["app-a"].Version(), "sha256-"))
+
+	_, err = MergeGraphs("md5", first, second)
+
+	assert.EqualError(t, err, fmt.Sprintf(msgUnknownVersionHash, "md5"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// mermaidDirections are the directions accepted by mermaid flowcharts.
+var mermaidDirections = map[string]bool{"TB": true, "TD": true, "BT": true, "RL": true, "LR": true}
+
+// ToMermaid converts specified modules into a mermaid flowchart
+// with an edge from each module to the modules it requires.
+// Direction is the orientation of the flowchart (TB, TD, BT, RL or LR
+// in any case) and defaults to LR when it is empty.
+// Returns an error if the direction is not recognised.
+// Node ids are derived from the module names by replacing the
+// characters other than letters, digits and underscores, while the
+// names are used as the labels of the nodes.
+func (mods Modules) ToMermaid(direction string) (string, error) {
+	d := strings.ToUpper(strings.TrimSpace(direction))
+	if d == "" {
+		d = "LR"
+	}
+	if !mermaidDirections[d] {
+		return "", e.NewErrorf(ErrClassUser, msgUnknownMermaidDirection, direction)
+	}
+
+	ids := make(map[string]string)
+	used := make(map[string]bool)
+	buff := new(bytes.Buffer)
+	node := func(m *Module) string {
+		if id, ok := ids[m.Name()]; ok {
+			return id
+		}
+
+		id := mermaidID(m.Name())
+		for i := 2; used[id]; i++ {
+			id = fmt.Sprintf("%s_%d", mermaidID(m.Name()), i)
+		}
+		ids[m.Name()] = id
+		used[id] = true
+		fmt.Fprintf(buff, "  %s[\"%s\"]\n", id, strings.Replace(m.Name(), `"`, "#quot;", -1))
+		return id
+	}
+
+	fmt.Fprintf(buff, "graph %s\n", d)
+	for _, m := range mods {
+		node(m)
+	}
+
+	edges := []string{}
+	for _, m := range mods {
+		for _, r := range m.Requires() {
+			edges = append(edges, fmt.Sprintf("  %s --> %s\n", node(m), node(r)))
+		}
+	}
+
+	for _, edge := range edges {
+		buff.WriteString(edge)
+	}
+
+	return buff.String(), nil
+}
+
+// mermaidID returns an identifier for a mermaid node with the
+// characters other than ascii letters, digits and underscores
+// replaced with underscores. Keywords such as end are prefixed
+// since they cannot be used as node ids.
+func mermaidID(name string) string {
+	id := []byte(name)
+	for i, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			id[i] = '_'
+		}
+	}
+
+	s := string(id)
+	switch strings.ToLower(s) {
+	case "", "end", "graph", "subgraph", "flowchart", "style", "class", "classdef", "click", "linkstyle", "direction":
+		s = "m_" + s
+	}
+
+	return s
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToMermaid(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"lib.b"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil),
+		newModuleMetadata("lib.b", "c", &Spec{Name: "lib.b", Dependencies: []string{"lib-a"}}, nil),
+		newModuleMetadata("lib-a", "d", &Spec{Name: "lib-a"}, nil),
+	})
+	check(t, err)
+
+	idx := mods.indexByName()
+	ordered := Modules{idx["lib-a"], idx["lib.b"], idx["app-a"], idx["app-b"]}
+
+	out, err := ordered.ToMermaid("LR")
+	check(t, err)
+	assert.Equal(t, `graph LR
+  lib_a["lib-a"]
+  lib_b["lib.b"]
+  app_a["app-a"]
+  app_b["app-b"]
+  lib_b --> lib_a
+  app_a --> lib_b
+`, out)
+
+	out, err = Modules{idx["app-a"]}.ToMermaid("td")
+	check(t, err)
+	assert.Equal(t, `graph TD
+  app_a["app-a"]
+  lib_b["lib.b"]
+  app_a --> lib_b
+`, out)
+}
+
+func TestToMermaidWithUnknownDirection(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a"}, nil),
+	})
+	check(t, err)
+
+	_, err = mods.ToMermaid("sideways")
+
+	assert.EqualError(t, err, fmt.Sprintf(msgUnknownMermaidDirection, "sideways"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+
+	out, err := Modules{}.ToMermaid("")
+	check(t, err)
+	assert.Equal(t, "graph LR\n", out)
+}
+
+func TestToMermaidWithConflictingIDs(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a"}, nil),
+		newModuleMetadata("app_a", "b", &Spec{Name: "app_a"}, nil),
+		newModuleMetadata("end", "c", &Spec{Name: "end", Dependencies: []string{"app-a"}}, nil),
+	})
+	check(t, err)
+
+	idx := mods.indexByName()
+	ordered := Modules{idx["app-a"], idx["app_a"], idx["end"]}
+
+	out, err := ordered.ToMermaid("LR")
+	check(t, err)
+	assert.Equal(t, `graph LR
+  app_a["app-a"]
+  app_a_2["app_a"]
+  m_end["end"]
+  m_end --> app_a
+`, out)
+}
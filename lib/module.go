@@ -16,6 +16,15 @@ limitations under the License.
 package lib
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/mbtproject/mbt/e"
 	"github.com/mbtproject/mbt/graph"
 )
@@ -25,6 +34,36 @@ func (a *Module) Name() string {
 	return a.metadata.spec.Name
 }
 
+// ID returns the stable identifier of the module.
+// Modules without an explicit id in their spec are identified by name.
+func (a *Module) ID() string {
+	if a.metadata.spec.ID == "" {
+		return a.Name()
+	}
+	return a.metadata.spec.ID
+}
+
+// Parent returns the module this module inherits its properties
+// from or nil if it does not inherit any property.
+func (a *Module) Parent() *Module {
+	if len(a.metadata.spec.Inherit) == 0 {
+		return nil
+	}
+
+	name, err := parentOf(a.metadata.spec)
+	if err != nil {
+		return nil
+	}
+
+	for _, r := range a.Requires() {
+		if r.Name() == name {
+			return r
+		}
+	}
+
+	return nil
+}
+
 // Path returns the relative path to module.
 func (a *Module) Path() string {
 	return a.metadata.dir
@@ -35,8 +74,8 @@ func (a *Module) Build() map[string]*Cmd {
 	return a.metadata.spec.Build
 }
 
-// Commands returns a list of user defined commands in the spec.
-func (a *Module) Commands() map[string]*UserCmd {
+// UserCommands returns a list of user defined commands in the spec.
+func (a *Module) UserCommands() map[string]*UserCmd {
 	return a.metadata.spec.Commands
 }
 
@@ -45,6 +84,15 @@ func (a *Module) Properties() map[string]interface{} {
 	return a.metadata.spec.Properties
 }
 
+// RawProperty returns the value of a scalar property as written in
+// the spec, before the implicit typing of yaml is applied (e.g. "01"
+// rather than 1 and "yes" rather than true).
+// Returns false if the property is not defined or is not a scalar.
+func (a *Module) RawProperty(key string) (string, bool) {
+	v, ok := a.metadata.spec.rawProperties[key]
+	return v, ok
+}
+
 // Requires returns an array of modules required by this module.
 func (a *Module) Requires() Modules {
 	return a.requires
@@ -70,6 +118,284 @@ func (a *Module) FileDependencies() []string {
 	return a.metadata.spec.FileDependencies
 }
 
+// Resource returns the name of the shared resource this module's build
+// depends on. Modules sharing a resource are never built concurrently.
+func (a *Module) Resource() string {
+	return a.metadata.spec.Resource
+}
+
+// Weight returns the share of the parallel build budget used by
+// this module while it is built. Weight is 1 if it is not specified
+// or is not positive.
+func (a *Module) Weight() int {
+	if a.metadata.spec.Weight < 1 {
+		return 1
+	}
+	return a.metadata.spec.Weight
+}
+
+// Priority returns the value of the priority property of the module.
+// Modules with lower priority are built first when their dependencies
+// allow it. Priority is 0 if the property is not specified or is not
+// a number.
+func (a *Module) Priority() int {
+	switch v := a.Properties()["priority"].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case uint64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+
+	return 0
+}
+
+// VersionExtensions returns the list of file extensions contributing
+// to the version of this module. All files contribute to the version
+// if this list is empty.
+func (a *Module) VersionExtensions() []string {
+	return a.metadata.spec.VersionExtensions
+}
+
+// EnvFile returns the env file declared for the module.
+// Returns nil if the module does not have an env file.
+func (a *Module) EnvFile() *EnvFile {
+	return a.metadata.spec.EnvFile
+}
+
+// Artifacts returns the build outputs declared for the module.
+func (a *Module) Artifacts() []*Artifact {
+	return a.metadata.spec.Artifacts
+}
+
+// Deprecated returns true if the module is marked as deprecated
+// along with the deprecation message.
+func (a *Module) Deprecated() (bool, string) {
+	return a.metadata.spec.Deprecated, a.metadata.spec.DeprecationMessage
+}
+
+// ConflictsWith returns the names of the modules that are mutually
+// exclusive with this module.
+func (a *Module) ConflictsWith() []string {
+	return a.metadata.spec.ConflictsWith
+}
+
+// CommandSetBuild is the name of the command set containing
+// the build commands of a module.
+const CommandSetBuild = "build"
+
+// CommandForOS returns the command in the specified command set
+// applicable to the specified operating system.
+// Build command set is resolved from the build section of the spec,
+// where the default build command is used if there is no command
+// specific to that operating system.
+// Other command sets are resolved from the user defined commands,
+// which are applicable to an operating system if their os list is
+// empty or contains it.
+func (a *Module) CommandForOS(set, goos string) (*Cmd, bool) {
+	if set == CommandSetBuild {
+		c, ok := a.Build()[goos]
+		if !ok {
+			c, ok = a.Build()["default"]
+		}
+
+		return c, ok
+	}
+
+	c, ok := a.UserCommands()[set]
+	if !ok || c == nil {
+		return nil, false
+	}
+
+	cmd := &Cmd{Cmd: c.Cmd, Args: c.Args, SuccessCodes: c.SuccessCodes, SkipCodes: c.SkipCodes}
+	if len(c.OS) == 0 {
+		return cmd, true
+	}
+
+	for _, os := range c.OS {
+		if os == goos {
+			return cmd, true
+		}
+	}
+
+	return nil, false
+}
+
+// Commands returns all command sets of the module by their name.
+// Commands in each set are keyed by the operating system they are
+// applicable to, where "default" is applicable to any operating system
+// without a specific command (see CommandForOS).
+// Build command set contains the build section of the spec and each
+// user defined command forms a set with its name (e.g. healthcheck).
+// User defined command named build is not included since it is
+// shadowed by the build section.
+// Returned commands are copies and changing them does not affect
+// the module.
+func (a *Module) Commands() map[string]map[string]*Cmd {
+	sets := make(map[string]map[string]*Cmd)
+	build := make(map[string]*Cmd)
+	for os, c := range a.Build() {
+		if c != nil {
+			build[os] = c.copy()
+		}
+	}
+	if len(build) > 0 {
+		sets[CommandSetBuild] = build
+	}
+
+	for name, c := range a.UserCommands() {
+		if c == nil || name == CommandSetBuild {
+			continue
+		}
+
+		set := make(map[string]*Cmd)
+		cmd := &Cmd{Cmd: c.Cmd, Args: c.Args, SuccessCodes: c.SuccessCodes, SkipCodes: c.SkipCodes}
+		if len(c.OS) == 0 {
+			set["default"] = cmd.copy()
+		}
+		for _, os := range c.OS {
+			set[os] = cmd.copy()
+		}
+		sets[name] = set
+	}
+
+	return sets
+}
+
+// declaresCommand returns true if the module has a command in the
+// specified command set regardless of the operating system.
+func (a *Module) declaresCommand(set string) bool {
+	if set == CommandSetBuild {
+		for _, c := range a.Build() {
+			if c != nil {
+				return true
+			}
+		}
+		return false
+	}
+
+	return a.UserCommands()[set] != nil
+}
+
+// Buildable returns true if the module has a build command applicable
+// to current operating system.
+func (a *Module) Buildable() bool {
+	_, ok := a.CommandForOS(CommandSetBuild, runtime.GOOS)
+	return ok
+}
+
+// CommandHash returns the hash of the build command of the module
+// applicable to the specified operating system.
+// Returns an empty string if the module cannot be built in that
+// operating system.
+func (a *Module) CommandHash(goos string) string {
+	c, ok := a.CommandForOS(CommandSetBuild, goos)
+	if !ok || c == nil {
+		return ""
+	}
+
+	return c.Hash()
+}
+
+// Equal returns true if both modules have the same name, version,
+// commands and dependencies.
+func (a *Module) Equal(other *Module) bool {
+	if a == other {
+		return true
+	}
+
+	if a == nil || other == nil {
+		return false
+	}
+
+	if a.Name() != other.Name() || a.Version() != other.Version() {
+		return false
+	}
+
+	if !stringSlicesEqual(a.commandHashes(), other.commandHashes()) {
+		return false
+	}
+
+	return stringSlicesEqual(a.Requires().names(), other.Requires().names())
+}
+
+// commandHashes returns the sorted hashes of all commands of
+// the module qualified by their command set.
+func (a *Module) commandHashes() []string {
+	hashes := make([]string, 0)
+	for os, c := range a.Build() {
+		if c != nil {
+			hashes = append(hashes, CommandSetBuild+"."+os+":"+c.Hash())
+		}
+	}
+
+	for name, c := range a.UserCommands() {
+		if c == nil {
+			continue
+		}
+
+		oses := append([]string{}, c.OS...)
+		sort.Strings(oses)
+		cmd := &Cmd{Cmd: c.Cmd, Args: c.Args}
+		hashes = append(hashes, name+":"+cmd.Hash()+":"+strings.Join(oses, ","))
+	}
+
+	sort.Strings(hashes)
+	return hashes
+}
+
+// copy returns a deep copy of the command.
+func (c *Cmd) copy() *Cmd {
+	return &Cmd{
+		Cmd:          c.Cmd,
+		Args:         append([]string(nil), c.Args...),
+		SuccessCodes: append([]int(nil), c.SuccessCodes...),
+		SkipCodes:    append([]int(nil), c.SkipCodes...),
+	}
+}
+
+// Hash returns a hash of the command and its arguments.
+func (c *Cmd) Hash() string {
+	h := sha1.New()
+	io.WriteString(h, c.Cmd)
+	for _, arg := range c.Args {
+		// Separate each item so that moving characters between
+		// command and arguments results in a different hash.
+		io.WriteString(h, "\x00")
+		io.WriteString(h, arg)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Argv returns the command followed by its arguments.
+// Commands are executed directly without a shell, therefore shell
+// features such as pipes and redirections are not supported unless
+// the command itself is a shell (e.g. sh -c).
+func (c *Cmd) Argv() []string {
+	return append([]string{c.Cmd}, c.Args...)
+}
+
+// Classify returns the result of the command for the specified exit code.
+func (c *Cmd) Classify(exitCode int) CmdResult {
+	if len(c.SuccessCodes) == 0 {
+		if exitCode == 0 {
+			return CmdResultSuccess
+		}
+	} else if containsInt(c.SuccessCodes, exitCode) {
+		return CmdResultSuccess
+	}
+
+	if containsInt(c.SkipCodes, exitCode) {
+		return CmdResultSkipped
+	}
+
+	return CmdResultFailed
+}
+
 type requiredByNodeProvider struct{}
 
 func (p *requiredByNodeProvider) ID(vertex interface{}) interface{} {
@@ -105,17 +431,40 @@ func newModule(metadata *moduleMetadata, requires Modules) *Module {
 		metadata:   metadata,
 	}
 
+	// Dependencies are kept sorted by name so that graph traversals
+	// are deterministic regardless of the order modules are discovered.
 	if requires != nil {
-		mod.requires = requires
+		sorted := append(modulesByNameSorter{}, requires...)
+		sort.Stable(sorted)
+		mod.requires = Modules(sorted)
 	}
 
 	for _, d := range requires {
-		d.requiredBy = append(d.requiredBy, mod)
+		d.requiredBy = d.requiredBy.insertSorted(mod)
 	}
 
 	return mod
 }
 
+// insertSorted inserts the specified module into a list
+// sorted by name, preserving the order.
+func (l Modules) insertSorted(mod *Module) Modules {
+	i := sort.Search(len(l), func(i int) bool {
+		return l[i].Name() > mod.Name()
+	})
+
+	l = append(l, nil)
+	copy(l[i+1:], l[i:])
+	l[i] = mod
+	return l
+}
+
+// Empty returns true if there are no modules in the list.
+// Nil and empty lists are treated the same.
+func (l Modules) Empty() bool {
+	return len(l) == 0
+}
+
 func (l Modules) indexByName() map[string]*Module {
 	q := make(map[string]*Module)
 	for _, a := range l {
@@ -124,6 +473,17 @@ func (l Modules) indexByName() map[string]*Module {
 	return q
 }
 
+// names returns the sorted names of the modules.
+func (l Modules) names() []string {
+	names := make([]string, 0, len(l))
+	for _, a := range l {
+		names = append(names, a.Name())
+	}
+
+	sort.Strings(names)
+	return names
+}
+
 func (l Modules) indexByPath() map[string]*Module {
 	q := make(map[string]*Module)
 	for _, a := range l {
@@ -132,6 +492,585 @@ func (l Modules) indexByPath() map[string]*Module {
 	return q
 }
 
+// DriftedFrom returns the modules with a version different to the one
+// specified in deployed map (keyed by module name).
+// Modules missing in deployed map are also included in the result.
+func (l Modules) DriftedFrom(deployed map[string]string) Modules {
+	return l.Filter(func(a *Module) bool {
+		v, ok := deployed[a.Name()]
+		return !ok || v != a.Version()
+	})
+}
+
+// NeedsBuild returns the modules with a version not found in cache map
+// (keyed by module name) and the modules in their requiredBy
+// dependency chain.
+func (l Modules) NeedsBuild(cache map[string]string) (Modules, error) {
+	return l.DriftedFrom(cache).expandRequiredByDependencies()
+}
+
+// DependentsOf returns the modules requiring the specified module
+// directly or transitively, sorted by name. Specified module is not
+// included in the result.
+func (l Modules) DependentsOf(name string) (Modules, error) {
+	m, ok := l.indexByName()[name]
+	if !ok {
+		return nil, e.NewErrorf(ErrClassUser, msgModuleNotFound, name)
+	}
+
+	impacted, err := Modules{m}.expandRequiredByDependencies()
+	if err != nil {
+		return nil, err
+	}
+
+	dependents := Modules{}
+	for _, d := range impacted {
+		if d != m {
+			dependents = append(dependents, d)
+		}
+	}
+
+	sort.Slice(dependents, func(i, j int) bool {
+		return dependents[i].Name() < dependents[j].Name()
+	})
+
+	return dependents, nil
+}
+
+// Stale returns the modules built before one of their transitive
+// dependencies based on the build times specified in lastBuilt map
+// (keyed by module name).
+// Modules missing in lastBuilt map are not included in the result and
+// dependencies missing in the map are ignored.
+func (l Modules) Stale(lastBuilt map[string]time.Time) Modules {
+	// Latest build time of the transitive dependencies of each module.
+	latest := make(map[string]time.Time)
+	var latestDependency func(a *Module) time.Time
+	latestDependency = func(a *Module) time.Time {
+		if t, ok := latest[a.Name()]; ok {
+			return t
+		}
+
+		var t time.Time
+		for _, r := range a.Requires() {
+			if rt, ok := lastBuilt[r.Name()]; ok && rt.After(t) {
+				t = rt
+			}
+			if rt := latestDependency(r); rt.After(t) {
+				t = rt
+			}
+		}
+
+		latest[a.Name()] = t
+		return t
+	}
+
+	r := make(Modules, 0)
+	for _, a := range l {
+		if t, ok := lastBuilt[a.Name()]; ok && latestDependency(a).After(t) {
+			r = append(r, a)
+		}
+	}
+	return r
+}
+
+// Symbolic tokens accepted by ResolveSelection.
+const (
+	// SelectionAll selects all modules.
+	SelectionAll = "all"
+	// SelectionNone selects no modules.
+	SelectionNone = "none"
+	// SelectionChanged selects the modules impacted by a diff.
+	SelectionChanged = "changed"
+)
+
+// ResolveSelection returns the modules identified by the specified token.
+// Token could be one of the symbolic tokens (all, none or changed) or
+// a comma separated list of module names, in which case the modules
+// matching those names are selected from all.
+func ResolveSelection(token string, all, changed Modules) Modules {
+	switch strings.ToLower(strings.TrimSpace(token)) {
+	case SelectionAll:
+		return all
+	case SelectionNone:
+		return Modules{}
+	case SelectionChanged:
+		return changed
+	}
+
+	filters := strings.Split(strings.ToLower(token), ",")
+	selected := Modules{}
+	for _, m := range all {
+		if matches(strings.ToLower(m.Name()), filters, false) {
+			selected = append(selected, m)
+		}
+	}
+
+	return selected
+}
+
+// SortByPriority returns a copy of the list sorted by the priority of
+// the modules. Order of the modules with same priority is preserved.
+func (l Modules) SortByPriority() Modules {
+	sorted := append(Modules{}, l...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority() < sorted[j].Priority()
+	})
+
+	return sorted
+}
+
+// BuildStages groups the modules in the list into a sequence of stages.
+// Modules in a stage depend only on the modules in previous stages and
+// therefore can be built concurrently. Modules in each stage are sorted
+// by their priority.
+// Only the dependencies within the list are considered.
+func (l Modules) BuildStages() ([]Modules, error) {
+	sorted, err := l.expandRequiresDependencies()
+	if err != nil {
+		return nil, err
+	}
+
+	inList := l.indexByName()
+	stageOf := make(map[string]int)
+	stages := make([]Modules, 0)
+
+	for _, m := range sorted {
+		if _, ok := inList[m.Name()]; !ok {
+			continue
+		}
+
+		stage := 0
+		for _, r := range m.Requires() {
+			if rs, ok := stageOf[r.Name()]; ok && rs+1 > stage {
+				stage = rs + 1
+			}
+		}
+		stageOf[m.Name()] = stage
+
+		if stage == len(stages) {
+			stages = append(stages, Modules{})
+		}
+		stages[stage] = append(stages[stage], m)
+	}
+
+	for i, stage := range stages {
+		stages[i] = stage.SortByPriority()
+	}
+
+	return stages, nil
+}
+
+// OrderedWithStages returns the modules in the list in build order
+// along with the index of their build stage (see BuildStages).
+// Modules in a stage are sorted by their priority and then by name
+// so that the order does not depend on the order of the list.
+func (l Modules) OrderedWithStages() ([]*StagedModule, error) {
+	stages, err := l.BuildStages()
+	if err != nil {
+		return nil, err
+	}
+
+	r := make([]*StagedModule, 0, len(l))
+	for i, stage := range stages {
+		sort.SliceStable(stage, func(a, b int) bool {
+			if stage[a].Priority() != stage[b].Priority() {
+				return stage[a].Priority() < stage[b].Priority()
+			}
+			return stage[a].Name() < stage[b].Name()
+		})
+
+		for _, m := range stage {
+			r = append(r, &StagedModule{Module: m, Stage: i})
+		}
+	}
+
+	return r, nil
+}
+
+// MissingProperty returns the modules that do not have a value for
+// the specified property key.
+func (l Modules) MissingProperty(key string) Modules {
+	return l.Filter(func(m *Module) bool {
+		v, ok := m.Properties()[key]
+		return !ok || v == nil
+	})
+}
+
+// WithoutCommand returns the modules that do not declare a command
+// in the specified command set for any operating system.
+func (l Modules) WithoutCommand(set string) Modules {
+	return l.Filter(func(m *Module) bool {
+		return !m.declaresCommand(set)
+	})
+}
+
+// Filter returns a new list of the modules matching the specified
+// predicate, preserving their order in the list.
+// The list is never modified, therefore filters can be chained.
+func (l Modules) Filter(pred func(*Module) bool) Modules {
+	r := Modules{}
+	for _, m := range l {
+		if pred(m) {
+			r = append(r, m)
+		}
+	}
+
+	return r
+}
+
+// DistinctCommands groups the modules by their build command for
+// the specified operating system. Keys are the command and its
+// arguments separated by spaces. Modules that cannot be built in
+// that operating system are omitted.
+func (l Modules) DistinctCommands(goos string) map[string]Modules {
+	r := make(map[string]Modules)
+	for _, m := range l {
+		c, ok := m.CommandForOS(CommandSetBuild, goos)
+		if !ok || c == nil {
+			continue
+		}
+
+		k := strings.Join(c.Argv(), " ")
+		r[k] = append(r[k], m)
+	}
+
+	return r
+}
+
+// Complement returns the modules in the list that are not in the
+// affected list, sorted by their path.
+// Modules are compared by name.
+func (l Modules) Complement(affected Modules) Modules {
+	index := affected.indexByName()
+	r := Modules{}
+	for _, a := range l {
+		if _, ok := index[a.Name()]; !ok {
+			r = append(r, a)
+		}
+	}
+
+	sort.SliceStable(r, func(i, j int) bool {
+		return r[i].Path() < r[j].Path()
+	})
+
+	return r
+}
+
+// IndexByProperty groups the modules by the value of the specified
+// property. Numbers and booleans are converted to strings. Modules with
+// a list value are listed under each element of the list.
+// Modules without a value or with a map value are not included.
+func (l Modules) IndexByProperty(key string) map[string]Modules {
+	index := make(map[string]Modules)
+	for _, m := range l {
+		v := m.Properties()[key]
+		values, ok := v.([]interface{})
+		if !ok {
+			values = []interface{}{v}
+		}
+
+		seen := make(map[string]bool)
+		for _, value := range values {
+			s, ok := propertyString(value)
+			if !ok || seen[s] {
+				continue
+			}
+
+			seen[s] = true
+			index[s] = append(index[s], m)
+		}
+	}
+
+	return index
+}
+
+// Teams returns the sorted distinct values of the specified ownership
+// property across the modules. Values are resolved the same way as
+// IndexByProperty.
+func (l Modules) Teams(key string) []string {
+	teams := make([]string, 0)
+	for team := range l.IndexByProperty(key) {
+		teams = append(teams, team)
+	}
+
+	sort.Strings(teams)
+	return teams
+}
+
+// propertyString converts a scalar property value to a string.
+// Returns false if the value is not a scalar.
+func propertyString(v interface{}) (string, bool) {
+	switch value := v.(type) {
+	case string:
+		return value, true
+	case bool:
+		return strconv.FormatBool(value), true
+	case int:
+		return strconv.Itoa(value), true
+	case int64:
+		return strconv.FormatInt(value, 10), true
+	case uint64:
+		return strconv.FormatUint(value, 10), true
+	case float64:
+		return strconv.FormatFloat(value, 'g', -1, 64), true
+	}
+
+	return "", false
+}
+
+// Fingerprint returns a hash representing the names and versions of
+// all modules in the list. Fingerprint does not depend on the order of
+// the modules.
+func (l Modules) Fingerprint() string {
+	entries := make([]string, 0, len(l))
+	for _, a := range l {
+		entries = append(entries, a.Name()+":"+a.Version())
+	}
+	sort.Strings(entries)
+
+	h := sha1.New()
+	for _, entry := range entries {
+		io.WriteString(h, entry)
+		io.WriteString(h, "\n")
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// CommonOwner returns the innermost module containing all of the
+// specified repository relative file paths. Modules nested in other
+// modules are contained by them as well. Therefore, the owner of
+// the files in app-a and app-a/lib-b modules is app-a.
+// Returns an error if any of the files does not belong to a module or
+// the files do not belong to a common module. Returns nil if paths
+// is empty.
+// Paths are compared case insensitively like in diffs (see Reducer).
+func (l Modules) CommonOwner(paths []string) (*Module, error) {
+	var common Modules
+	for i, p := range paths {
+		owners := l.owners(p)
+		if len(owners) == 0 {
+			return nil, e.NewErrorf(ErrClassUser, msgNoModuleOwnsPath, p)
+		}
+
+		if i == 0 {
+			common = owners
+			continue
+		}
+
+		index := owners.indexByName()
+		common = common.Filter(func(m *Module) bool {
+			_, ok := index[m.Name()]
+			return ok
+		})
+
+		if len(common) == 0 {
+			return nil, e.NewErrorf(ErrClassUser, msgNoCommonOwner, paths[0], p)
+		}
+	}
+
+	if len(common) == 0 {
+		return nil, nil
+	}
+
+	return common[0], nil
+}
+
+// owners returns the modules containing the specified path with the
+// innermost module first.
+func (l Modules) owners(p string) Modules {
+	p = strings.ToLower(strings.Trim(p, "/"))
+	r := l.Filter(func(m *Module) bool {
+		mp := strings.ToLower(m.Path())
+		return mp == "" || strings.HasPrefix(p, mp+"/")
+	})
+
+	sort.SliceStable(r, func(i, j int) bool {
+		return len(r[i].Path()) > len(r[j].Path())
+	})
+
+	return r
+}
+
+// LongestChain returns the longest chain of modules in the list
+// connected by requires dependencies. Modules are ordered such that
+// each module is required by the module following it.
+// Length of the chain is the minimum number of sequential build
+// stages required to build the modules in the list.
+// Only the dependencies within the list are considered.
+func (l Modules) LongestChain() (Modules, error) {
+	sorted, err := l.expandRequiresDependencies()
+	if err != nil {
+		return nil, err
+	}
+
+	inList := l.indexByName()
+	depth := make(map[string]int)
+	prev := make(map[string]*Module)
+	var last *Module
+
+	for _, m := range sorted {
+		if _, ok := inList[m.Name()]; !ok {
+			continue
+		}
+
+		depth[m.Name()] = 1
+		for _, r := range m.Requires() {
+			if _, ok := inList[r.Name()]; ok && depth[r.Name()]+1 > depth[m.Name()] {
+				depth[m.Name()] = depth[r.Name()] + 1
+				prev[m.Name()] = r
+			}
+		}
+
+		if last == nil || depth[m.Name()] > depth[last.Name()] {
+			last = m
+		}
+	}
+
+	chain := Modules{}
+	for m := last; m != nil; m = prev[m.Name()] {
+		chain = append(Modules{m}, chain...)
+	}
+
+	return chain, nil
+}
+
+// Depths returns the depth of each module in the list keyed by the
+// module name. Depth is the length of the longest path to a module
+// without dependencies (i.e. 0 for the modules without dependencies).
+// Returns an error naming the modules in the cycle if the dependency
+// graph is not acyclic.
+func (l Modules) Depths() (map[string]int, error) {
+	g := make([]interface{}, 0, len(l))
+	for _, a := range l {
+		g = append(g, a)
+	}
+
+	sorted, err := graph.TopSort(&requiresNodeProvider{}, g...)
+	if err != nil {
+		if cycleErr, ok := err.(*graph.CycleError); ok {
+			names := make([]string, 0, len(cycleErr.Path))
+			for _, v := range cycleErr.Path {
+				names = append(names, v.(*Module).Name())
+			}
+			return nil, e.NewErrorf(ErrClassUser, msgCyclicDependency, strings.Join(names, " -> "))
+		}
+		return nil, e.Wrap(ErrClassInternal, err)
+	}
+
+	depth := make(map[string]int)
+	for _, v := range sorted {
+		m := v.(*Module)
+		depth[m.Name()] = 0
+		for _, r := range m.Requires() {
+			if depth[r.Name()]+1 > depth[m.Name()] {
+				depth[m.Name()] = depth[r.Name()] + 1
+			}
+		}
+	}
+
+	r := make(map[string]int)
+	for _, m := range l {
+		r[m.Name()] = depth[m.Name()]
+	}
+
+	return r, nil
+}
+
+// VerifyGraphConsistency checks that the requires and requiredBy
+// relations of the modules in the list are inverses of each other.
+// A failure indicates a bug in the construction of the module graph,
+// hence the error is classified as internal.
+func (l Modules) VerifyGraphConsistency() error {
+	contains := func(list Modules, mod *Module) bool {
+		for _, m := range list {
+			if m == mod {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, m := range l {
+		for _, r := range m.Requires() {
+			if !contains(r.RequiredBy(), m) {
+				return e.NewErrorf(ErrClassInternal, msgInconsistentDependencyGraph, m.Name(), r.Name(), DirectionRequires)
+			}
+		}
+
+		for _, d := range m.RequiredBy() {
+			if !contains(d.Requires(), m) {
+				return e.NewErrorf(ErrClassInternal, msgInconsistentDependencyGraph, m.Name(), d.Name(), DirectionRequiredBy)
+			}
+		}
+	}
+
+	return nil
+}
+
+// WithEdge returns a copy of the modules with an additional dependency
+// from module from to module to. It is useful to evaluate the impact of
+// a new dependency (e.g. on BuildStages) before adding it to the spec.
+// Returns an error if either module is not in the list or the new
+// dependency introduces a cycle. Modules in the list are not modified.
+func (l Modules) WithEdge(from, to string) (Modules, error) {
+	inList := l.indexByName()
+	for _, n := range []string{from, to} {
+		if _, ok := inList[n]; !ok {
+			return nil, e.NewErrorf(ErrClassUser, msgModuleNotFound, n)
+		}
+	}
+
+	// Dependencies outside the list are required to rebuild the graph.
+	all, err := l.expandRequiresDependencies()
+	if err != nil {
+		return nil, err
+	}
+
+	versionHash := VersionHashSHA1
+	metadataSet := make(moduleMetadataSet, 0, len(all))
+	for _, m := range all {
+		if strings.HasPrefix(m.Version(), "sha256-") {
+			versionHash = VersionHashSHA256
+		}
+
+		metadata := m.metadata
+		if m.Name() == from && !m.dependsOn(to) {
+			spec := *metadata.spec
+			spec.Dependencies = append(append([]string{}, spec.Dependencies...), to)
+			metadata = newModuleMetadata(metadata.dir, metadata.hash, &spec, metadata.dependentFileHashes)
+		}
+		metadataSet = append(metadataSet, metadata)
+	}
+
+	mods, err := toModulesWithVersionHash(metadataSet, versionHash)
+	if err != nil {
+		return nil, err
+	}
+
+	r := Modules{}
+	for _, m := range mods {
+		if _, ok := inList[m.Name()]; ok {
+			r = append(r, m)
+		}
+	}
+
+	return r, nil
+}
+
+// dependsOn returns true if the module directly requires the
+// specified module.
+func (a *Module) dependsOn(name string) bool {
+	for _, d := range a.metadata.spec.Dependencies {
+		if d == name {
+			return true
+		}
+	}
+
+	return false
+}
+
 // expandRequiredByDependencies takes a list of Modules and
 // returns a new list of Modules including the ones in their
 // requiredBy (see below) dependency chain.
@@ -166,6 +1105,50 @@ func (l Modules) expandRequiredByDependencies() (Modules, error) {
 	return r, nil
 }
 
+// expandRequiredByDependenciesToDepth is similar to
+// expandRequiredByDependencies except it only includes the modules
+// within maxDepth requiredBy dependencies from the modules in the list.
+// Depth is unlimited when maxDepth is UnlimitedImpactDepth.
+func (l Modules) expandRequiredByDependenciesToDepth(maxDepth int) (Modules, error) {
+	if maxDepth == UnlimitedImpactDepth {
+		return l.expandRequiredByDependencies()
+	}
+
+	depths := make(map[*Module]int)
+	queue := make(Modules, 0, len(l))
+	for _, m := range l {
+		if _, ok := depths[m]; !ok {
+			depths[m] = 0
+			queue = append(queue, m)
+		}
+	}
+
+	for len(queue) > 0 {
+		m := queue[0]
+		queue = queue[1:]
+		if depths[m] == maxDepth {
+			continue
+		}
+
+		for _, r := range m.RequiredBy() {
+			if _, ok := depths[r]; !ok {
+				depths[r] = depths[m] + 1
+				queue = append(queue, r)
+			}
+		}
+	}
+
+	all, err := l.expandRequiredByDependencies()
+	if err != nil {
+		return nil, err
+	}
+
+	return all.Filter(func(m *Module) bool {
+		_, ok := depths[m]
+		return ok
+	}), nil
+}
+
 // expandRequiresDependencies takes a list of Modules and
 // returns a new list of Modules including the ones in their
 // requires (see below) dependency chain.
@@ -192,3 +1175,34 @@ func (l Modules) expandRequiresDependencies() (Modules, error) {
 
 	return r, nil
 }
+
+// checkConflicts returns an error if the list contains two modules
+// declared to be mutually exclusive by either of them.
+func (l Modules) checkConflicts() error {
+	index := l.indexByName()
+	for _, a := range l {
+		for _, c := range a.ConflictsWith() {
+			if _, ok := index[c]; ok && c != a.Name() {
+				return e.NewErrorf(ErrClassUser, msgConflictingModules, a.Name(), c)
+			}
+		}
+	}
+
+	return nil
+}
+
+// warnDeprecated logs a warning for each deprecated module in the list.
+func (l Modules) warnDeprecated(log Log) {
+	for _, a := range l {
+		deprecated, message := a.Deprecated()
+		if !deprecated {
+			continue
+		}
+
+		if message == "" {
+			log.Warnf(msgDeprecatedModule, a.Name())
+		} else {
+			log.Warnf(msgDeprecatedModuleWithMessage, a.Name(), message)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import "github.com/mbtproject/mbt/e"
+
+func (s *stdSystem) ModuleDiff(from, to, name string) (string, error) {
+	f, err := s.Repo.GetCommit(from)
+	if err != nil {
+		return "", err
+	}
+
+	t, err := s.Repo.GetCommit(to)
+	if err != nil {
+		return "", err
+	}
+
+	modules, err := s.Discover.ModulesInCommit(t)
+	if err != nil {
+		return "", err
+	}
+
+	mod, ok := modules.indexByName()[name]
+	if !ok {
+		return "", e.NewErrorf(ErrClassUser, msgModuleNotFound, name)
+	}
+
+	// Module at the root of the repository owns all files.
+	paths := []string{}
+	if mod.Path() != "" {
+		paths = append(paths, mod.Path())
+	}
+
+	return s.Repo.PatchMergeBase(f, t, paths)
+}
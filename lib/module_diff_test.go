@@ -0,0 +1,81 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModuleDiff(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModule("app-ab"))
+	check(t, repo.Commit("first"))
+	first := repo.LastCommit.String()
+
+	check(t, repo.SwitchToBranch("feature"))
+	check(t, repo.AppendContent("app-a/foo", "hello-a\n"))
+	check(t, repo.AppendContent("app-ab/foo", "hello-ab\n"))
+	check(t, repo.Commit("second"))
+	second := repo.LastCommit.String()
+
+	patch, err := NewWorld(t, ".tmp/repo").System.ModuleDiff(first, second, "app-a")
+	check(t, err)
+
+	assert.Contains(t, patch, "diff --git a/app-a/foo b/app-a/foo")
+	assert.Contains(t, patch, "+hello-a")
+	assert.NotContains(t, patch, "app-ab/foo")
+	assert.NotContains(t, patch, "+hello-ab")
+}
+
+func TestModuleDiffWithoutChanges(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.Commit("first"))
+	first := repo.LastCommit.String()
+
+	check(t, repo.AppendContent("app-b/foo", "hello"))
+	check(t, repo.Commit("second"))
+	second := repo.LastCommit.String()
+
+	patch, err := NewWorld(t, ".tmp/repo").System.ModuleDiff(first, second, "app-a")
+	check(t, err)
+
+	assert.Equal(t, "", patch)
+}
+
+func TestModuleDiffOfUnknownModule(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+	first := repo.LastCommit.String()
+
+	_, err := NewWorld(t, ".tmp/repo").System.ModuleDiff(first, first, "app-x")
+
+	assert.EqualError(t, err, fmt.Sprintf(msgModuleNotFound, "app-x"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
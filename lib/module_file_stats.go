@@ -0,0 +1,56 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import "github.com/mbtproject/mbt/e"
+
+func (s *stdSystem) ModuleFileStats(commit, name string) (int, int64, error) {
+	c, err := s.Repo.GetCommit(commit)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	modules, err := s.Discover.ModulesInCommit(c)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	mod, ok := modules.indexByName()[name]
+	if !ok {
+		return 0, 0, e.NewErrorf(ErrClassUser, msgModuleNotFound, name)
+	}
+
+	var (
+		count int
+		bytes int64
+	)
+
+	err = s.Repo.WalkBlobsUnder(c, []string{mod.Path()}, func(b Blob) error {
+		size, err := s.Repo.BlobSize(b)
+		if err != nil {
+			return err
+		}
+
+		count++
+		bytes += size
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return count, bytes, nil
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModuleFileStats(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModule("app-ab"))
+	check(t, repo.WriteContent("app-a/foo", "hello"))
+	check(t, repo.WriteContent("app-a/src/bar", "world!"))
+	check(t, repo.WriteContent("app-ab/foo", "not counted"))
+	check(t, repo.Commit("first"))
+
+	spec, err := os.Stat(path.Join(repo.Dir, "app-a", ".mbt.yml"))
+	check(t, err)
+
+	count, bytes, err := NewWorld(t, ".tmp/repo").System.ModuleFileStats(repo.LastCommit.String(), "app-a")
+	check(t, err)
+
+	assert.Equal(t, 3, count)
+	assert.Equal(t, spec.Size()+11, bytes)
+}
+
+func TestModuleFileStatsOfOlderCommit(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+	first := repo.LastCommit.String()
+
+	check(t, repo.WriteContent("app-a/foo", "hello"))
+	check(t, repo.Commit("second"))
+
+	count, _, err := NewWorld(t, ".tmp/repo").System.ModuleFileStats(first, "app-a")
+	check(t, err)
+
+	assert.Equal(t, 1, count)
+}
+
+func TestModuleFileStatsOfUnknownModule(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+
+	_, _, err := NewWorld(t, ".tmp/repo").System.ModuleFileStats(repo.LastCommit.String(), "app-x")
+
+	assert.EqualError(t, err, fmt.Sprintf(msgModuleNotFound, "app-x"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
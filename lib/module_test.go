@@ -0,0 +1,1022 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmpty(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a"}, nil),
+	})
+	check(t, err)
+
+	assert.False(t, mods.Empty())
+	assert.True(t, Modules{}.Empty())
+	assert.True(t, Modules(nil).Empty())
+}
+
+func TestDriftedFrom(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a"}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c"}, nil),
+	})
+	check(t, err)
+
+	drifted := mods.DriftedFrom(map[string]string{
+		"app-a": "a",
+		"app-b": "old",
+	})
+
+	assert.Len(t, drifted, 2)
+	assert.Equal(t, "app-b", drifted[0].Name())
+	assert.Equal(t, "app-c", drifted[1].Name())
+}
+
+func TestDriftedFromWhenEverythingIsDeployed(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a"}, nil),
+	})
+	check(t, err)
+
+	assert.Len(t, mods.DriftedFrom(map[string]string{"app-a": "a"}), 0)
+}
+
+func TestNeedsBuild(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-b"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c"}, nil),
+		newModuleMetadata("app-d", "d", &Spec{Name: "app-d"}, nil),
+	})
+	check(t, err)
+
+	m := mods.indexByName()
+	cache := map[string]string{
+		"app-a": m["app-a"].Version(),
+		"app-b": "old",
+		"app-c": m["app-c"].Version(),
+	}
+
+	needsBuild, err := mods.NeedsBuild(cache)
+	check(t, err)
+
+	assert.ElementsMatch(t, []string{"app-a", "app-b", "app-d"}, needsBuild.names())
+}
+
+func TestNeedsBuildWhenEverythingIsCached(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-b"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil),
+	})
+	check(t, err)
+
+	m := mods.indexByName()
+	needsBuild, err := mods.NeedsBuild(map[string]string{
+		"app-a": m["app-a"].Version(),
+		"app-b": m["app-b"].Version(),
+	})
+	check(t, err)
+
+	assert.Empty(t, needsBuild)
+}
+
+func TestDependentsOf(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-b"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b", Dependencies: []string{"common-utils"}}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c", Dependencies: []string{"common-utils"}}, nil),
+		newModuleMetadata("app-d", "d", &Spec{Name: "app-d"}, nil),
+		newModuleMetadata("common-utils", "u", &Spec{Name: "common-utils"}, nil),
+	})
+	check(t, err)
+
+	dependents, err := mods.DependentsOf("common-utils")
+	check(t, err)
+	assert.Len(t, dependents, 3)
+	assert.Equal(t, "app-a", dependents[0].Name())
+	assert.Equal(t, "app-b", dependents[1].Name())
+	assert.Equal(t, "app-c", dependents[2].Name())
+
+	dependents, err = mods.DependentsOf("app-d")
+	check(t, err)
+	assert.Empty(t, dependents)
+}
+
+func TestDependentsOfUnknownModule(t *testing.T) {
+	_, err := Modules{}.DependentsOf("app-x")
+
+	assert.EqualError(t, err, fmt.Sprintf(msgModuleNotFound, "app-x"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestStale(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-b"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b", Dependencies: []string{"app-c"}}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c"}, nil),
+		newModuleMetadata("app-d", "d", &Spec{Name: "app-d", Dependencies: []string{"app-c"}}, nil),
+		newModuleMetadata("app-e", "e", &Spec{Name: "app-e"}, nil),
+	})
+	check(t, err)
+
+	now := time.Now()
+	stale := mods.Stale(map[string]time.Time{
+		"app-a": now.Add(-2 * time.Hour),
+		"app-b": now.Add(-3 * time.Hour),
+		"app-c": now.Add(-1 * time.Hour),
+		"app-d": now,
+		"app-e": now.Add(-5 * time.Hour),
+	})
+
+	// app-a is older than app-c which it requires transitively.
+	assert.ElementsMatch(t, []string{"app-a", "app-b"}, stale.names())
+}
+
+func TestStaleWithMissingBuildTimes(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-b"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b", Dependencies: []string{"app-c"}}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c"}, nil),
+	})
+	check(t, err)
+
+	now := time.Now()
+	assert.Equal(t, []string{"app-a"}, mods.Stale(map[string]time.Time{
+		"app-a": now.Add(-1 * time.Hour),
+		"app-c": now,
+	}).names())
+
+	assert.Empty(t, mods.Stale(map[string]time.Time{"app-c": now}))
+}
+
+func TestResolveSelection(t *testing.T) {
+	all, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a"}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c"}, nil),
+	})
+	check(t, err)
+	changed := Modules{all[1]}
+
+	assert.Equal(t, all, ResolveSelection("all", all, changed))
+	assert.Equal(t, changed, ResolveSelection("changed", all, changed))
+	assert.Len(t, ResolveSelection("none", all, changed), 0)
+	assert.Equal(t, all, ResolveSelection("ALL", all, changed))
+
+	selected := ResolveSelection("app-c,app-a", all, changed)
+	assert.Len(t, selected, 2)
+	assert.Equal(t, "app-a", selected[0].Name())
+	assert.Equal(t, "app-c", selected[1].Name())
+}
+
+func TestCommandHash(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Build: map[string]*Cmd{
+			"default": {Cmd: "make", Args: []string{"build"}},
+			"windows": {Cmd: "powershell", Args: []string{"build.ps1"}},
+		}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b", Build: map[string]*Cmd{
+			"default": {Cmd: "make", Args: []string{"build"}},
+		}}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c"}, nil),
+	})
+	check(t, err)
+
+	assert.Equal(t, mods[0].CommandHash("linux"), mods[1].CommandHash("linux"))
+	assert.NotEqual(t, mods[0].CommandHash("windows"), mods[1].CommandHash("windows"))
+	assert.Equal(t, "", mods[2].CommandHash("linux"))
+	assert.NotEqual(t, (&Cmd{Cmd: "make", Args: []string{"build"}}).Hash(), (&Cmd{Cmd: "makeb", Args: []string{"uild"}}).Hash())
+}
+
+func TestCommandForOS(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{
+			Name: "app-a",
+			Build: map[string]*Cmd{
+				"default": {Cmd: "make", Args: []string{"build"}},
+			},
+			Commands: map[string]*UserCmd{
+				"test": {Cmd: "make", Args: []string{"test"}},
+				"lint": {Cmd: "make", Args: []string{"lint"}, OS: []string{"linux"}},
+			},
+		}, nil),
+	})
+	check(t, err)
+
+	c, ok := mods[0].CommandForOS(CommandSetBuild, "darwin")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"build"}, c.Args)
+
+	c, ok = mods[0].CommandForOS("test", "windows")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"test"}, c.Args)
+
+	c, ok = mods[0].CommandForOS("lint", "linux")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"lint"}, c.Args)
+
+	_, ok = mods[0].CommandForOS("lint", "darwin")
+	assert.False(t, ok)
+
+	_, ok = mods[0].CommandForOS("deploy", "linux")
+	assert.False(t, ok)
+}
+
+func TestCommands(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{
+			Name: "app-a",
+			Build: map[string]*Cmd{
+				"default": {Cmd: "make", Args: []string{"build"}},
+				"windows": {Cmd: "build.bat"},
+			},
+			Commands: map[string]*UserCmd{
+				"healthcheck": {Cmd: "curl", Args: []string{"localhost:8080/health"}, SuccessCodes: []int{0, 22}},
+				"lint":        {Cmd: "make", Args: []string{"lint"}, OS: []string{"linux", "darwin"}},
+				"build":       {Cmd: "ignored"},
+			},
+		}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil),
+	})
+	check(t, err)
+
+	sets := mods[0].Commands()
+	assert.Len(t, sets, 3)
+	assert.Equal(t, map[string]*Cmd{
+		"default": {Cmd: "make", Args: []string{"build"}},
+		"windows": {Cmd: "build.bat"},
+	}, sets[CommandSetBuild])
+	assert.Equal(t, map[string]*Cmd{
+		"default": {Cmd: "curl", Args: []string{"localhost:8080/health"}, SuccessCodes: []int{0, 22}},
+	}, sets["healthcheck"])
+	assert.Equal(t, map[string]*Cmd{
+		"linux":  {Cmd: "make", Args: []string{"lint"}},
+		"darwin": {Cmd: "make", Args: []string{"lint"}},
+	}, sets["lint"])
+
+	c, ok := mods[0].CommandForOS("healthcheck", "windows")
+	assert.True(t, ok)
+	assert.Equal(t, sets["healthcheck"]["default"], c)
+
+	sets[CommandSetBuild]["default"].Args[0] = "changed"
+	sets["lint"]["linux"].Cmd = "changed"
+	assert.Equal(t, []string{"build"}, mods[0].Build()["default"].Args)
+	assert.Equal(t, "make", mods[0].UserCommands()["lint"].Cmd)
+
+	assert.Empty(t, mods[1].Commands())
+}
+
+func TestCmdArgv(t *testing.T) {
+	assert.Equal(t, []string{"make"}, (&Cmd{Cmd: "make"}).Argv())
+
+	c := &Cmd{Cmd: "sh", Args: []string{"-c", "echo 'a b' | wc -w"}}
+	argv := c.Argv()
+	assert.Equal(t, []string{"sh", "-c", "echo 'a b' | wc -w"}, argv)
+
+	// Modifying the result does not modify the command
+	argv[1] = "-x"
+	assert.Equal(t, []string{"-c", "echo 'a b' | wc -w"}, c.Args)
+}
+
+func TestCmdClassify(t *testing.T) {
+	c := &Cmd{Cmd: "make"}
+	assert.Equal(t, CmdResultSuccess, c.Classify(0))
+	assert.Equal(t, CmdResultFailed, c.Classify(1))
+	assert.Equal(t, CmdResultFailed, c.Classify(2))
+
+	c = &Cmd{Cmd: "make", SuccessCodes: []int{0, 3}, SkipCodes: []int{2}}
+	assert.Equal(t, CmdResultSuccess, c.Classify(0))
+	assert.Equal(t, CmdResultSuccess, c.Classify(3))
+	assert.Equal(t, CmdResultSkipped, c.Classify(2))
+	assert.Equal(t, CmdResultFailed, c.Classify(1))
+
+	c = &Cmd{Cmd: "make", SuccessCodes: []int{1}}
+	assert.Equal(t, CmdResultFailed, c.Classify(0))
+	assert.Equal(t, CmdResultSuccess, c.Classify(1))
+}
+
+func TestCommandForOSCarriesExitCodes(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{
+			Name: "app-a",
+			Commands: map[string]*UserCmd{
+				"test": {Cmd: "make", SuccessCodes: []int{0, 3}, SkipCodes: []int{2}},
+			},
+		}, nil),
+	})
+	check(t, err)
+
+	c, ok := mods[0].CommandForOS("test", "linux")
+	assert.True(t, ok)
+	assert.Equal(t, []int{0, 3}, c.SuccessCodes)
+	assert.Equal(t, []int{2}, c.SkipCodes)
+}
+
+func TestCommonOwner(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a"}, nil),
+		newModuleMetadata("app-a/lib-b", "b", &Spec{Name: "lib-b"}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c"}, nil),
+	})
+	check(t, err)
+
+	owner, err := mods.CommonOwner([]string{"app-a/main.go", "App-A/docs/readme.md"})
+	check(t, err)
+	assert.Equal(t, "app-a", owner.Name())
+
+	owner, err = mods.CommonOwner([]string{"app-a/lib-b/lib.go"})
+	check(t, err)
+	assert.Equal(t, "lib-b", owner.Name())
+
+	owner, err = mods.CommonOwner([]string{"app-a/lib-b/lib.go", "app-a/main.go"})
+	check(t, err)
+	assert.Equal(t, "app-a", owner.Name())
+
+	owner, err = mods.CommonOwner([]string{})
+	check(t, err)
+	assert.Nil(t, owner)
+}
+
+func TestCommonOwnerOfFilesInMultipleModules(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a"}, nil),
+		newModuleMetadata("app-ab", "b", &Spec{Name: "app-ab"}, nil),
+	})
+	check(t, err)
+
+	_, err = mods.CommonOwner([]string{"app-a/main.go", "app-ab/main.go"})
+
+	assert.EqualError(t, err, fmt.Sprintf(msgNoCommonOwner, "app-a/main.go", "app-ab/main.go"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+
+	_, err = mods.CommonOwner([]string{"app-a/main.go", "readme.md"})
+
+	assert.EqualError(t, err, fmt.Sprintf(msgNoModuleOwnsPath, "readme.md"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestCommonOwnerWithRootModule(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("", "r", &Spec{Name: "root"}, nil),
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a"}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil),
+	})
+	check(t, err)
+
+	owner, err := mods.CommonOwner([]string{"app-a/main.go", "app-b/main.go"})
+	check(t, err)
+	assert.Equal(t, "root", owner.Name())
+
+	owner, err = mods.CommonOwner([]string{"app-b/main.go"})
+	check(t, err)
+	assert.Equal(t, "app-b", owner.Name())
+}
+
+func TestInheritProperties(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{
+			Name:         "app-a",
+			Dependencies: []string{"svc-b"},
+			Inherit:      []string{"team", "owner"},
+		}, nil),
+		newModuleMetadata("svc-b", "b", &Spec{
+			Name:         "svc-b",
+			Dependencies: []string{"lib-c"},
+			Inherit:      []string{"team", "tier"},
+			Properties:   map[string]interface{}{"tier": 2},
+		}, nil),
+		newModuleMetadata("lib-c", "c", &Spec{
+			Name:       "lib-c",
+			Properties: map[string]interface{}{"team": "core", "tier": 1, "owner": "alice"},
+		}, nil),
+	})
+	check(t, err)
+
+	m := mods.indexByName()
+	assert.Equal(t, map[string]interface{}{"team": "core", "tier": 2}, m["svc-b"].Properties())
+	// Properties are inherited transitively only if the parent inherits them.
+	assert.Equal(t, map[string]interface{}{"team": "core"}, m["app-a"].Properties())
+	assert.Equal(t, map[string]interface{}{"team": "core", "tier": 1, "owner": "alice"}, m["lib-c"].Properties())
+
+	assert.Equal(t, "svc-b", m["app-a"].Parent().Name())
+	assert.Equal(t, "lib-c", m["svc-b"].Parent().Name())
+	assert.Nil(t, m["lib-c"].Parent())
+}
+
+func TestInheritPropertiesFromExplicitParent(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{
+			Name:         "app-a",
+			Dependencies: []string{"lib-b", "lib-c"},
+			Inherit:      []string{"team"},
+			Parent:       "lib-c",
+		}, nil),
+		newModuleMetadata("lib-b", "b", &Spec{Name: "lib-b", Properties: map[string]interface{}{"team": "b"}}, nil),
+		newModuleMetadata("lib-c", "c", &Spec{Name: "lib-c", Properties: map[string]interface{}{"team": "c"}}, nil),
+	})
+	check(t, err)
+
+	m := mods.indexByName()
+	assert.Equal(t, "c", m["app-a"].Properties()["team"])
+	assert.Equal(t, "lib-c", m["app-a"].Parent().Name())
+}
+
+func TestInheritPropertiesWithAmbiguousParent(t *testing.T) {
+	_, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"lib-b", "lib-c"}, Inherit: []string{"team"}}, nil),
+		newModuleMetadata("lib-b", "b", &Spec{Name: "lib-b"}, nil),
+		newModuleMetadata("lib-c", "c", &Spec{Name: "lib-c"}, nil),
+	})
+
+	assert.EqualError(t, err, fmt.Sprintf(msgAmbiguousParent, "app-a"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+
+	_, err = toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"lib-b"}, Inherit: []string{"team"}, Parent: "lib-c"}, nil),
+		newModuleMetadata("lib-b", "b", &Spec{Name: "lib-b"}, nil),
+		newModuleMetadata("lib-c", "c", &Spec{Name: "lib-c"}, nil),
+	})
+
+	assert.EqualError(t, err, fmt.Sprintf(msgParentNotDependency, "lib-c", "app-a"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestLongestChain(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-b", "app-d"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b", Dependencies: []string{"app-c"}}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c"}, nil),
+		newModuleMetadata("app-d", "d", &Spec{Name: "app-d"}, nil),
+		newModuleMetadata("app-e", "e", &Spec{Name: "app-e"}, nil),
+	})
+	check(t, err)
+
+	chain, err := mods.LongestChain()
+	check(t, err)
+
+	assert.Len(t, chain, 3)
+	assert.Equal(t, "app-c", chain[0].Name())
+	assert.Equal(t, "app-b", chain[1].Name())
+	assert.Equal(t, "app-a", chain[2].Name())
+}
+
+func TestLongestChainOfEmptyList(t *testing.T) {
+	chain, err := Modules{}.LongestChain()
+	check(t, err)
+
+	assert.Len(t, chain, 0)
+}
+
+func TestDepths(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-b", "app-c"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b", Dependencies: []string{"app-c"}}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c"}, nil),
+		newModuleMetadata("app-d", "d", &Spec{Name: "app-d"}, nil),
+	})
+	check(t, err)
+
+	depths, err := mods.Depths()
+	check(t, err)
+
+	assert.Equal(t, map[string]int{"app-a": 2, "app-b": 1, "app-c": 0, "app-d": 0}, depths)
+}
+
+func TestDepthsOfCyclicModules(t *testing.T) {
+	a := newModule(newModuleMetadata("app-a", "a", &Spec{Name: "app-a"}, nil), nil)
+	b := newModule(newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil), Modules{a})
+	a.requires = Modules{b}
+
+	_, err := Modules{a, b}.Depths()
+
+	assert.EqualError(t, err, fmt.Sprintf(msgCyclicDependency, "app-a -> app-b -> app-a"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestMissingProperty(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Properties: map[string]interface{}{"team": "search"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b", Properties: map[string]interface{}{"team": nil}}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c"}, nil),
+	})
+	check(t, err)
+
+	missing := mods.MissingProperty("team")
+
+	assert.Len(t, missing, 2)
+	assert.Equal(t, "app-b", missing[0].Name())
+	assert.Equal(t, "app-c", missing[1].Name())
+}
+
+func TestOrderedWithStages(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-c"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b", Dependencies: []string{"app-c"}}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c"}, nil),
+		newModuleMetadata("app-d", "d", &Spec{Name: "app-d", Properties: map[string]interface{}{"priority": -1}}, nil),
+		newModuleMetadata("app-e", "e", &Spec{Name: "app-e"}, nil),
+	})
+	check(t, err)
+
+	reversed := Modules{}
+	for i := len(mods) - 1; i >= 0; i-- {
+		reversed = append(reversed, mods[i])
+	}
+
+	for _, l := range []Modules{mods, reversed} {
+		ordered, err := l.OrderedWithStages()
+		check(t, err)
+
+		names := []string{}
+		stages := []int{}
+		for _, s := range ordered {
+			names = append(names, s.Module.Name())
+			stages = append(stages, s.Stage)
+		}
+
+		assert.Equal(t, []string{"app-d", "app-c", "app-e", "app-a", "app-b"}, names)
+		assert.Equal(t, []int{0, 0, 0, 1, 1}, stages)
+	}
+}
+
+func TestWithoutCommand(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{
+			Name:     "app-a",
+			Build:    map[string]*Cmd{"linux": {Cmd: "make"}},
+			Commands: map[string]*UserCmd{"test": {Cmd: "make", Args: []string{"test"}, OS: []string{"windows"}}},
+		}, nil),
+		newModuleMetadata("app-b", "b", &Spec{
+			Name:     "app-b",
+			Commands: map[string]*UserCmd{"lint": {Cmd: "make", Args: []string{"lint"}}},
+		}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c"}, nil),
+	})
+	check(t, err)
+
+	assert.Equal(t, []string{"app-b", "app-c"}, mods.WithoutCommand("test").names())
+	assert.Equal(t, []string{"app-b", "app-c"}, mods.WithoutCommand(CommandSetBuild).names())
+	assert.Equal(t, []string{"app-a", "app-c"}, mods.WithoutCommand("lint").names())
+}
+
+func TestBuildStages(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-b"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c", Properties: map[string]interface{}{"priority": 2}}, nil),
+		newModuleMetadata("lint", "d", &Spec{Name: "lint", Properties: map[string]interface{}{"priority": -1}}, nil),
+	})
+	check(t, err)
+
+	stages, err := mods.BuildStages()
+	check(t, err)
+
+	assert.Len(t, stages, 2)
+	assert.Len(t, stages[0], 3)
+	assert.Equal(t, "lint", stages[0][0].Name())
+	assert.Equal(t, "app-b", stages[0][1].Name())
+	assert.Equal(t, "app-c", stages[0][2].Name())
+	assert.Len(t, stages[1], 1)
+	assert.Equal(t, "app-a", stages[1][0].Name())
+}
+
+func TestSortByPriority(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Properties: map[string]interface{}{"priority": 1}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b", Properties: map[string]interface{}{"priority": "high"}}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c", Properties: map[string]interface{}{"priority": 0.5}}, nil),
+	})
+	check(t, err)
+
+	sorted := mods.SortByPriority()
+
+	assert.Equal(t, "app-b", sorted[0].Name())
+	assert.Equal(t, "app-c", sorted[1].Name())
+	assert.Equal(t, "app-a", sorted[2].Name())
+}
+
+func TestDependenciesAreSortedByName(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c", Dependencies: []string{"lib-b", "lib-a"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b", Dependencies: []string{"lib-a"}}, nil),
+		newModuleMetadata("lib-b", "lb", &Spec{Name: "lib-b"}, nil),
+		newModuleMetadata("lib-a", "la", &Spec{Name: "lib-a"}, nil),
+	})
+	check(t, err)
+
+	m := mods.indexByName()
+	assert.Equal(t, Modules{m["lib-a"], m["lib-b"]}, m["app-c"].Requires())
+	assert.Equal(t, Modules{m["app-b"], m["app-c"]}, m["lib-a"].RequiredBy())
+}
+
+func TestDeprecatedModules(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Deprecated: true}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b", Deprecated: true, DeprecationMessage: "use app-c"}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c"}, nil),
+	})
+	check(t, err)
+
+	m := mods.indexByName()
+	deprecated, message := m["app-b"].Deprecated()
+	assert.True(t, deprecated)
+	assert.Equal(t, "use app-c", message)
+
+	deprecated, _ = m["app-c"].Deprecated()
+	assert.False(t, deprecated)
+
+	log := NewTestLog()
+	Modules{m["app-a"], m["app-b"], m["app-c"]}.warnDeprecated(log)
+
+	assert.Equal(t, []string{
+		fmt.Sprintf(msgDeprecatedModule, "app-a"),
+		fmt.Sprintf(msgDeprecatedModuleWithMessage, "app-b", "use app-c"),
+	}, log.Warnings)
+}
+
+func TestDeepDependencyChain(t *testing.T) {
+	set := moduleMetadataSet{}
+	for i := 0; i < 10000; i++ {
+		spec := &Spec{Name: fmt.Sprintf("app-%d", i)}
+		if i > 0 {
+			spec.Dependencies = []string{fmt.Sprintf("app-%d", i-1)}
+		}
+		set = append(set, newModuleMetadata(spec.Name, spec.Name, spec, nil))
+	}
+
+	mods, err := toModules(set)
+	check(t, err)
+	assert.Len(t, mods, 10000)
+
+	impacted, err := Modules{mods[0]}.expandRequiredByDependencies()
+	check(t, err)
+	assert.Len(t, impacted, 10000)
+
+	chain, err := mods.LongestChain()
+	check(t, err)
+	assert.Len(t, chain, 10000)
+}
+
+func TestExpandRequiredByDependenciesToDepth(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("lib-a", "a", &Spec{Name: "lib-a"}, nil),
+		newModuleMetadata("lib-b", "b", &Spec{Name: "lib-b", Dependencies: []string{"lib-a"}}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c", Dependencies: []string{"lib-b"}}, nil),
+		newModuleMetadata("app-d", "d", &Spec{Name: "app-d", Dependencies: []string{"lib-a", "app-c"}}, nil),
+	})
+	check(t, err)
+	libA := Modules{mods.indexByName()["lib-a"]}
+
+	for depth, expected := range map[int][]string{
+		0:  {"lib-a"},
+		1:  {"lib-a", "lib-b", "app-d"},
+		2:  {"lib-a", "lib-b", "app-c", "app-d"},
+		-1: {"lib-a", "lib-b", "app-c", "app-d"},
+	} {
+		impacted, err := libA.expandRequiredByDependenciesToDepth(depth)
+		check(t, err)
+
+		all, err := libA.expandRequiredByDependencies()
+		check(t, err)
+		inOrder := all.Filter(func(m *Module) bool {
+			for _, n := range expected {
+				if n == m.Name() {
+					return true
+				}
+			}
+			return false
+		})
+
+		// Same order as the unlimited expansion
+		assert.Equal(t, inOrder, impacted, "depth %v", depth)
+		assert.Len(t, impacted, len(expected), "depth %v", depth)
+	}
+}
+
+func TestModuleEqual(t *testing.T) {
+	build := map[string]*Cmd{"default": {Cmd: "make", Args: []string{"build"}}}
+	base, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Build: build, Dependencies: []string{"lib-a"}, Properties: map[string]interface{}{"foo": "bar"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b", Build: build}, nil),
+		newModuleMetadata("lib-a", "l", &Spec{Name: "lib-a"}, nil),
+	})
+	check(t, err)
+
+	head, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Build: build, Dependencies: []string{"lib-a"}, Properties: map[string]interface{}{"foo": "baz"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b", Build: map[string]*Cmd{"default": {Cmd: "make", Args: []string{"all"}}}}, nil),
+		newModuleMetadata("lib-a", "l", &Spec{Name: "lib-a"}, nil),
+	})
+	check(t, err)
+
+	b := base.indexByName()
+	h := head.indexByName()
+
+	// Properties are not compared
+	assert.True(t, b["app-a"].Equal(h["app-a"]))
+	assert.False(t, b["app-b"].Equal(h["app-b"]))
+	assert.False(t, b["app-a"].Equal(b["app-b"]))
+	assert.False(t, b["app-a"].Equal(nil))
+}
+
+func TestIndexByProperty(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Properties: map[string]interface{}{"team": "core"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b", Properties: map[string]interface{}{"team": []interface{}{"core", "web", "core"}}}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c", Properties: map[string]interface{}{"team": 42}}, nil),
+		newModuleMetadata("app-d", "d", &Spec{Name: "app-d", Properties: map[string]interface{}{"team": true}}, nil),
+		newModuleMetadata("app-e", "e", &Spec{Name: "app-e", Properties: map[string]interface{}{"team": 1.5}}, nil),
+		newModuleMetadata("app-f", "f", &Spec{Name: "app-f", Properties: map[string]interface{}{"team": map[string]interface{}{"a": "b"}}}, nil),
+		newModuleMetadata("app-g", "g", &Spec{Name: "app-g"}, nil),
+	})
+	check(t, err)
+
+	index := mods.IndexByProperty("team")
+
+	assert.Len(t, index, 5)
+	assert.Len(t, index["core"], 2)
+	assert.Equal(t, "app-a", index["core"][0].Name())
+	assert.Equal(t, "app-b", index["core"][1].Name())
+	assert.Len(t, index["web"], 1)
+	assert.Equal(t, "app-c", index["42"][0].Name())
+	assert.Equal(t, "app-d", index["true"][0].Name())
+	assert.Equal(t, "app-e", index["1.5"][0].Name())
+}
+
+func TestVerifyGraphConsistency(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-b", "app-c"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b", Dependencies: []string{"app-c"}}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c"}, nil),
+	})
+	check(t, err)
+
+	check(t, mods.VerifyGraphConsistency())
+}
+
+func TestVerifyGraphConsistencyWithMissingRequiredBy(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-b"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil),
+	})
+	check(t, err)
+
+	mods.indexByName()["app-b"].requiredBy = Modules{}
+	err = mods.VerifyGraphConsistency()
+
+	assert.EqualError(t, err, fmt.Sprintf(msgInconsistentDependencyGraph, "app-a", "app-b", DirectionRequires))
+	assert.Equal(t, ErrClassInternal, (err.(*e.E)).Class())
+}
+
+func TestVerifyGraphConsistencyWithMissingRequires(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-b"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil),
+	})
+	check(t, err)
+
+	mods.indexByName()["app-a"].requires = Modules{}
+	err = mods.VerifyGraphConsistency()
+
+	assert.EqualError(t, err, fmt.Sprintf(msgInconsistentDependencyGraph, "app-b", "app-a", DirectionRequiredBy))
+	assert.Equal(t, ErrClassInternal, (err.(*e.E)).Class())
+}
+
+func TestFilter(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-b"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c"}, nil),
+	})
+	check(t, err)
+
+	r := mods.Filter(func(m *Module) bool {
+		return m.Name() != "app-b"
+	})
+
+	assert.Equal(t, []string{"app-a", "app-c"}, r.names())
+	assert.Len(t, mods, 3)
+
+	none := r.Filter(func(m *Module) bool { return false })
+	assert.NotNil(t, none)
+	assert.Empty(t, none)
+}
+
+func TestDistinctCommands(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Build: map[string]*Cmd{
+			"default": {Cmd: "make", Args: []string{"build"}},
+		}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b", Build: map[string]*Cmd{
+			"default": {Cmd: "make", Args: []string{"build"}},
+			"windows": {Cmd: "powershell", Args: []string{"-ExecutionPolicy", "Bypass", "-File", ".\\build.ps1"}},
+		}}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c", Build: map[string]*Cmd{
+			"linux": {Cmd: "./build.sh"},
+		}}, nil),
+		newModuleMetadata("app-d", "d", &Spec{Name: "app-d"}, nil),
+	})
+	check(t, err)
+
+	linux := mods.DistinctCommands("linux")
+	assert.Len(t, linux, 2)
+	assert.Equal(t, []string{"app-a", "app-b"}, linux["make build"].names())
+	assert.Equal(t, []string{"app-c"}, linux["./build.sh"].names())
+
+	windows := mods.DistinctCommands("windows")
+	assert.Len(t, windows, 2)
+	assert.Equal(t, []string{"app-a"}, windows["make build"].names())
+	assert.Equal(t, []string{"app-b"}, windows["powershell -ExecutionPolicy Bypass -File .\\build.ps1"].names())
+}
+
+func TestWeight(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Weight: 4}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c", Weight: -1}, nil),
+	})
+	check(t, err)
+
+	m := mods.indexByName()
+	assert.Equal(t, 4, m["app-a"].Weight())
+	assert.Equal(t, 1, m["app-b"].Weight())
+	assert.Equal(t, 1, m["app-c"].Weight())
+}
+
+func TestID(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", ID: "1234"}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil),
+	})
+	check(t, err)
+
+	m := mods.indexByName()
+	assert.Equal(t, "1234", m["app-a"].ID())
+	assert.Equal(t, "app-b", m["app-b"].ID())
+}
+
+func TestDuplicateID(t *testing.T) {
+	_, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", ID: "1234"}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b", ID: "1234"}, nil),
+	})
+
+	assert.EqualError(t, err, fmt.Sprintf(msgDuplicateModuleID, "1234", "app-b", "app-a"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestIDConflictingWithName(t *testing.T) {
+	_, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a"}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b", ID: "app-a"}, nil),
+	})
+
+	assert.EqualError(t, err, fmt.Sprintf(msgDuplicateModuleID, "app-a", "app-b", "app-a"))
+}
+
+func TestWithEdge(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-b"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c"}, nil),
+	})
+	check(t, err)
+
+	withEdge, err := mods.WithEdge("app-b", "app-c")
+	check(t, err)
+
+	stages, err := withEdge.BuildStages()
+	check(t, err)
+	assert.Len(t, stages, 3)
+	assert.Equal(t, "app-c", stages[0][0].Name())
+	assert.Equal(t, "app-b", stages[1][0].Name())
+	assert.Equal(t, "app-a", stages[2][0].Name())
+
+	// Original graph is not modified
+	m := mods.indexByName()
+	assert.Empty(t, m["app-b"].Requires())
+	assert.Empty(t, m["app-c"].RequiredBy())
+	assert.Equal(t, []string{"app-b"}, withEdge.indexByName()["app-a"].Requires().names())
+	check(t, withEdge.VerifyGraphConsistency())
+	check(t, mods.VerifyGraphConsistency())
+
+	// Existing edges are not duplicated
+	withEdge, err = mods.WithEdge("app-a", "app-b")
+	check(t, err)
+	assert.Len(t, withEdge.indexByName()["app-a"].Requires(), 1)
+}
+
+func TestWithEdgeOfSubset(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-b"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c"}, nil),
+	})
+	check(t, err)
+
+	m := mods.indexByName()
+	withEdge, err := Modules{m["app-a"], m["app-c"]}.WithEdge("app-c", "app-a")
+	check(t, err)
+
+	assert.Equal(t, []string{"app-a", "app-c"}, withEdge.names())
+	assert.Equal(t, []string{"app-a"}, withEdge[1].Requires().names())
+	assert.Equal(t, []string{"app-b"}, withEdge[0].Requires().names())
+}
+
+func TestWithEdgeIntroducingCycle(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-b"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil),
+	})
+	check(t, err)
+
+	_, err = mods.WithEdge("app-b", "app-a")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cyclic dependency")
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestWithEdgeToUnknownModule(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a"}, nil),
+	})
+	check(t, err)
+
+	_, err = mods.WithEdge("app-a", "app-x")
+	assert.EqualError(t, err, fmt.Sprintf(msgModuleNotFound, "app-x"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestTeams(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Properties: map[string]interface{}{"owner": "web"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b", Properties: map[string]interface{}{"owner": []interface{}{"infra", "core"}}}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c", Properties: map[string]interface{}{"owner": "core"}}, nil),
+		newModuleMetadata("app-d", "d", &Spec{Name: "app-d"}, nil),
+	})
+	check(t, err)
+
+	assert.Equal(t, []string{"core", "infra", "web"}, mods.Teams("owner"))
+	assert.Equal(t, []string{}, mods.Teams("team"))
+	assert.Equal(t, []string{}, Modules{}.Teams("owner"))
+}
+
+func TestCheckConflicts(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a"}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b", ConflictsWith: []string{"app-a"}}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c"}, nil),
+	})
+	check(t, err)
+
+	m := mods.indexByName()
+
+	assert.NoError(t, Modules{m["app-a"], m["app-c"]}.checkConflicts())
+	assert.NoError(t, Modules{m["app-b"], m["app-c"]}.checkConflicts())
+
+	err = Modules{m["app-a"], m["app-b"]}.checkConflicts()
+	assert.EqualError(t, err, fmt.Sprintf(msgConflictingModules, "app-b", "app-a"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestComplement(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("services/app-a", "a", &Spec{Name: "app-a"}, nil),
+		newModuleMetadata("apps/app-b", "b", &Spec{Name: "app-b"}, nil),
+		newModuleMetadata("apps/app-c", "c", &Spec{Name: "app-c"}, nil),
+		newModuleMetadata("app-d", "d", &Spec{Name: "app-d"}, nil),
+	})
+	check(t, err)
+
+	m := mods.indexByName()
+	unaffected := mods.Complement(Modules{m["app-b"]})
+
+	assert.Len(t, unaffected, 3)
+	assert.Equal(t, "app-d", unaffected[0].Name())
+	assert.Equal(t, "app-c", unaffected[1].Name())
+	assert.Equal(t, "app-a", unaffected[2].Name())
+
+	assert.Len(t, mods.Complement(mods), 0)
+	assert.Len(t, mods.Complement(Modules{}), 4)
+}
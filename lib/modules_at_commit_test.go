@@ -0,0 +1,85 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModulesAtCommit(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+	first := repo.LastCommit.String()
+
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.Commit("second"))
+
+	w := NewWorld(t, ".tmp/repo")
+
+	mods, err := w.System.ModulesAtCommit(first)
+	check(t, err)
+	assert.Equal(t, []string{"app-a"}, mods.names())
+
+	mods, err = w.System.ModulesAtCommit(first[:7])
+	check(t, err)
+	assert.Equal(t, []string{"app-a"}, mods.names())
+
+	mods, err = w.System.ModulesAtCommit(repo.LastCommit.String()[:7])
+	check(t, err)
+	assert.Equal(t, []string{"app-a", "app-b"}, mods.names())
+}
+
+func TestModulesAtCommitWithInvalidSha(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+
+	w := NewWorld(t, ".tmp/repo")
+
+	for _, sha := range []string{"abc", "xyz123", repo.LastCommit.String() + "0"} {
+		_, err := w.System.ModulesAtCommit(sha)
+
+		assert.EqualError(t, err, fmt.Sprintf(msgInvalidSha, sha))
+		assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+	}
+}
+
+func TestModulesAtMissingCommit(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+
+	sha := "0000000"
+	if repo.LastCommit.String()[:7] == sha {
+		sha = "1111111"
+	}
+
+	_, err := NewWorld(t, ".tmp/repo").System.ModulesAtCommit(sha)
+
+	assert.EqualError(t, err, fmt.Sprintf(msgCommitShaNotFound, sha))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
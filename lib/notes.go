@@ -0,0 +1,107 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// DefaultNotesNamespace is the namespace used for build notes when
+// a namespace is not specified.
+const DefaultNotesNamespace = "mbt"
+
+// BuildNote is the build information stored in a git note.
+type BuildNote struct {
+	TypeMeta
+	// Fingerprint of the modules built
+	Fingerprint string `json:"fingerprint"`
+	// Versions of the modules built keyed by the module name
+	Versions map[string]string `json:"versions"`
+}
+
+// NewBuildNote creates a BuildNote for the specified modules.
+func NewBuildNote(mods Modules) *BuildNote {
+	versions := make(map[string]string)
+	for _, m := range mods {
+		versions[m.Name()] = m.Version()
+	}
+
+	return &BuildNote{
+		TypeMeta:    TypeMeta{APIVersion: APIVersion, Kind: KindBuildNote},
+		Fingerprint: mods.Fingerprint(),
+		Versions:    versions,
+	}
+}
+
+// Matches returns true if the note was recorded for the same
+// set of modules.
+func (n *BuildNote) Matches(mods Modules) bool {
+	return n.Fingerprint == mods.Fingerprint()
+}
+
+func (s *stdSystem) ReadBuildNote(namespace, commit string) (*BuildNote, error) {
+	c, err := s.Repo.GetCommit(commit)
+	if err != nil {
+		return nil, err
+	}
+
+	ref := notesRef(namespace)
+	content, err := s.Repo.Note(ref, c)
+	if err != nil {
+		return nil, err
+	}
+
+	if content == nil {
+		return nil, nil
+	}
+
+	note := &BuildNote{}
+	err = json.Unmarshal(content, note)
+	if err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgMalformedBuildNote, ref, commit)
+	}
+
+	return note, nil
+}
+
+func (s *stdSystem) WriteBuildNote(namespace string, manifest *Manifest) error {
+	c, err := s.Repo.GetCommit(manifest.Sha)
+	if err != nil {
+		return err
+	}
+
+	content, err := json.Marshal(NewBuildNote(manifest.Modules))
+	if err != nil {
+		return e.Wrap(ErrClassInternal, err)
+	}
+
+	return s.Repo.WriteNote(notesRef(namespace), c, content)
+}
+
+func notesRef(namespace string) string {
+	if namespace == "" {
+		namespace = DefaultNotesNamespace
+	}
+
+	if strings.HasPrefix(namespace, "refs/") {
+		return namespace
+	}
+
+	return "refs/notes/" + namespace
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadBuildNoteOfCommitWithoutNote(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+
+	note, err := NewWorld(t, ".tmp/repo").System.ReadBuildNote("", repo.LastCommit.String())
+	check(t, err)
+
+	assert.Nil(t, note)
+}
+
+func TestWriteAndReadBuildNote(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.Commit("first"))
+
+	w := NewWorld(t, ".tmp/repo")
+	m, err := w.System.ManifestByCommit(repo.LastCommit.String())
+	check(t, err)
+
+	check(t, w.System.WriteBuildNote("builds", m))
+
+	note, err := w.System.ReadBuildNote("builds", repo.LastCommit.String())
+	check(t, err)
+
+	assert.True(t, note.Matches(m.Modules))
+	assert.Equal(t, m.Modules[0].Version(), note.Versions["app-a"])
+	assert.Equal(t, m.Modules[1].Version(), note.Versions["app-b"])
+
+	other, err := w.System.ReadBuildNote("", repo.LastCommit.String())
+	check(t, err)
+	assert.Nil(t, other)
+}
+
+func TestBuildNoteDoesNotMatchChangedModules(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+
+	w := NewWorld(t, ".tmp/repo")
+	m, err := w.System.ManifestByCommit(repo.LastCommit.String())
+	check(t, err)
+	note := NewBuildNote(m.Modules)
+
+	check(t, repo.WriteContent("app-a/foo", "bar"))
+	check(t, repo.Commit("second"))
+
+	m, err = w.System.ManifestByCommit(repo.LastCommit.String())
+	check(t, err)
+
+	assert.False(t, note.Matches(m.Modules))
+}
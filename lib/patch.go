@@ -0,0 +1,234 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// ModulesFromPatch returns the modules impacted by the changes in a
+// unified diff, including the modules in their requiredBy
+// dependency chain.
+// Paths in the patch are expected to have a leading directory to
+// strip (e.g. a/ and b/ prefixes produced by git diff).
+func ModulesFromPatch(all Modules, patch io.Reader) (Modules, error) {
+	return ModulesFromPatchWithOptions(all, patch, &ReducerOptions{})
+}
+
+// ModulesFromPatchWithOptions is same as ModulesFromPatch except that
+// the changes are reduced with the specified options.
+func ModulesFromPatchWithOptions(all Modules, patch io.Reader, options *ReducerOptions) (Modules, error) {
+	deltas, err := parsePatch(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	reduced, err := NewReducerWithOptions(NewStdLog(LogLevelNormal), options).Reduce(all, deltas)
+	if err != nil {
+		return nil, err
+	}
+
+	return reduced.expandRequiredByDependencies()
+}
+
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -\d+(?:,(\d+))? \+\d+(?:,(\d+))? @@`)
+
+// patchFile holds the paths found in the headers of a file in a patch.
+type patchFile struct {
+	old, new string
+	hasOld   bool
+}
+
+// parsePatch reads the paths of the files changed in a unified diff.
+// Renamed and copied files produce a delta for each path so that
+// the changes are attributed to both locations.
+func parsePatch(patch io.Reader) ([]*DiffDelta, error) {
+	deltas := make([]*DiffDelta, 0)
+	var current *patchFile
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+
+		old, new := current.old, current.new
+		if old == "" {
+			old = new
+		}
+		if new == "" {
+			new = old
+		}
+
+		if old != new {
+			deltas = append(deltas, &DiffDelta{OldFile: old, NewFile: old})
+		}
+		if new != "" {
+			deltas = append(deltas, &DiffDelta{OldFile: new, NewFile: new})
+		}
+		current = nil
+	}
+
+	r := bufio.NewReader(patch)
+	oldLeft, newLeft := 0, 0
+	for n := 1; ; n++ {
+		line, err := r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, e.Wrap(ErrClassUser, err)
+		}
+		if line == "" && err == io.EOF {
+			break
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if oldLeft > 0 || newLeft > 0 {
+			// Inside a hunk, lines are content rather than headers.
+			switch {
+			case strings.HasPrefix(line, "-"):
+				oldLeft--
+			case strings.HasPrefix(line, "+"):
+				newLeft--
+			case strings.HasPrefix(line, "\\"):
+			default:
+				oldLeft--
+				newLeft--
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			flush()
+			old, new := parseGitDiffHeader(strings.TrimPrefix(line, "diff --git "))
+			current = &patchFile{old: old, new: new}
+		case strings.HasPrefix(line, "--- "):
+			if current == nil || current.hasOld {
+				flush()
+				current = &patchFile{}
+			}
+			current.old = parsePatchPath(strings.TrimPrefix(line, "--- "))
+			current.hasOld = true
+		case strings.HasPrefix(line, "+++ "):
+			if current == nil {
+				current = &patchFile{}
+			}
+			current.new = parsePatchPath(strings.TrimPrefix(line, "+++ "))
+		case strings.HasPrefix(line, "rename from "), strings.HasPrefix(line, "copy from "):
+			if current != nil {
+				current.old = unquotePatchPath(line[strings.Index(line, " from ")+6:])
+			}
+		case strings.HasPrefix(line, "rename to "), strings.HasPrefix(line, "copy to "):
+			if current != nil {
+				current.new = unquotePatchPath(line[strings.Index(line, " to ")+4:])
+			}
+		case strings.HasPrefix(line, "@@ "):
+			m := hunkHeaderPattern.FindStringSubmatch(line)
+			if m == nil || current == nil {
+				return nil, e.NewErrorf(ErrClassUser, msgMalformedPatch, n)
+			}
+			oldLeft, newLeft = hunkLength(m[1]), hunkLength(m[2])
+		}
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	flush()
+	return deltas, nil
+}
+
+// parseGitDiffHeader extracts the paths from the "a/<old> b/<new>"
+// section of a diff --git line.
+func parseGitDiffHeader(s string) (string, string) {
+	if strings.HasPrefix(s, "\"") {
+		if i := strings.Index(s[1:], "\" "); i >= 0 {
+			return parsePatchPath(s[:i+2]), parsePatchPath(s[i+3:])
+		}
+	}
+
+	// Paths are same unless the file is renamed. Look for the
+	// split point producing the same path on both sides first,
+	// because paths could contain spaces.
+	for i := 0; i < len(s); i++ {
+		if s[i] == ' ' {
+			old, new := stripPatchPrefix(s[:i]), stripPatchPrefix(s[i+1:])
+			if old == new {
+				return old, new
+			}
+		}
+	}
+
+	// Actual paths of the renamed files are read from rename headers.
+	if i := strings.Index(s, " b/"); i >= 0 {
+		return stripPatchPrefix(s[:i]), stripPatchPrefix(s[i+1:])
+	}
+
+	return "", ""
+}
+
+// parsePatchPath returns the path in a ---/+++ line of a patch.
+// Returns an empty string for /dev/null.
+func parsePatchPath(s string) string {
+	if !strings.HasPrefix(s, "\"") {
+		// Drop the timestamp added by diff utilities other than git.
+		if i := strings.Index(s, "\t"); i >= 0 {
+			s = s[:i]
+		}
+	}
+
+	s = unquotePatchPath(s)
+	if s == "/dev/null" {
+		return ""
+	}
+
+	return stripPatchPrefix(s)
+}
+
+func unquotePatchPath(s string) string {
+	if strings.HasPrefix(s, "\"") {
+		if u, err := strconv.Unquote(s); err == nil {
+			return u
+		}
+	}
+
+	return s
+}
+
+// stripPatchPrefix removes the leading directory from a path
+// similar to patch -p1.
+func stripPatchPrefix(s string) string {
+	s = unquotePatchPath(s)
+	if i := strings.Index(s, "/"); i >= 0 {
+		return s[i+1:]
+	}
+
+	return s
+}
+
+func hunkLength(s string) int {
+	if s == "" {
+		return 1
+	}
+
+	n, _ := strconv.Atoi(s)
+	return n
+}
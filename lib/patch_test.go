@@ -0,0 +1,109 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPatch = "diff --git a/app c/file name b/app c/file name\n" +
+	"index f2ad6c7..b680253 100644\n" +
+	"--- a/app c/file name\t\n" +
+	"+++ b/app c/file name\t\n" +
+	"@@ -1 +1 @@\n" +
+	"-c\n" +
+	"+z\n" +
+	"diff --git a/app-a/f b/app-a/f\n" +
+	"index adaaafc..587be6b 100644\n" +
+	"--- a/app-a/f\n" +
+	"+++ b/app-a/f\n" +
+	"@@ -1,2 +1 @@\n" +
+	" x\n" +
+	"--- y\n" +
+	"diff --git a/app-b/del b/app-b/del\n" +
+	"deleted file mode 100644\n" +
+	"index 4bcfe98..0000000\n" +
+	"--- a/app-b/del\n" +
+	"+++ /dev/null\n" +
+	"@@ -1 +0,0 @@\n" +
+	"-d\n" +
+	"diff --git a/app-b/old b/lib-a/new\n" +
+	"similarity index 100%\n" +
+	"rename from app-b/old\n" +
+	"rename to lib-a/new\n"
+
+func TestParsePatch(t *testing.T) {
+	deltas, err := parsePatch(strings.NewReader(testPatch))
+	check(t, err)
+
+	files := make([]string, 0, len(deltas))
+	for _, d := range deltas {
+		files = append(files, d.NewFile)
+	}
+
+	assert.Equal(t, []string{"app c/file name", "app-a/f", "app-b/del", "app-b/old", "lib-a/new"}, files)
+}
+
+func TestParsePlainUnifiedDiff(t *testing.T) {
+	deltas, err := parsePatch(strings.NewReader("--- a/app-a/f\t2018-01-01 00:00:00\n" +
+		"+++ b/app-a/f\t2018-01-02 00:00:00\n" +
+		"@@ -1 +1 @@\n" +
+		"-a\n" +
+		"+b\n" +
+		"--- /dev/null\n" +
+		"+++ b/app-b/f\n" +
+		"@@ -0,0 +1 @@\n" +
+		"+b\n"))
+	check(t, err)
+
+	assert.Len(t, deltas, 2)
+	assert.Equal(t, "app-a/f", deltas[0].NewFile)
+	assert.Equal(t, "app-b/f", deltas[1].NewFile)
+}
+
+func TestParseMalformedPatch(t *testing.T) {
+	_, err := parsePatch(strings.NewReader("--- a/app-a/f\n+++ b/app-a/f\n@@ foo @@\n"))
+
+	assert.EqualError(t, err, fmt.Sprintf(msgMalformedPatch, 3))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestModulesFromPatch(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a"}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil),
+		newModuleMetadata("app-d", "d", &Spec{Name: "app-d", Dependencies: []string{"lib-a"}}, nil),
+		newModuleMetadata("lib-a", "l", &Spec{Name: "lib-a"}, nil),
+	})
+	check(t, err)
+
+	impacted, err := ModulesFromPatch(mods, strings.NewReader("diff --git a/lib-a/foo b/lib-a/foo\n"+
+		"--- a/lib-a/foo\n"+
+		"+++ b/lib-a/foo\n"+
+		"@@ -1 +1 @@\n"+
+		"-a\n"+
+		"+b\n"))
+	check(t, err)
+
+	assert.Len(t, impacted, 2)
+	assert.Equal(t, "lib-a", impacted[0].Name())
+	assert.Equal(t, "app-d", impacted[1].Name())
+}
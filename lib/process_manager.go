@@ -17,10 +17,13 @@ package lib
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path"
 	"strings"
+
+	"github.com/mbtproject/mbt/e"
 )
 
 type stdProcessManager struct {
@@ -28,8 +31,15 @@ type stdProcessManager struct {
 }
 
 func (p *stdProcessManager) Exec(manifest *Manifest, module *Module, options *CmdOptions, command string, args ...string) error {
+	env, err := p.readEnvFile(manifest, module)
+	if err != nil {
+		return err
+	}
+
 	cmd := exec.Command(command)
-	cmd.Env = append(os.Environ(), p.setupModBuildEnvironment(manifest, module)...)
+	// Variables in env file are layered under the process environment.
+	cmd.Env = append(env, os.Environ()...)
+	cmd.Env = append(cmd.Env, p.setupModBuildEnvironment(manifest, module)...)
 	cmd.Dir = path.Join(manifest.Dir, module.Path())
 	cmd.Stdin = options.Stdin
 	cmd.Stdout = options.Stdout
@@ -43,10 +53,23 @@ func (p *stdProcessManager) setupModBuildEnvironment(manifest *Manifest, mod *Mo
 		fmt.Sprintf("MBT_BUILD_COMMIT=%s", manifest.Sha),
 		fmt.Sprintf("MBT_MODULE_VERSION=%s", mod.Version()),
 		fmt.Sprintf("MBT_MODULE_NAME=%s", mod.Name()),
+		fmt.Sprintf("MBT_MODULE_ID=%s", mod.ID()),
 		fmt.Sprintf("MBT_MODULE_PATH=%s", mod.Path()),
 		fmt.Sprintf("MBT_REPO_PATH=%s", manifest.Dir),
 	}
 
+	if manifest.State != nil {
+		if dirty, err := manifest.State.IsDirty(); err == nil {
+			r = append(r, fmt.Sprintf("MBT_REPO_DIRTY=%t", dirty))
+		} else {
+			p.Log.Warnf(msgFailedWorkspaceStatus, err)
+		}
+	}
+
+	if kind, ok := manifest.Changes[mod.Name()]; ok {
+		r = append(r, fmt.Sprintf("MBT_MODULE_CHANGE=%s", changeKindNames[kind]))
+	}
+
 	for k, v := range mod.Properties() {
 		if value, ok := v.(string); ok {
 			r = append(r, fmt.Sprintf("MBT_MODULE_PROPERTY_%s=%s", strings.ToUpper(k), value))
@@ -56,6 +79,58 @@ func (p *stdProcessManager) setupModBuildEnvironment(manifest *Manifest, mod *Mo
 	return r
 }
 
+func (p *stdProcessManager) readEnvFile(manifest *Manifest, mod *Module) ([]string, error) {
+	envFile := mod.EnvFile()
+	if envFile == nil || envFile.Path == "" {
+		return nil, nil
+	}
+
+	rel, ok := cleanModulePath(envFile.Path)
+	if !ok {
+		return nil, e.NewErrorf(ErrClassUser, msgEnvFileOutsideModule, envFile.Path, mod.Name())
+	}
+
+	file := path.Join(manifest.Dir, mod.Path(), rel)
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			if envFile.Required {
+				return nil, e.NewErrorf(ErrClassUser, msgEnvFileNotFound, envFile.Path, mod.Name())
+			}
+			p.Log.Debug("Skip missing env file %s", file)
+			return nil, nil
+		}
+		return nil, e.Wrapf(ErrClassUser, err, msgFailedReadFile, file)
+	}
+
+	return parseEnvFile(envFile.Path, content)
+}
+
+// classifyExec classifies the outcome of executing the command using
+// the error returned by ProcessManager.Exec.
+// Returned error is nil unless the result is CmdResultFailed.
+func classifyExec(cmd *Cmd, err error) (CmdResult, error) {
+	code := 0
+	if err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			return CmdResultFailed, err
+		}
+		code = exitErr.ExitCode()
+	}
+
+	result := cmd.Classify(code)
+	if result != CmdResultFailed {
+		return result, nil
+	}
+
+	if err == nil {
+		err = e.NewErrorf(ErrClassUser, msgUnexpectedExitCode, code)
+	}
+
+	return result, err
+}
+
 // NewProcessManager creates an instance of ProcessManager.
 func NewProcessManager(log Log) ProcessManager {
 	return &stdProcessManager{Log: log}
@@ -17,63 +17,231 @@ package lib
 
 import (
 	"fmt"
+	"path"
 	"strings"
 
 	"github.com/mbtproject/mbt/trie"
 )
 
 type stdReducer struct {
-	Log Log
+	Log       Log
+	Roots     []string
+	Scope     []string
+	Excludes  []string
+	Attribute AttributionFunc
+}
+
+// AttributionFunc returns the module a changed file (repository
+// relative path) belongs to or nil if the change does not belong to
+// any of the modules.
+type AttributionFunc func(path string, mods Modules) *Module
+
+// CommonExcludes is a list of commonly generated directories that can
+// be used as ReducerOptions.Excludes. Changes are not excluded by default.
+var CommonExcludes = []string{"node_modules", "vendor", "dist"}
+
+// ReducerOptions specifies the options used to create a Reducer.
+type ReducerOptions struct {
+	// Roots restricts the changes considered to these repository
+	// relative paths. All changes are considered when Roots is empty.
+	Roots []string
+	// Scope further restricts the changes considered to these
+	// repository relative paths. Unlike Roots, it is not used for
+	// discovery. Therefore, modules outside the scope are still
+	// impacted by the changes in their dependencies.
+	Scope []string
+	// Excludes is a list of glob patterns for the changes to be ignored.
+	// A pattern without a slash is matched against each directory name in
+	// the path of a change (e.g. node_modules). Other patterns are matched
+	// against the leading directories of the path (e.g. app-a/build).
+	// Changes to the file dependencies of a module are never ignored.
+	Excludes []string
+	// Attribute replaces the default matching of changes to the modules
+	// by their directory. It is invoked for each change that is not
+	// excluded. Changes to the file dependencies of a module are
+	// still matched by their path.
+	Attribute AttributionFunc
 }
 
 // NewReducer creates a new reducer
 func NewReducer(log Log) Reducer {
-	return &stdReducer{Log: log}
+	return NewReducerWithOptions(log, &ReducerOptions{})
+}
+
+// NewReducerWithOptions creates a new reducer with the specified options.
+func NewReducerWithOptions(log Log, options *ReducerOptions) Reducer {
+	return &stdReducer{
+		Log:       log,
+		Roots:     normalizeRoots(options.Roots),
+		Scope:     normalizeRoots(options.Scope),
+		Excludes:  options.Excludes,
+		Attribute: options.Attribute,
+	}
 }
 
 func (r *stdReducer) Reduce(modules Modules, deltas []*DiffDelta) (Modules, error) {
+	// t indexes changes considered for module content and
+	// ft indexes all changes which are considered for file dependencies.
 	t := trie.NewTrie()
+	ft := trie.NewTrie()
 	filtered := make(Modules, 0)
+	deltas = splitRenames(deltas)
+	inRoots := make([]*DiffDelta, 0, len(deltas))
+	for _, d := range deltas {
+		if !isInRoots(d.NewFile, r.Roots) {
+			r.Log.Debug("Ignore change %s outside roots", d.NewFile)
+			continue
+		}
+		if !isInRoots(d.NewFile, r.Scope) {
+			r.Log.Debug("Ignore change %s outside scope", d.NewFile)
+			continue
+		}
+		inRoots = append(inRoots, d)
+	}
+	deltas = inRoots
+	contentChanges := 0
+
 	for _, d := range deltas {
 		// Current comparison is case insensitive. This is problematic
 		// for case sensitive file systems.
 		// Perhaps we can read core.ignorecase configuration value
 		// in git and adjust accordingly.
 		nfp := strings.ToLower(d.NewFile)
+		ft.Add(nfp, nfp)
+		if isExcluded(d.NewFile, r.Excludes) {
+			r.Log.Debug("Exclude change %s", nfp)
+			continue
+		}
 		r.Log.Debug("Index change %s", nfp)
 		t.Add(nfp, nfp)
+		contentChanges++
+	}
+
+	var attributed map[*Module]bool
+	if r.Attribute != nil {
+		attributed = r.attribute(modules, deltas)
 	}
 
 	for _, m := range modules {
 		mp := m.Path()
 
-		if mp == "" {
+		if r.Attribute != nil {
+			if attributed[m] {
+				filtered = append(filtered, m)
+				continue
+			}
+		} else if mp == "" {
 			// Fast path for the root module if there's one.
 			// Root module should match any change.
-			if len(deltas) > 0 {
+			if len(m.VersionExtensions()) > 0 {
+				if containsChangeWithExtension(deltas, "", m.VersionExtensions(), r.Excludes) {
+					filtered = append(filtered, m)
+				}
+			} else if contentChanges > 0 {
 				filtered = append(filtered, m)
 			}
 			continue
-		}
-
-		// Append / to the end of module path to make sure
-		// we restrict the search exactly for that path.
-		// for example, change in path a/bb should not
-		// match a module in a/b
-		mp = strings.ToLower(fmt.Sprintf("%s/", m.Path()))
-		r.Log.Debug("Filter by module path %s", mp)
-		if t.ContainsPrefix(mp) {
-			filtered = append(filtered, m)
 		} else {
-			for _, p := range m.FileDependencies() {
-				fdp := strings.ToLower(p)
-				r.Log.Debug("Filter by file dependency path %s", fdp)
-				if t.ContainsPrefix(fdp) {
+			// Append / to the end of module path to make sure
+			// we restrict the search exactly for that path.
+			// for example, change in path a/bb should not
+			// match a module in a/b
+			mp = strings.ToLower(fmt.Sprintf("%s/", m.Path()))
+			r.Log.Debug("Filter by module path %s", mp)
+			if len(m.VersionExtensions()) > 0 {
+				if containsChangeWithExtension(deltas, mp, m.VersionExtensions(), r.Excludes) {
 					filtered = append(filtered, m)
+					continue
 				}
+			} else if t.ContainsPrefix(mp) {
+				filtered = append(filtered, m)
+				continue
+			}
+		}
+
+		for _, p := range m.FileDependencies() {
+			fdp := strings.ToLower(p)
+			r.Log.Debug("Filter by file dependency path %s", fdp)
+			if ft.ContainsPrefix(fdp) {
+				filtered = append(filtered, m)
 			}
 		}
 	}
 
 	return filtered, nil
 }
+
+// attribute returns the set of modules the non-excluded deltas
+// are attributed to by the attribution function.
+func (r *stdReducer) attribute(modules Modules, deltas []*DiffDelta) map[*Module]bool {
+	attributed := make(map[*Module]bool)
+	for _, d := range deltas {
+		if isExcluded(d.NewFile, r.Excludes) {
+			continue
+		}
+		if m := r.Attribute(d.NewFile, modules); m != nil {
+			r.Log.Debug("Attribute change %s to %s", d.NewFile, m.Name())
+			attributed[m] = true
+		}
+	}
+	return attributed
+}
+
+// containsChangeWithExtension returns true if any of the non-excluded
+// deltas is under the specified directory and has one of the extensions.
+func containsChangeWithExtension(deltas []*DiffDelta, dir string, extensions, excludes []string) bool {
+	for _, d := range deltas {
+		if strings.HasPrefix(strings.ToLower(d.NewFile), dir) &&
+			isVersionedFile(path.Base(d.NewFile), extensions) &&
+			!isExcluded(d.NewFile, excludes) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isExcluded returns true if the path matches any of the exclude patterns.
+func isExcluded(p string, excludes []string) bool {
+	if len(excludes) == 0 {
+		return false
+	}
+
+	segments := strings.Split(p, "/")
+	// Last segment is the file name, only the directories are considered.
+	dirs := segments[:len(segments)-1]
+
+	for _, pattern := range excludes {
+		pattern = strings.Trim(pattern, "/")
+		if !strings.Contains(pattern, "/") {
+			for _, dir := range dirs {
+				if ok, _ := path.Match(pattern, dir); ok {
+					return true
+				}
+			}
+			continue
+		}
+
+		for i := range dirs {
+			if ok, _ := path.Match(pattern, strings.Join(dirs[:i+1], "/")); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// splitRenames adds a delta for the old path of each rename (or copy)
+// so that the changes are attributed to both source and destination.
+func splitRenames(deltas []*DiffDelta) []*DiffDelta {
+	r := make([]*DiffDelta, 0, len(deltas))
+	for _, d := range deltas {
+		r = append(r, d)
+		if d.OldFile != "" && d.OldFile != d.NewFile {
+			r = append(r, &DiffDelta{NewFile: d.OldFile, OldFile: d.OldFile})
+		}
+	}
+
+	return r
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReduceWithRoots(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("services/app-a", "a", &Spec{Name: "app-a", FileDependencies: []string{"tools/build.sh"}}, nil),
+	})
+	check(t, err)
+
+	reducer := NewReducerWithOptions(NewStdLog(LogLevelNormal), &ReducerOptions{Roots: []string{"services"}})
+
+	reduced, err := reducer.Reduce(mods, []*DiffDelta{{NewFile: "tools/build.sh", OldFile: "tools/build.sh"}})
+	check(t, err)
+	assert.Len(t, reduced, 0)
+
+	reduced, err = reducer.Reduce(mods, []*DiffDelta{{NewFile: "services/app-a/main.go", OldFile: "services/app-a/main.go"}})
+	check(t, err)
+	assert.Len(t, reduced, 1)
+	assert.Equal(t, "app-a", reduced[0].Name())
+}
+
+func TestReduceRename(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a"}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c"}, nil),
+	})
+	check(t, err)
+
+	reducer := NewReducer(NewStdLog(LogLevelNormal))
+
+	reduced, err := reducer.Reduce(mods, []*DiffDelta{{NewFile: "app-b/foo", OldFile: "app-a/foo"}})
+	check(t, err)
+	assert.Equal(t, []string{"app-a", "app-b"}, reduced.names())
+}
+
+func TestReduceWithScope(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("apps/app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"lib-a"}}, nil),
+		newModuleMetadata("services/lib-a", "l", &Spec{Name: "lib-a"}, nil),
+	})
+	check(t, err)
+
+	reducer := NewReducerWithOptions(NewStdLog(LogLevelNormal), &ReducerOptions{Scope: []string{"services"}})
+
+	reduced, err := reducer.Reduce(mods, []*DiffDelta{
+		{NewFile: "apps/app-a/main.go", OldFile: "apps/app-a/main.go"},
+		{NewFile: "services/lib-a/lib.go", OldFile: "services/lib-a/lib.go"},
+	})
+	check(t, err)
+	assert.Len(t, reduced, 1)
+	assert.Equal(t, "lib-a", reduced[0].Name())
+
+	// Modules outside the scope are impacted via their dependencies
+	impacted, err := reduced.expandRequiredByDependencies()
+	check(t, err)
+	assert.Len(t, impacted, 2)
+	assert.Equal(t, "app-a", impacted[1].Name())
+}
+
+func TestReduceChangeInExcludedDirectory(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a"}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b", FileDependencies: []string{"app-a/dist/schema.json"}}, nil),
+	})
+	check(t, err)
+
+	reducer := NewReducerWithOptions(NewStdLog(LogLevelNormal), &ReducerOptions{Excludes: CommonExcludes})
+
+	reduced, err := reducer.Reduce(mods, []*DiffDelta{
+		{NewFile: "app-a/node_modules/lib/index.js", OldFile: "app-a/node_modules/lib/index.js"},
+		{NewFile: "app-a/dist/schema.json", OldFile: "app-a/dist/schema.json"},
+	})
+	check(t, err)
+
+	assert.Len(t, reduced, 1)
+	assert.Equal(t, "app-b", reduced[0].Name())
+}
+
+func TestReduceChangeInExcludedRootModuleDirectory(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("", "a", &Spec{Name: "root"}, nil),
+	})
+	check(t, err)
+
+	reducer := NewReducerWithOptions(NewStdLog(LogLevelNormal), &ReducerOptions{Excludes: []string{"build/out"}})
+
+	reduced, err := reducer.Reduce(mods, []*DiffDelta{{NewFile: "build/out/a.txt", OldFile: "build/out/a.txt"}})
+	check(t, err)
+	assert.Len(t, reduced, 0)
+
+	reduced, err = reducer.Reduce(mods, []*DiffDelta{{NewFile: "build/a.txt", OldFile: "build/a.txt"}})
+	check(t, err)
+	assert.Len(t, reduced, 1)
+}
+
+func TestReduceModuleWithVersionExtensions(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", VersionExtensions: []string{".go", "proto"}}, nil),
+	})
+	check(t, err)
+
+	reducer := NewReducer(NewStdLog(LogLevelNormal))
+
+	reduced, err := reducer.Reduce(mods, []*DiffDelta{{NewFile: "app-a/docs/README.md", OldFile: "app-a/docs/README.md"}})
+	check(t, err)
+	assert.Len(t, reduced, 0)
+
+	reduced, err = reducer.Reduce(mods, []*DiffDelta{{NewFile: "app-a/api/service.PROTO", OldFile: "app-a/api/service.PROTO"}})
+	check(t, err)
+	assert.Len(t, reduced, 1)
+
+	reduced, err = reducer.Reduce(mods, []*DiffDelta{{NewFile: "app-a/.mbt.yml", OldFile: "app-a/.mbt.yml"}})
+	check(t, err)
+	assert.Len(t, reduced, 1)
+}
+
+func TestReduceWithAttribution(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a"}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b", FileDependencies: []string{"tools/build.sh"}}, nil),
+		newModuleMetadata("app-c", "c", &Spec{Name: "app-c"}, nil),
+	})
+	check(t, err)
+
+	// Sources of app-a are split across app-a and assets/app-a directories
+	// and changes in app-c are never attributed to any module.
+	attribute := func(p string, mods Modules) *Module {
+		m := mods.indexByName()
+		switch {
+		case strings.HasPrefix(p, "assets/app-a/"), strings.HasPrefix(p, "app-a/"):
+			return m["app-a"]
+		case strings.HasPrefix(p, "app-b/"):
+			return m["app-b"]
+		}
+		return nil
+	}
+
+	reducer := NewReducerWithOptions(NewStdLog(LogLevelNormal), &ReducerOptions{
+		Excludes:  []string{"dist"},
+		Attribute: attribute,
+	})
+
+	reduced, err := reducer.Reduce(mods, []*DiffDelta{
+		{NewFile: "assets/app-a/logo.png", OldFile: "assets/app-a/logo.png"},
+		{NewFile: "app-c/main.go", OldFile: "app-c/main.go"},
+	})
+	check(t, err)
+	assert.Equal(t, []string{"app-a"}, reduced.names())
+
+	reduced, err = reducer.Reduce(mods, []*DiffDelta{{NewFile: "app-b/dist/main", OldFile: "app-b/dist/main"}})
+	check(t, err)
+	assert.Len(t, reduced, 0)
+
+	reduced, err = reducer.Reduce(mods, []*DiffDelta{{NewFile: "tools/build.sh", OldFile: "tools/build.sh"}})
+	check(t, err)
+	assert.Equal(t, []string{"app-b"}, reduced.names())
+}
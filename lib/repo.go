@@ -17,11 +17,22 @@ package lib
 
 import (
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	git "github.com/libgit2/git2go/v28"
 	"github.com/mbtproject/mbt/e"
 )
 
+const (
+	// shaLength is the number of hex characters in a full commit sha.
+	shaLength = 40
+	// minAbbreviatedShaLength is the minimum number of hex characters
+	// accepted in an abbreviated commit sha.
+	minAbbreviatedShaLength = 4
+)
+
 type libgitBlob struct {
 	path   string
 	commit *libgitCommit
@@ -57,6 +68,30 @@ func (c *libgitCommit) String() string {
 	return c.ID()
 }
 
+func (c *libgitCommit) Author() string {
+	sig := c.commit.Author()
+	if sig == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s <%s>", sig.Name, sig.Email)
+}
+
+func (c *libgitCommit) AuthorEmail() string {
+	sig := c.commit.Author()
+	if sig == nil {
+		return ""
+	}
+	return sig.Email
+}
+
+func (c *libgitCommit) Message() string {
+	return c.commit.Message()
+}
+
+func (c *libgitCommit) ParentCount() int {
+	return int(c.commit.ParentCount())
+}
+
 type libgitReference struct {
 	reference    *git.Reference
 	symbolicName string
@@ -71,9 +106,48 @@ func (r *libgitReference) SymbolicName() string {
 }
 
 type libgitRepo struct {
-	path string
-	Repo *git.Repository
-	Log  Log
+	path                 string
+	Repo                 *git.Repository
+	Log                  Log
+	FirstParentMergeBase bool
+	RenameThreshold      int
+	CopyThreshold        int
+	Fetch                FetchFunc
+	TrackedOnly          bool
+	// odb is opened once by objectDatabase and shared by the
+	// subsequent reads (e.g. the sizes of all blobs in a module).
+	odb     *git.Odb
+	odbErr  error
+	odbOnce sync.Once
+}
+
+// FetchFunc deepens the history of the shallow clone in the
+// specified path (e.g. git fetch --unshallow).
+type FetchFunc func(path string) error
+
+// RepoOptions is used to customise the behaviour of Repo.
+type RepoOptions struct {
+	// FirstParentMergeBase finds the merge base of two commits along the
+	// first parent history of the first commit. That is the most recent
+	// commit in the first parent history of the first commit, which is
+	// also reachable from the second commit.
+	// Otherwise, the best common ancestor is used (as in git merge-base).
+	FirstParentMergeBase bool
+	// RenameThreshold is the similarity (as a percentage) required
+	// to report a deleted file and an added file as a rename in the
+	// diffs between commits. Renames are not detected when 0.
+	RenameThreshold int
+	// CopyThreshold is the similarity (as a percentage) required
+	// to report an added file as a copy of a modified file in the
+	// diffs between commits. Copies are not detected when 0.
+	CopyThreshold int
+	// Fetch is invoked when a merge base cannot be found because the
+	// repository is a shallow clone. Merge base is computed again once
+	// it returns. Otherwise, a user error wrapping ErrShallowClone is returned.
+	Fetch FetchFunc
+	// TrackedOnly ignores the untracked files in the workspace when
+	// finding files and changes in it.
+	TrackedOnly bool
 }
 
 func (c *libgitCommit) Tree() (*git.Tree, error) {
@@ -90,15 +164,32 @@ func (c *libgitCommit) Tree() (*git.Tree, error) {
 
 // NewLibgitRepo creates a libgit2 repo instance
 func NewLibgitRepo(path string, log Log) (Repo, error) {
+	return NewLibgitRepoWithOptions(path, log, &RepoOptions{})
+}
+
+// NewLibgitRepoWithOptions creates a libgit2 repo instance
+// with the specified options.
+func NewLibgitRepoWithOptions(path string, log Log, options *RepoOptions) (Repo, error) {
+	for _, t := range []int{options.RenameThreshold, options.CopyThreshold} {
+		if t < 0 || t > 100 {
+			return nil, e.NewErrorf(ErrClassUser, msgInvalidSimilarityThreshold, t)
+		}
+	}
+
 	repo, err := git.OpenRepository(path)
 	if err != nil {
 		return nil, e.Wrapf(ErrClassUser, err, msgFailedOpenRepo, path)
 	}
 
 	return &libgitRepo{
-		path: path,
-		Repo: repo,
-		Log:  log,
+		path:                 path,
+		Repo:                 repo,
+		Log:                  log,
+		FirstParentMergeBase: options.FirstParentMergeBase,
+		RenameThreshold:      options.RenameThreshold,
+		CopyThreshold:        options.CopyThreshold,
+		Fetch:                options.Fetch,
+		TrackedOnly:          options.TrackedOnly,
 	}, nil
 }
 
@@ -116,6 +207,111 @@ func (r *libgitRepo) GetCommit(commitSha string) (Commit, error) {
 	return &libgitCommit{commit: commit}, nil
 }
 
+func (r *libgitRepo) ResolveCommit(sha string) (Commit, error) {
+	if len(sha) == shaLength {
+		return r.GetCommit(sha)
+	}
+
+	if len(sha) < minAbbreviatedShaLength || len(sha) > shaLength {
+		return nil, e.NewErrorf(ErrClassUser, msgInvalidSha, sha)
+	}
+
+	// Oid of the prefix is padded to full length. Only the number of
+	// characters specified in the lookup are significant.
+	oid, err := git.NewOid(sha + strings.Repeat("0", shaLength-len(sha)))
+	if err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgInvalidSha, sha)
+	}
+
+	commit, err := r.Repo.LookupPrefixCommit(oid, uint(len(sha)))
+	if err != nil {
+		if git.IsErrorCode(err, git.ErrorCodeAmbiguous) {
+			return nil, e.Wrapf(ErrClassUser, err, msgAmbiguousSha, sha)
+		}
+		return nil, e.Wrapf(ErrClassUser, err, msgCommitShaNotFound, sha)
+	}
+
+	return &libgitCommit{commit: commit}, nil
+}
+
+func (r *libgitRepo) ResolveRevision(rev string) (Commit, error) {
+	// Full shas are looked up directly to report the same errors
+	// as GetCommit.
+	if len(rev) == shaLength {
+		if _, err := git.NewOid(rev); err == nil {
+			return r.GetCommit(rev)
+		}
+	}
+
+	obj, err := r.Repo.RevparseSingle(rev)
+	if err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgFailedResolveRevision, rev)
+	}
+	defer obj.Free()
+
+	peeled, err := obj.Peel(git.ObjectCommit)
+	if err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgFailedResolveRevision, rev)
+	}
+
+	commit, err := peeled.AsCommit()
+	if err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgFailedResolveRevision, rev)
+	}
+
+	return &libgitCommit{commit: commit}, nil
+}
+
+func (r *libgitRepo) LatestTag(commit Commit, constraint string) (string, Commit, error) {
+	c, err := parseSemConstraint(constraint)
+	if err != nil {
+		return "", nil, err
+	}
+
+	names, err := r.Repo.Tags.List()
+	if err != nil {
+		return "", nil, e.Wrap(ErrClassInternal, err)
+	}
+
+	id := commit.(*libgitCommit).commit.Id()
+	var (
+		latestName    string
+		latestVersion *semVersion
+		latest        Commit
+	)
+
+	for _, name := range names {
+		v, err := parseSemVersion(name)
+		if err != nil || !c.check(v) || (latestVersion != nil && v.compare(latestVersion) <= 0) {
+			continue
+		}
+
+		tagged, err := r.ResolveRevision("refs/tags/" + name)
+		if err != nil {
+			return "", nil, err
+		}
+
+		tid := tagged.(*libgitCommit).commit.Id()
+		reachable := tid.Equal(id)
+		if !reachable {
+			reachable, err = r.Repo.DescendantOf(id, tid)
+			if err != nil {
+				return "", nil, e.Wrap(ErrClassInternal, err)
+			}
+		}
+
+		if reachable {
+			latestName, latestVersion, latest = name, v, tagged
+		}
+	}
+
+	if latest == nil {
+		return "", nil, e.NewErrorf(ErrClassUser, msgNoMatchingTag, commit, constraint)
+	}
+
+	return latestName, latest, nil
+}
+
 func (r *libgitRepo) Path() string {
 	return r.path
 }
@@ -126,6 +322,11 @@ func (r *libgitRepo) Diff(a, b Commit) ([]*DiffDelta, error) {
 		return nil, e.Wrap(ErrClassInternal, err)
 	}
 
+	err = r.findSimilar(diff)
+	if err != nil {
+		return nil, err
+	}
+
 	return deltas(diff)
 }
 
@@ -140,9 +341,72 @@ func (r *libgitRepo) DiffMergeBase(from, to Commit) ([]*DiffDelta, error) {
 		return nil, e.Wrap(ErrClassInternal, err)
 	}
 
+	err = r.findSimilar(diff)
+	if err != nil {
+		return nil, err
+	}
+
 	return deltas(diff)
 }
 
+// findSimilar marks the renames and copies in the diff according to
+// the thresholds specified in RepoOptions.
+func (r *libgitRepo) findSimilar(diff *git.Diff) error {
+	if r.RenameThreshold == 0 && r.CopyThreshold == 0 {
+		return nil
+	}
+
+	options, err := git.DefaultDiffFindOptions()
+	if err != nil {
+		return e.Wrap(ErrClassInternal, err)
+	}
+
+	options.Flags = 0
+	if r.RenameThreshold > 0 {
+		options.Flags |= git.DiffFindRenames
+		options.RenameThreshold = uint16(r.RenameThreshold)
+	}
+	if r.CopyThreshold > 0 {
+		options.Flags |= git.DiffFindCopies
+		options.CopyThreshold = uint16(r.CopyThreshold)
+	}
+
+	err = diff.FindSimilar(&options)
+	if err != nil {
+		return e.Wrap(ErrClassInternal, err)
+	}
+
+	return nil
+}
+
+func (r *libgitRepo) PatchMergeBase(from, to Commit, paths []string) (string, error) {
+	bc, err := r.MergeBase(from, to)
+	if err != nil {
+		return "", err
+	}
+
+	options, err := git.DefaultDiffOptions()
+	if err != nil {
+		return "", e.Wrap(ErrClassInternal, err)
+	}
+	// Paths are literal paths to files or directories.
+	options.Pathspec = paths
+	options.Flags |= git.DiffDisablePathspecMatch
+
+	diff, err := diffWithOptions(r.Repo, bc, to, &options)
+	if err != nil {
+		return "", err
+	}
+	defer diff.Free()
+
+	patch, err := diff.ToBuf(git.DiffFormatPatch)
+	if err != nil {
+		return "", e.Wrap(ErrClassInternal, err)
+	}
+
+	return string(patch), nil
+}
+
 func (r *libgitRepo) DiffWorkspace() ([]*DiffDelta, error) {
 	index, err := r.Repo.Index()
 	if err != nil {
@@ -155,8 +419,13 @@ func (r *libgitRepo) DiffWorkspace() ([]*DiffDelta, error) {
 	// Without git.DiffRecurseUntracked option, if a new file is added inside
 	// a new directory, we only get the path to the directory.
 	// This option is same as running git status -uall
+	flags := git.DiffIncludeUntracked | git.DiffRecurseUntracked
+	if r.TrackedOnly {
+		flags = git.DiffNormal
+	}
+
 	diff, err := r.Repo.DiffIndexToWorkdir(index, &git.DiffOptions{
-		Flags: git.DiffIncludeUntracked | git.DiffRecurseUntracked,
+		Flags: flags,
 	})
 
 	if err != nil {
@@ -193,10 +462,83 @@ func (r *libgitRepo) Changes(c Commit) ([]*DiffDelta, error) {
 		return nil, e.Wrap(ErrClassInternal, err)
 	}
 
+	err = r.findSimilar(d)
+	if err != nil {
+		return nil, err
+	}
+
 	return deltas(d)
 }
 
 func (r *libgitRepo) WalkBlobs(commit Commit, callback BlobWalkCallback) error {
+	return r.WalkBlobsUnder(commit, nil, callback)
+}
+
+func (r *libgitRepo) WalkCommits(commit Commit, callback CommitWalkCallback) error {
+	return r.walkCommits(nil, commit, callback)
+}
+
+func (r *libgitRepo) WalkCommitsInRange(from, to Commit, callback CommitWalkCallback) error {
+	return r.walkCommits(from, to, callback)
+}
+
+// walkCommits walks the commits reachable from 'to' excluding the
+// ones reachable from 'from' when it is not nil.
+func (r *libgitRepo) walkCommits(from, to Commit, callback CommitWalkCallback) error {
+	walk, err := r.Repo.Walk()
+	if err != nil {
+		return e.Wrap(ErrClassInternal, err)
+	}
+	defer walk.Free()
+
+	walk.Sorting(git.SortTopological | git.SortReverse)
+	err = walk.Push(to.(*libgitCommit).commit.Id())
+	if err != nil {
+		return e.Wrap(ErrClassInternal, err)
+	}
+
+	if from != nil {
+		err = walk.Hide(from.(*libgitCommit).commit.Id())
+		if err != nil {
+			return e.Wrap(ErrClassInternal, err)
+		}
+	}
+
+	var cbErr error
+	err = walk.Iterate(func(c *git.Commit) bool {
+		cbErr = callback(&libgitCommit{commit: c})
+		return cbErr == nil
+	})
+
+	if cbErr != nil {
+		return cbErr
+	}
+
+	if err != nil {
+		return e.Wrap(ErrClassInternal, err)
+	}
+
+	return nil
+}
+
+func (r *libgitRepo) WalkBlobsUnder(commit Commit, paths []string, callback BlobWalkCallback) error {
+	return r.WalkBlobsExcluding(commit, paths, nil, UnlimitedDiscoveryDepth, callback)
+}
+
+func (r *libgitRepo) WalkBlobsExcluding(commit Commit, paths, excluded []string, maxDepth int, callback BlobWalkCallback) error {
+	return r.walkEntries(commit, git.ObjectBlob, paths, excluded, maxDepth, callback)
+}
+
+func (r *libgitRepo) WalkGitlinksUnder(commit Commit, paths []string, callback BlobWalkCallback) error {
+	return r.walkEntries(commit, git.ObjectCommit, paths, nil, UnlimitedDiscoveryDepth, callback)
+}
+
+// walkEntries invokes the callback for each tree entry of the specified
+// type under paths, skipping the trees at excluded paths and the trees
+// deeper than maxDepth.
+func (r *libgitRepo) walkEntries(commit Commit, kind git.ObjectType, paths, excluded []string, maxDepth int, callback BlobWalkCallback) error {
+	paths = normalizeRoots(paths)
+	excluded = normalizeRoots(excluded)
 	tree, err := commit.(*libgitCommit).Tree()
 	if err != nil {
 		return err
@@ -207,7 +549,20 @@ func (r *libgitRepo) WalkBlobs(commit Commit, callback BlobWalkCallback) error {
 	)
 
 	err = tree.Walk(func(path string, entry *git.TreeEntry) int {
-		if entry.Type == git.ObjectBlob {
+		if entry.Type == git.ObjectTree && !isTreeInRoots(path+entry.Name, paths) {
+			// Skip this subtree
+			return 1
+		}
+
+		if entry.Type == git.ObjectTree && len(excluded) > 0 && isInRoots(path+entry.Name, excluded) {
+			return 1
+		}
+
+		if entry.Type == git.ObjectTree && !isWithinDepth(path+entry.Name, maxDepth) {
+			return 1
+		}
+
+		if entry.Type == kind && isInRoots(path+entry.Name, paths) {
 			b := &libgitBlob{
 				entry:  entry,
 				path:   path,
@@ -241,6 +596,33 @@ func (r *libgitRepo) BlobContents(blob Blob) ([]byte, error) {
 	return bl.Contents(), nil
 }
 
+func (r *libgitRepo) BlobSize(blob Blob) (int64, error) {
+	odb, err := r.objectDatabase()
+	if err != nil {
+		return 0, err
+	}
+
+	size, _, err := odb.ReadHeader(blob.(*libgitBlob).entry.Id)
+	if err != nil {
+		return 0, e.Wrapf(ErrClassInternal, err, "error while reading the blob header for %s%s", blob.Path(), blob.Name())
+	}
+
+	return int64(size), nil
+}
+
+// objectDatabase returns the object database of the repository.
+// It is opened on the first call and reused afterwards.
+func (r *libgitRepo) objectDatabase() (*git.Odb, error) {
+	r.odbOnce.Do(func() {
+		r.odb, r.odbErr = r.Repo.Odb()
+		if r.odbErr != nil {
+			r.odbErr = e.Wrap(ErrClassInternal, r.odbErr)
+		}
+	})
+
+	return r.odb, r.odbErr
+}
+
 func (r *libgitRepo) EntryID(commit Commit, path string) (string, error) {
 	tree, err := commit.(*libgitCommit).Tree()
 	if err != nil {
@@ -288,6 +670,17 @@ func (r *libgitRepo) CurrentBranchCommit() (Commit, error) {
 	return r.BranchCommit(b)
 }
 
+func (r *libgitRepo) HeadCommit() (Commit, error) {
+	// Head resolves symbolic references. Therefore, this works
+	// the same way for a detached head.
+	head, err := r.Repo.Head()
+	if err != nil {
+		return nil, e.Wrap(ErrClassInternal, err)
+	}
+
+	return r.GetCommit(head.Target().String())
+}
+
 func (r *libgitRepo) IsEmpty() (bool, error) {
 	empty, err := r.Repo.IsEmpty()
 	if err != nil {
@@ -299,8 +692,13 @@ func (r *libgitRepo) IsEmpty() (bool, error) {
 
 func (r *libgitRepo) FindAllFilesInWorkspace(pathSpec []string) ([]string, error) {
 	var configPaths []string
+	flags := git.StatusOptIncludeUntracked | git.StatusOptIncludeUnmodified | git.StatusOptRecurseUntrackedDirs
+	if r.TrackedOnly {
+		flags = git.StatusOptIncludeUnmodified
+	}
+
 	status, err := r.Repo.StatusList(&git.StatusOptions{
-		Flags:    git.StatusOptIncludeUntracked | git.StatusOptIncludeUnmodified | git.StatusOptRecurseUntrackedDirs,
+		Flags:    flags,
 		Pathspec: pathSpec,
 	})
 
@@ -338,6 +736,39 @@ func (r *libgitRepo) FindAllFilesInWorkspace(pathSpec []string) ([]string, error
 	return configPaths, nil
 }
 
+func (r *libgitRepo) IsDetached() (bool, error) {
+	detached, err := r.Repo.IsHeadDetached()
+	if err != nil {
+		return false, e.Wrap(ErrClassInternal, err)
+	}
+
+	return detached, nil
+}
+
+func (r *libgitRepo) IsDirty() (bool, error) {
+	if r.Repo.IsBare() {
+		// Bare repositories do not have a workspace.
+		return false, nil
+	}
+
+	status, err := r.Repo.StatusList(&git.StatusOptions{
+		Flags: git.StatusOptIncludeUntracked,
+	})
+
+	if err != nil {
+		return false, e.Wrap(ErrClassInternal, err)
+	}
+
+	defer status.Free()
+
+	count, err := status.EntryCount()
+	if err != nil {
+		return false, e.Wrap(ErrClassInternal, err)
+	}
+
+	return count > 0, nil
+}
+
 func (r *libgitRepo) EnsureSafeWorkspace() error {
 	status, err := r.Repo.StatusList(&git.StatusOptions{
 		Flags: git.StatusOptIncludeUntracked,
@@ -390,6 +821,30 @@ func (r *libgitRepo) BlobContentsFromTree(commit Commit, path string) ([]byte, e
 	return blob.Contents(), nil
 }
 
+func (r *libgitRepo) TreeBlobContents(treeID, path string) ([]byte, error) {
+	oid, err := git.NewOid(treeID)
+	if err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgInvalidTreeID, treeID)
+	}
+
+	t, err := r.Repo.LookupTree(oid)
+	if err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgTreeNotFound, treeID)
+	}
+
+	item, err := t.EntryByPath(path)
+	if err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgTreeEntryNotFound, path, treeID)
+	}
+
+	blob, err := r.Repo.LookupBlob(item.Id)
+	if err != nil {
+		return nil, e.Wrap(ErrClassInternal, err)
+	}
+
+	return blob.Contents(), nil
+}
+
 func (r *libgitRepo) readHeadReference() (Reference, error) {
 	ref, err := r.Repo.Head()
 	if err != nil {
@@ -471,6 +926,36 @@ func (r *libgitRepo) CheckoutReference(reference Reference) error {
 }
 
 func (r *libgitRepo) MergeBase(a, b Commit) (Commit, error) {
+	base, err := r.mergeBase(a, b)
+	if err == nil {
+		return base, nil
+	}
+
+	// Commits in the history of a shallow clone are not available in
+	// the object database, which would otherwise surface as a cryptic
+	// libgit2 error.
+	shallow, serr := r.Repo.IsShallow()
+	if serr != nil || !shallow {
+		return nil, err
+	}
+
+	if r.Fetch == nil {
+		return nil, e.Wrapf(ErrClassUser, ErrShallowClone, msgShallowClone, a, b)
+	}
+
+	r.Log.Infof("Fetching the history of the shallow clone in %s", r.path)
+	if err := r.Fetch(r.path); err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgFailedFetch, r.path)
+	}
+
+	return r.mergeBase(a, b)
+}
+
+func (r *libgitRepo) mergeBase(a, b Commit) (Commit, error) {
+	if r.FirstParentMergeBase {
+		return r.firstParentMergeBase(a, b)
+	}
+
 	bid, err := r.Repo.MergeBase(a.(*libgitCommit).commit.Id(), b.(*libgitCommit).commit.Id())
 	if err != nil {
 		return nil, e.Wrap(ErrClassInternal, err)
@@ -479,7 +964,67 @@ func (r *libgitRepo) MergeBase(a, b Commit) (Commit, error) {
 	return r.GetCommit(bid.String())
 }
 
+// firstParentMergeBase walks the first parent history of a and
+// returns the first commit reachable from b.
+func (r *libgitRepo) firstParentMergeBase(a, b Commit) (Commit, error) {
+	bid := b.(*libgitCommit).commit.Id()
+	for c := a.(*libgitCommit).commit; c != nil; {
+		reachable := c.Id().Equal(bid)
+		if !reachable {
+			var err error
+			reachable, err = r.Repo.DescendantOf(bid, c.Id())
+			if err != nil {
+				return nil, e.Wrap(ErrClassInternal, err)
+			}
+		}
+
+		if reachable {
+			return &libgitCommit{commit: c}, nil
+		}
+
+		if c.ParentCount() == 0 {
+			break
+		}
+		c = c.Parent(0)
+	}
+
+	return nil, e.NewErrorf(ErrClassUser, msgNoMergeBase, a, b)
+}
+
+func (r *libgitRepo) Note(ref string, commit Commit) ([]byte, error) {
+	note, err := r.Repo.Notes.Read(ref, commit.(*libgitCommit).commit.Id())
+	if err != nil {
+		if git.IsErrorCode(err, git.ErrorCodeNotFound) {
+			return nil, nil
+		}
+		return nil, e.Wrapf(ErrClassInternal, err, msgFailedNoteRead, ref, commit)
+	}
+	defer note.Free()
+
+	return []byte(note.Message()), nil
+}
+
+func (r *libgitRepo) WriteNote(ref string, commit Commit, content []byte) error {
+	sig, err := r.Repo.DefaultSignature()
+	if err != nil {
+		// Fallback to a generic signature when user.name and user.email
+		// are not configured (e.g. in CI environments).
+		sig = &git.Signature{Name: "mbt", Email: "mbt@localhost", When: time.Now()}
+	}
+
+	_, err = r.Repo.Notes.Create(ref, sig, sig, commit.(*libgitCommit).commit.Id(), string(content), true)
+	if err != nil {
+		return e.Wrapf(ErrClassInternal, err, msgFailedNoteWrite, ref, commit)
+	}
+
+	return nil
+}
+
 func diff(repo *git.Repository, ca, cb Commit) (*git.Diff, error) {
+	return diffWithOptions(repo, ca, cb, &git.DiffOptions{})
+}
+
+func diffWithOptions(repo *git.Repository, ca, cb Commit, options *git.DiffOptions) (*git.Diff, error) {
 	t1, err := ca.(*libgitCommit).Tree()
 	if err != nil {
 		return nil, err
@@ -490,7 +1035,7 @@ func diff(repo *git.Repository, ca, cb Commit) (*git.Diff, error) {
 		return nil, err
 	}
 
-	diff, err := repo.DiffTreeToTree(t1, t2, &git.DiffOptions{})
+	diff, err := repo.DiffTreeToTree(t1, t2, options)
 	if err != nil {
 		return nil, e.Wrap(ErrClassInternal, err)
 	}
@@ -16,9 +16,13 @@ limitations under the License.
 package lib
 
 import (
+	"errors"
 	"fmt"
+	"os/exec"
+	"path/filepath"
 	"testing"
 
+	git "github.com/libgit2/git2go/v28"
 	"github.com/mbtproject/mbt/e"
 	"github.com/stretchr/testify/assert"
 )
@@ -69,6 +73,45 @@ func TestDiffByIndex(t *testing.T) {
 	assert.Len(t, diff, 1)
 }
 
+func TestWorkspaceWithTrackedOnly(t *testing.T) {
+	clean()
+
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.WriteContent("app-a/test.txt", "test contents"))
+	check(t, repo.Commit("first"))
+
+	check(t, repo.WriteContent("app-a/test.txt", "amend contents"))
+	check(t, repo.WriteContent("app-a/dist/out.bin", "output"))
+	check(t, repo.InitModule("app-b"))
+
+	world := NewWorld(t, ".tmp/repo")
+	diff, err := world.Repo.DiffWorkspace()
+	check(t, err)
+	assert.Len(t, diff, 3)
+
+	files, err := world.Repo.FindAllFilesInWorkspace([]string{".mbt.yml", "/**/.mbt.yml"})
+	check(t, err)
+	assert.ElementsMatch(t, []string{"app-a/.mbt.yml", "app-b/.mbt.yml"}, files)
+
+	r, err := NewLibgitRepoWithOptions(".tmp/repo", world.Log, &RepoOptions{TrackedOnly: true})
+	check(t, err)
+
+	diff, err = r.DiffWorkspace()
+	check(t, err)
+	assert.Len(t, diff, 1)
+	assert.Equal(t, "app-a/test.txt", diff[0].NewFile)
+
+	files, err = r.FindAllFilesInWorkspace([]string{".mbt.yml", "/**/.mbt.yml"})
+	check(t, err)
+	assert.Equal(t, []string{"app-a/.mbt.yml"}, files)
+
+	mods, err := NewDiscover(r, world.Log).ModulesInWorkspace()
+	check(t, err)
+	assert.Equal(t, []string{"app-a"}, mods.names())
+}
+
 func TestDirtyWorkspaceForUntracked(t *testing.T) {
 	clean()
 	repo := NewTestRepo(t, ".tmp/repo")
@@ -232,3 +275,432 @@ func TestGitIgnoreRulesInSafeWorkspace(t *testing.T) {
 	w := NewWorld(t, ".tmp/repo")
 	check(t, w.Repo.EnsureSafeWorkspace())
 }
+
+func TestDescribeBareRepository(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModuleWithOptions("lib/app-b", &Spec{Name: "app-b", Dependencies: []string{"app-a"}}))
+	check(t, repo.Commit("first"))
+
+	src, err := filepath.Abs(".tmp/repo")
+	check(t, err)
+	bare, err := git.Clone(src, ".tmp/bare.git", &git.CloneOptions{Bare: true})
+	check(t, err)
+	defer bare.Free()
+
+	root, err := GitRepoRoot(".tmp/bare.git/refs")
+	check(t, err)
+	expected, err := filepath.Abs(".tmp/bare.git")
+	check(t, err)
+	assert.Equal(t, expected, root)
+
+	w := NewWorld(t, ".tmp/bare.git")
+
+	m, err := w.System.ManifestByCurrentBranch()
+	check(t, err)
+
+	assert.Len(t, m.Modules, 2)
+	assert.Equal(t, "app-a", m.Modules[0].Name())
+	assert.Equal(t, "app-b", m.Modules[1].Name())
+	dirty, err := m.State.IsDirty()
+	check(t, err)
+	assert.False(t, dirty)
+
+	m, err = w.System.ManifestByBranch("master")
+	check(t, err)
+
+	assert.Len(t, m.Modules, 2)
+}
+
+func TestFirstParentMergeBase(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.WriteContent("readme.md", "hello"))
+	check(t, repo.Commit("c1"))
+	c1 := repo.LastCommit.String()
+
+	check(t, repo.SwitchToBranch("side"))
+	check(t, repo.WriteContent("side.md", "hello"))
+	check(t, repo.Commit("s1"))
+	s1 := repo.LastCommit.String()
+
+	check(t, repo.SwitchToBranch("master"))
+	check(t, repo.SwitchToBranch("release"))
+	check(t, repo.WriteContent("release.md", "hello"))
+	check(t, repo.Commit("r1"))
+	m, err := repo.SimpleMerge("side", "release")
+	check(t, err)
+
+	check(t, repo.SwitchToBranch("side"))
+	check(t, repo.SwitchToBranch("feature"))
+	check(t, repo.WriteContent("feature.md", "hello"))
+	check(t, repo.Commit("f1"))
+	f1 := repo.LastCommit.String()
+
+	world := NewWorld(t, ".tmp/repo")
+	from, err := world.Repo.GetCommit(m.String())
+	check(t, err)
+	to, err := world.Repo.GetCommit(f1)
+	check(t, err)
+
+	base, err := world.Repo.MergeBase(from, to)
+	check(t, err)
+	assert.Equal(t, s1, base.ID())
+
+	deltas, err := world.Repo.DiffMergeBase(from, to)
+	check(t, err)
+	assert.Len(t, deltas, 1)
+	assert.Equal(t, "feature.md", deltas[0].NewFile)
+
+	r, err := NewLibgitRepoWithOptions(".tmp/repo", world.Log, &RepoOptions{FirstParentMergeBase: true})
+	check(t, err)
+
+	base, err = r.MergeBase(from, to)
+	check(t, err)
+	assert.Equal(t, c1, base.ID())
+
+	deltas, err = r.DiffMergeBase(from, to)
+	check(t, err)
+	assert.Len(t, deltas, 2)
+	assert.Equal(t, "feature.md", deltas[0].NewFile)
+	assert.Equal(t, "side.md", deltas[1].NewFile)
+}
+
+func TestDiffWithRenameThreshold(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.WriteContent("app-a/foo", "hello world\n"))
+	check(t, repo.Commit("first"))
+	first := repo.LastCommit.String()
+
+	check(t, repo.Rename("app-a/foo", "app-b/foo"))
+	check(t, repo.Commit("second"))
+	second := repo.LastCommit.String()
+
+	world := NewWorld(t, ".tmp/repo")
+	from, err := world.Repo.GetCommit(first)
+	check(t, err)
+	to, err := world.Repo.GetCommit(second)
+	check(t, err)
+
+	deltas, err := world.Repo.DiffMergeBase(from, to)
+	check(t, err)
+	assert.Len(t, deltas, 2)
+
+	r, err := NewLibgitRepoWithOptions(".tmp/repo", world.Log, &RepoOptions{RenameThreshold: 50})
+	check(t, err)
+
+	deltas, err = r.DiffMergeBase(from, to)
+	check(t, err)
+	assert.Len(t, deltas, 1)
+	assert.Equal(t, "app-a/foo", deltas[0].OldFile)
+	assert.Equal(t, "app-b/foo", deltas[0].NewFile)
+}
+
+func TestInvalidSimilarityThreshold(t *testing.T) {
+	_, err := NewLibgitRepoWithOptions(".tmp/repo", NewStdLog(LogLevelNormal), &RepoOptions{RenameThreshold: 101})
+
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidSimilarityThreshold, 101))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestFirstParentMergeBaseOfAncestor(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.WriteContent("readme.md", "hello"))
+	check(t, repo.Commit("first"))
+	first := repo.LastCommit.String()
+	check(t, repo.WriteContent("foo.md", "hello"))
+	check(t, repo.Commit("second"))
+	second := repo.LastCommit.String()
+
+	world := NewWorld(t, ".tmp/repo")
+	r, err := NewLibgitRepoWithOptions(".tmp/repo", world.Log, &RepoOptions{FirstParentMergeBase: true})
+	check(t, err)
+
+	a, err := r.GetCommit(first)
+	check(t, err)
+	b, err := r.GetCommit(second)
+	check(t, err)
+
+	base, err := r.MergeBase(b, a)
+	check(t, err)
+	assert.Equal(t, first, base.ID())
+
+	base, err = r.MergeBase(a, b)
+	check(t, err)
+	assert.Equal(t, first, base.ID())
+}
+
+// shallowClone creates a repo with diverged master and feature
+// branches and clones it to .tmp/shallow with a depth of 1.
+// Returns the shas of the merge base, master and feature.
+func shallowClone(t *testing.T) (string, string, string) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.WriteContent("readme.md", "hello"))
+	check(t, repo.Commit("first"))
+	base := repo.LastCommit.String()
+
+	check(t, repo.SwitchToBranch("feature"))
+	check(t, repo.WriteContent("feature.md", "hello"))
+	check(t, repo.Commit("feature"))
+	feature := repo.LastCommit.String()
+
+	check(t, repo.SwitchToBranch("master"))
+	check(t, repo.WriteContent("master.md", "hello"))
+	check(t, repo.Commit("master"))
+	master := repo.LastCommit.String()
+
+	abs, err := filepath.Abs(".tmp/repo")
+	check(t, err)
+	out, err := exec.Command("git", "clone", "-q", "--depth", "1", "--no-single-branch", "file://"+filepath.ToSlash(abs), ".tmp/shallow").CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+
+	return base, master, feature
+}
+
+func TestMergeBaseInShallowClone(t *testing.T) {
+	_, master, feature := shallowClone(t)
+
+	r, err := NewLibgitRepo(".tmp/shallow", NewStdLog(LogLevelNormal))
+	check(t, err)
+	from, err := r.GetCommit(master)
+	check(t, err)
+	to, err := r.GetCommit(feature)
+	check(t, err)
+
+	_, err = r.MergeBase(from, to)
+
+	assert.EqualError(t, err, fmt.Sprintf(msgShallowClone, from, to))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+	assert.True(t, IsShallowCloneError(err))
+
+	_, err = r.DiffMergeBase(from, to)
+
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+	assert.True(t, IsShallowCloneError(err))
+}
+
+func TestMergeBaseInShallowCloneWithFetch(t *testing.T) {
+	base, master, feature := shallowClone(t)
+
+	fetched := 0
+	r, err := NewLibgitRepoWithOptions(".tmp/shallow", NewStdLog(LogLevelNormal), &RepoOptions{
+		Fetch: func(path string) error {
+			fetched++
+			cmd := exec.Command("git", "fetch", "-q", "--unshallow")
+			cmd.Dir = path
+			return cmd.Run()
+		},
+	})
+	check(t, err)
+	from, err := r.GetCommit(master)
+	check(t, err)
+	to, err := r.GetCommit(feature)
+	check(t, err)
+
+	b, err := r.MergeBase(from, to)
+	check(t, err)
+
+	assert.Equal(t, base, b.ID())
+	assert.Equal(t, 1, fetched)
+}
+
+func TestMergeBaseInShallowCloneWithFailingFetch(t *testing.T) {
+	_, master, feature := shallowClone(t)
+
+	r, err := NewLibgitRepoWithOptions(".tmp/shallow", NewStdLog(LogLevelNormal), &RepoOptions{
+		Fetch: func(path string) error {
+			return errors.New("doh")
+		},
+	})
+	check(t, err)
+	from, err := r.GetCommit(master)
+	check(t, err)
+	to, err := r.GetCommit(feature)
+	check(t, err)
+
+	_, err = r.MergeBase(from, to)
+
+	assert.EqualError(t, err, fmt.Sprintf(msgFailedFetch, ".tmp/shallow"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+	assert.False(t, IsShallowCloneError(err))
+}
+
+func TestIsShallowCloneError(t *testing.T) {
+	err := e.Wrapf(ErrClassUser, ErrShallowClone, "a")
+
+	assert.True(t, IsShallowCloneError(err))
+	assert.True(t, IsShallowCloneError(e.Wrapf(ErrClassUser, err, "b")))
+	assert.False(t, IsShallowCloneError(e.NewError(ErrClassUser, "a")))
+	assert.False(t, IsShallowCloneError(errors.New("a")))
+	assert.False(t, IsShallowCloneError(nil))
+}
+
+func TestResolveRevision(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.WriteContent("readme.md", "hello"))
+	check(t, repo.Commit("first"))
+	first := repo.LastCommit.String()
+	check(t, repo.WriteContent("readme.md", "hello world"))
+	check(t, repo.Commit("second"))
+	second := repo.LastCommit.String()
+
+	check(t, repo.SwitchToBranch("feature"))
+	check(t, repo.WriteContent("feature.md", "hello"))
+	check(t, repo.Commit("third"))
+	third := repo.LastCommit.String()
+
+	r := NewWorld(t, ".tmp/repo").Repo
+	for rev, expected := range map[string]string{
+		third:         third,
+		"HEAD":        third,
+		"HEAD^":       second,
+		"HEAD~1":      second,
+		"HEAD~2":      first,
+		"master":      second,
+		"master~1":    first,
+		"feature^^":   first,
+		second[:7]:    second,
+		"feature~0":   third,
+		"HEAD^{tree}": "",
+	} {
+		c, err := r.ResolveRevision(rev)
+		if expected == "" {
+			assert.Error(t, err, rev)
+			continue
+		}
+		check(t, err)
+		assert.Equal(t, expected, c.ID(), rev)
+	}
+}
+
+func TestLatestTag(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.WriteContent("readme.md", "hello"))
+	check(t, repo.Commit("first"))
+	check(t, repo.Tag("v1.0.0"))
+	first := repo.LastCommit.String()
+
+	check(t, repo.WriteContent("readme.md", "hello world"))
+	check(t, repo.Commit("second"))
+	check(t, repo.Tag("v1.1.0-rc1"))
+	check(t, repo.Tag("nightly"))
+	second := repo.LastCommit.String()
+
+	check(t, repo.SwitchToBranch("feature"))
+	check(t, repo.WriteContent("feature.md", "hello"))
+	check(t, repo.Commit("third"))
+	check(t, repo.Tag("v2.0.0"))
+	third := repo.LastCommit.String()
+
+	check(t, repo.SwitchToBranch("master"))
+	check(t, repo.WriteContent("readme.md", "hello again"))
+	check(t, repo.Commit("fourth"))
+
+	r := NewWorld(t, ".tmp/repo").Repo
+	head, err := r.ResolveRevision("master")
+	check(t, err)
+
+	name, c, err := r.LatestTag(head, ">=0.0.0")
+	check(t, err)
+	assert.Equal(t, "v1.0.0", name)
+	assert.Equal(t, first, c.ID())
+
+	name, c, err = r.LatestTag(head, ">=1.1.0-rc1")
+	check(t, err)
+	assert.Equal(t, "v1.1.0-rc1", name)
+	assert.Equal(t, second, c.ID())
+
+	feature, err := r.ResolveRevision("feature")
+	check(t, err)
+
+	name, c, err = r.LatestTag(feature, "")
+	check(t, err)
+	assert.Equal(t, "v2.0.0", name)
+	assert.Equal(t, third, c.ID())
+}
+
+func TestLatestTagWithoutMatchingTag(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.WriteContent("readme.md", "hello"))
+	check(t, repo.Commit("first"))
+	check(t, repo.Tag("v1.0.0-rc1"))
+
+	r := NewWorld(t, ".tmp/repo").Repo
+	head, err := r.ResolveRevision("HEAD")
+	check(t, err)
+
+	_, _, err = r.LatestTag(head, ">=0.0.0")
+
+	assert.EqualError(t, err, fmt.Sprintf(msgNoMatchingTag, head, ">=0.0.0"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestResolveInvalidRevision(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.WriteContent("readme.md", "hello"))
+	check(t, repo.Commit("first"))
+
+	r := NewWorld(t, ".tmp/repo").Repo
+	_, err := r.ResolveRevision("HEAD~5")
+
+	assert.EqualError(t, err, fmt.Sprintf(msgFailedResolveRevision, "HEAD~5"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+
+	_, err = r.ResolveRevision("0000000000000000000000000000000000000000")
+
+	assert.EqualError(t, err, fmt.Sprintf(msgCommitShaNotFound, "0000000000000000000000000000000000000000"))
+}
+
+func TestWalkBlobsExcludingWithMaxDepth(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.WriteContent("readme.md", "hello"))
+	check(t, repo.WriteContent("a/foo", "hello"))
+	check(t, repo.WriteContent("a/b/foo", "hello"))
+	check(t, repo.WriteContent("a/b/c/foo", "hello"))
+	check(t, repo.Commit("first"))
+
+	r := NewWorld(t, ".tmp/repo").Repo
+	c, err := r.GetCommit(repo.LastCommit.String())
+	check(t, err)
+
+	for depth, expected := range map[int][]string{
+		-1: {"readme.md", "a/foo", "a/b/foo", "a/b/c/foo"},
+		0:  {"readme.md"},
+		1:  {"readme.md", "a/foo"},
+		2:  {"readme.md", "a/foo", "a/b/foo"},
+	} {
+		visited := make([]string, 0)
+		err = r.WalkBlobsExcluding(c, nil, nil, depth, func(b Blob) error {
+			visited = append(visited, b.Path()+b.Name())
+			return nil
+		})
+		check(t, err)
+
+		assert.ElementsMatch(t, expected, visited, "depth %d", depth)
+	}
+}
@@ -35,4 +35,62 @@ const (
 	msgSuccessfulCheckout                  = "Successfully checked out commit %v"
 	msgDirtyWorkingDir                     = "Dirty working dir"
 	msgDetachedHead                        = "Head is currently detached"
+	msgFailedNoteRead                      = "Failed to read the note in %v for commit %v"
+	msgFailedNoteWrite                     = "Failed to write the note in %v for commit %v"
+	msgUndefinedProperty                   = "Module %v references undefined property %v in command %v"
+	msgEnvFileNotFound                     = "Env file %v of module %v is not found"
+	msgEnvFileOutsideModule                = "Env file %v of module %v is outside the module directory"
+	msgMalformedEnvFile                    = "Line %v in env file %v is malformed"
+	msgInvalidDiscoveryDepth               = "Invalid discovery depth %v - Specify a depth of 0 or more, or -1 for unlimited"
+	msgInvalidImpactDepth                  = "Invalid impact depth %v - Specify a depth of 0 or more, or -1 for unlimited"
+	msgFanOutExceeded                      = "Change in module %v impacts %v modules exceeding the limit of %v"
+	msgMalformedBuildNote                  = "Build note in %v for commit %v is malformed"
+	msgInvalidFilePath                     = "Invalid file path '%v'"
+	msgDeprecatedModule                    = "Module %v is deprecated"
+	msgDeprecatedModuleWithMessage         = "Module %v is deprecated - %v"
+	msgUnknownExportFormat                 = "Unknown export format '%v'"
+	msgFailedExport                        = "Failed to export %v to '%v'"
+	msgMalformedPatch                      = "Malformed patch at line %v"
+	msgUnknownVersionHash                  = "Unknown version hash algorithm '%v'"
+	msgConflictingModules                  = "Modules %v and %v are mutually exclusive but both are impacted by the change"
+	msgUnexpectedExitCode                  = "Command exited with code %v"
+	msgInvalidTreeID                       = "Invalid tree id '%v'"
+	msgTreeNotFound                        = "Failed to find tree '%v'"
+	msgTreeEntryNotFound                   = "Failed to find '%v' in tree '%v'"
+	msgModuleNotFound                      = "Module '%v' is not found"
+	msgDependencyPathNotFound              = "Failed to find a module in path '%v' required by module %v"
+	msgDuplicateModuleName                 = "Module name '%v' in directory '%v' conflicts with the module in '%v' directory"
+	msgMultipleSpecFiles                   = "Directory '%v' contains more than one spec file"
+	msgNoMergeBase                         = "Failed to find a merge base of %v and %v"
+	msgArtifactNotFound                    = "Artifact '%v' of module %v is not found after the build"
+	msgArtifactChecksumMismatch            = "Checksum of artifact '%v' of module %v is %v but expected %v"
+	msgInvalidSimilarityThreshold          = "Invalid similarity threshold %v (expected a value between 0 and 100)"
+	msgInvalidEnabledWhen                  = "Invalid enabledWhen value '%v' in module %v (expected a boolean)"
+	msgDisabledDependency                  = "Module %v requires module %v which is disabled"
+	msgAmbiguousSha                        = "Abbreviated commit sha '%v' matches more than one object"
+	msgInconsistentDependencyGraph         = "Inconsistent dependency graph: module %v has %v in its %v list but the inverse relation is missing"
+	msgModuleInMultipleGraphs              = "Module %v is defined in more than one of the merged module sets (%v and %v)"
+	msgShallowClone                        = "Failed to find a merge base of %v and %v because the repository is a shallow clone - Fetch the missing history (e.g. git fetch --unshallow) and try again"
+	msgFailedFetch                         = "Failed to fetch the history of the shallow clone in %v"
+	msgFailedResolveRevision               = "Failed to resolve revision '%v'"
+	msgDuplicateModuleID                   = "Module id '%v' in directory '%v' conflicts with the module in '%v' directory"
+	msgInvalidVersionConstraint            = "Invalid version constraint '%v'"
+	msgNoMatchingTag                       = "Could not find a tag reachable from '%v' satisfying the version constraint '%v'"
+	msgInvalidMakeTarget                   = "Module name '%v' cannot be used as a make target"
+	msgInvalidProposedSpec                 = "Proposed spec for the module in directory '%v' is invalid"
+	msgMissingModuleName                   = "Spec for the module in directory '%v' does not specify the module name"
+	msgNoModuleOwnsPath                    = "File '%v' does not belong to any module"
+	msgNoCommonOwner                       = "Files '%v' and '%v' do not belong to a common module"
+	msgDependenciesFileNotFound            = "Failed to read the dependencies file %v of module %v in %v"
+	msgAmbiguousParent                     = "Module '%v' inherits properties but does not have a single dependency - Specify the parent to inherit from"
+	msgParentNotDependency                 = "Parent '%v' of module '%v' is not one of its dependencies"
+	msgFailedLoadModules                   = "Failed to load the modules"
+	msgUnexpectedDocument                  = "Expected a document of kind %v (%v) but found %v (%v)"
+	msgFailedTreeSpecParse                 = "Failed to parse the spec in tree %v"
+	msgUnknownMermaidDirection             = "Unknown flowchart direction '%v' - Available options are 'TB', 'TD', 'BT', 'RL' and 'LR'"
+	msgUnknownDirection                    = "Unknown dependency direction '%v' - Available options are 'requires' and 'requiredBy'"
+	msgReservedCommandName                 = "Command name '%v' is reserved - Use the build section of the spec instead"
+	msgArtifactOutsideModule               = "Artifact '%v' of module %v is outside the module directory"
+	msgFailedWorkspaceStatus               = "Failed to read the status of the workspace - %v"
+	msgCyclicDependency                    = "Could not produce the module graph due to a cyclic dependency in path: %s"
 )
@@ -17,6 +17,7 @@ package lib
 
 import (
 	"runtime"
+	"time"
 
 	"github.com/mbtproject/mbt/e"
 )
@@ -53,6 +54,15 @@ func (s *stdSystem) RunInDiff(command, from, to string, options *CmdOptions) (*R
 	return s.checkoutAndRunManifest(command, m, options)
 }
 
+func (s *stdSystem) RunInSinceTag(command, constraint, to string, options *CmdOptions) (*RunResult, error) {
+	m, err := s.ManifestSinceTag(constraint, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.checkoutAndRunManifest(command, m, options)
+}
+
 func (s *stdSystem) RunInCurrentBranch(command string, filterOptions *FilterOptions, options *CmdOptions) (*RunResult, error) {
 	m, err := s.ManifestByCurrentBranch()
 	if err != nil {
@@ -129,53 +139,54 @@ func (s *stdSystem) runManifest(command string, m *Manifest, options *CmdOptions
 	completed := make([]*Module, 0)
 	skipped := make([]*Module, 0)
 	failed := make([]*CmdFailure, 0)
+	summary := newRunSummary()
+	started := time.Now()
 
 	var err error
 	for _, a := range m.Modules {
 		cmd, canRun := s.canRunHere(command, a)
 		if !canRun || (err != nil && options.FailFast) {
 			skipped = append(skipped, a)
+			summary.add(a, RunStatusSkipped, 0, nil)
 			options.Callback(a, CmdStageSkipBuild, nil)
 			continue
 		}
 
 		options.Callback(a, CmdStageBeforeBuild, nil)
-		err = s.execCommand(cmd, m, a, options)
-		if err != nil {
+		var result CmdResult
+		start := time.Now()
+		result, err = s.execCommand(cmd, m, a, options)
+		duration := time.Since(start)
+		switch {
+		case err != nil:
 			failed = append(failed, &CmdFailure{Err: err, Module: a})
+			summary.add(a, RunStatusFailed, duration, err)
 			options.Callback(a, CmdStageFailedBuild, err)
-		} else {
+		case result == CmdResultSkipped:
+			skipped = append(skipped, a)
+			summary.add(a, RunStatusSkipped, duration, nil)
+			options.Callback(a, CmdStageSkipBuild, nil)
+		default:
 			completed = append(completed, a)
+			summary.add(a, RunStatusSucceeded, duration, nil)
 			options.Callback(a, CmdStageAfterBuild, nil)
 		}
 	}
+	summary.Duration = time.Since(started)
 
-	return &RunResult{Manifest: m, Failures: failed, Completed: completed, Skipped: skipped}, nil
+	return &RunResult{Manifest: m, Failures: failed, Completed: completed, Skipped: skipped, Summary: summary}, nil
 }
 
-func (s *stdSystem) execCommand(command *UserCmd, manifest *Manifest, module *Module, options *CmdOptions) error {
-	err := s.ProcessManager.Exec(manifest, module, options, command.Cmd, command.Args...)
+func (s *stdSystem) execCommand(command *Cmd, manifest *Manifest, module *Module, options *CmdOptions) (CmdResult, error) {
+	options, flush := prefixOutput(options, module)
+	result, err := classifyExec(command, s.ProcessManager.Exec(manifest, module, options, command.Cmd, command.Args...))
+	flush()
 	if err != nil {
-		return e.Wrap(ErrClassUser, err)
+		return result, e.Wrap(ErrClassUser, err)
 	}
-	return nil
+	return result, nil
 }
 
-func (s *stdSystem) canRunHere(command string, mod *Module) (*UserCmd, bool) {
-	c, ok := mod.Commands()[command]
-	if !ok {
-		return nil, false
-	}
-
-	if len(c.OS) == 0 {
-		return c, true
-	}
-
-	for _, os := range c.OS {
-		if os == runtime.GOOS {
-			return c, true
-		}
-	}
-
-	return nil, false
+func (s *stdSystem) canRunHere(command string, mod *Module) (*Cmd, bool) {
+	return mod.CommandForOS(command, runtime.GOOS)
 }
@@ -216,6 +216,38 @@ func TestRunInDiff(t *testing.T) {
 	assert.Equal(t, "", buff.String())
 }
 
+func TestRunInSinceTag(t *testing.T) {
+	clean()
+	r := NewTestRepo(t, ".tmp/repo")
+
+	check(t, r.InitModuleWithOptions("app-a", &Spec{
+		Name: "app-a",
+		Commands: map[string]*UserCmd{
+			"echo": {Cmd: "echo", Args: []string{"hello-app-a"}},
+		},
+	}))
+	check(t, r.Commit("first"))
+	check(t, r.Tag("v1.0.0"))
+
+	check(t, r.InitModuleWithOptions("app-b", &Spec{
+		Name: "app-b",
+		Commands: map[string]*UserCmd{
+			"echo": {Cmd: "echo", Args: []string{"hello-app-b"}},
+		},
+	}))
+	check(t, r.Commit("second"))
+
+	w := NewWorld(t, ".tmp/repo")
+
+	buff := new(bytes.Buffer)
+	result, err := w.System.RunInSinceTag("echo", ">=1.0.0", r.LastCommit.String(), stdTestCmdOptions(buff))
+
+	check(t, err)
+	assert.Len(t, result.Completed, 1)
+	assert.Equal(t, "app-b", result.Completed[0].Name())
+	assert.Equal(t, "hello-app-b\n", buff.String())
+}
+
 func TestRunInCurrentBranch(t *testing.T) {
 	clean()
 	r := NewTestRepo(t, ".tmp/repo")
@@ -449,3 +481,93 @@ func TestFailFast(t *testing.T) {
 	assert.Equal(t, "app-c", result.Skipped[0].Name())
 	assert.Equal(t, "app-a\n", buff.String())
 }
+
+func TestRunInWithExitCodes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires sh")
+	}
+
+	clean()
+	r := NewTestRepo(t, ".tmp/repo")
+
+	check(t, r.InitModuleWithOptions("app-a", &Spec{
+		Name: "app-a",
+		Commands: map[string]*UserCmd{
+			"check": {Cmd: "sh", Args: []string{"-c", "exit 2"}, SkipCodes: []int{2}},
+		},
+	}))
+	check(t, r.InitModuleWithOptions("app-b", &Spec{
+		Name: "app-b",
+		Commands: map[string]*UserCmd{
+			"check": {Cmd: "sh", Args: []string{"-c", "exit 3"}, SuccessCodes: []int{0, 3}},
+		},
+	}))
+	check(t, r.InitModuleWithOptions("app-c", &Spec{
+		Name: "app-c",
+		Commands: map[string]*UserCmd{
+			"check": {Cmd: "sh", Args: []string{"-c", "exit 2"}},
+		},
+	}))
+	check(t, r.Commit("first"))
+
+	w := NewWorld(t, ".tmp/repo")
+
+	buff := new(bytes.Buffer)
+	result, err := w.System.RunInCurrentBranch("check", NoFilter, stdTestCmdOptions(buff))
+
+	check(t, err)
+	assert.Len(t, result.Completed, 1)
+	assert.Len(t, result.Skipped, 1)
+	assert.Len(t, result.Failures, 1)
+	assert.Equal(t, "app-b", result.Completed[0].Name())
+	assert.Equal(t, "app-a", result.Skipped[0].Name())
+	assert.Equal(t, "app-c", result.Failures[0].Module.Name())
+}
+
+func TestRunInSummary(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires sh")
+	}
+
+	clean()
+	r := NewTestRepo(t, ".tmp/repo")
+
+	check(t, r.InitModuleWithOptions("app-a", &Spec{
+		Name:     "app-a",
+		Commands: map[string]*UserCmd{"check": {Cmd: "sh", Args: []string{"-c", "exit 2"}, SkipCodes: []int{2}}},
+	}))
+	check(t, r.InitModuleWithOptions("app-b", &Spec{
+		Name:     "app-b",
+		Commands: map[string]*UserCmd{"check": {Cmd: "sh", Args: []string{"-c", "exit 0"}}},
+	}))
+	check(t, r.InitModuleWithOptions("app-c", &Spec{
+		Name:     "app-c",
+		Commands: map[string]*UserCmd{"check": {Cmd: "sh", Args: []string{"-c", "exit 1"}}},
+	}))
+	check(t, r.InitModule("app-d"))
+	check(t, r.Commit("first"))
+
+	w := NewWorld(t, ".tmp/repo")
+
+	buff := new(bytes.Buffer)
+	result, err := w.System.RunInCurrentBranch("check", NoFilter, stdTestCmdOptions(buff))
+	check(t, err)
+
+	summary := result.Summary
+	assert.Equal(t, 4, summary.Total)
+	assert.Equal(t, 1, summary.Succeeded)
+	assert.Equal(t, 1, summary.Failed)
+	assert.Equal(t, 2, summary.Skipped)
+	assert.Len(t, summary.Modules, 4)
+
+	statuses := make(map[string]string)
+	for _, m := range summary.Modules {
+		statuses[m.Name] = m.Status
+	}
+	assert.Equal(t, map[string]string{
+		"app-a": RunStatusSkipped,
+		"app-b": RunStatusSucceeded,
+		"app-c": RunStatusFailed,
+		"app-d": RunStatusSkipped,
+	}, statuses)
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// semVersion is a parsed semantic version (https://semver.org).
+// Build metadata is ignored.
+type semVersion struct {
+	major, minor, patch int
+	prerelease          []string
+}
+
+// parseSemVersion parses a version in major.minor.patch[-prerelease][+build]
+// format. An optional 'v' prefix is accepted (e.g. v1.2.3).
+func parseSemVersion(s string) (*semVersion, error) {
+	v := strings.TrimPrefix(s, "v")
+	if i := strings.Index(v, "+"); i >= 0 {
+		v = v[:i]
+	}
+
+	var pre string
+	if i := strings.Index(v, "-"); i >= 0 {
+		v, pre = v[:i], v[i+1:]
+		if pre == "" {
+			return nil, fmt.Errorf("invalid version %v", s)
+		}
+	}
+
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid version %v", s)
+	}
+
+	nums := make([]int, 3)
+	for i, p := range parts {
+		// Numbers must be non-negative integers without a sign or
+		// leading zeros (e.g. +1 and 01 are invalid).
+		if !isDigits(p) || (len(p) > 1 && p[0] == '0') {
+			return nil, fmt.Errorf("invalid version %v", s)
+		}
+
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version %v", s)
+		}
+		nums[i] = n
+	}
+
+	r := &semVersion{major: nums[0], minor: nums[1], patch: nums[2]}
+	if pre != "" {
+		r.prerelease = strings.Split(pre, ".")
+	}
+	return r, nil
+}
+
+// isDigits returns true if s is a non-empty string of decimal digits.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isPrerelease returns true if the version has prerelease identifiers
+// (e.g. 1.0.0-rc1).
+func (v *semVersion) isPrerelease() bool {
+	return len(v.prerelease) > 0
+}
+
+// compare returns -1, 0 or 1 if v is lower than, equal to or higher
+// than o according to semver precedence rules.
+func (v *semVersion) compare(o *semVersion) int {
+	for _, d := range []int{v.major - o.major, v.minor - o.minor, v.patch - o.patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+
+	// A version without prerelease identifiers has higher precedence.
+	if !v.isPrerelease() || !o.isPrerelease() {
+		return sign(len(o.prerelease) - len(v.prerelease))
+	}
+
+	for i := 0; i < len(v.prerelease) && i < len(o.prerelease); i++ {
+		a, b := v.prerelease[i], o.prerelease[i]
+		if a == b {
+			continue
+		}
+		an, aerr := strconv.Atoi(a)
+		bn, berr := strconv.Atoi(b)
+		switch {
+		case aerr == nil && berr == nil:
+			return sign(an - bn)
+		case aerr == nil:
+			// Numeric identifiers have lower precedence.
+			return -1
+		case berr == nil:
+			return 1
+		default:
+			return sign(strings.Compare(a, b))
+		}
+	}
+	return sign(len(v.prerelease) - len(o.prerelease))
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+type semComparison struct {
+	op      string
+	version *semVersion
+}
+
+// semConstraint is a set of comparisons (e.g. >=1.0.0, <2.0.0)
+// that must all be satisfied by a version.
+type semConstraint struct {
+	comparisons []*semComparison
+}
+
+var semOperators = []string{">=", "<=", "!=", ">", "<", "="}
+
+// parseSemConstraint parses a comma or space separated list of
+// comparisons. Supported operators are =, !=, >, >=, < and <=.
+// A version without an operator must match exactly.
+// Empty constraint is satisfied by any stable version.
+func parseSemConstraint(s string) (*semConstraint, error) {
+	c := &semConstraint{}
+	for _, term := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		op := "="
+		for _, o := range semOperators {
+			if strings.HasPrefix(term, o) {
+				op = o
+				term = term[len(o):]
+				break
+			}
+		}
+
+		v, err := parseSemVersion(term)
+		if err != nil {
+			return nil, e.NewErrorf(ErrClassUser, msgInvalidVersionConstraint, s)
+		}
+		c.comparisons = append(c.comparisons, &semComparison{op: op, version: v})
+	}
+	return c, nil
+}
+
+// check returns true if the version satisfies all comparisons in the
+// constraint.
+// Prerelease versions are only considered when the constraint itself
+// refers to a prerelease of the same major.minor.patch version.
+// Therefore, >=0.0.0 is satisfied by 1.0.0 but not by 1.0.0-rc1.
+func (c *semConstraint) check(v *semVersion) bool {
+	if v.isPrerelease() && !c.allowsPrereleaseOf(v) {
+		return false
+	}
+
+	for _, cmp := range c.comparisons {
+		r := v.compare(cmp.version)
+		var ok bool
+		switch cmp.op {
+		case "=":
+			ok = r == 0
+		case "!=":
+			ok = r != 0
+		case ">":
+			ok = r > 0
+		case ">=":
+			ok = r >= 0
+		case "<":
+			ok = r < 0
+		case "<=":
+			ok = r <= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *semConstraint) allowsPrereleaseOf(v *semVersion) bool {
+	for _, cmp := range c.comparisons {
+		o := cmp.version
+		if o.isPrerelease() && o.major == v.major && o.minor == v.minor && o.patch == v.patch {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSemVersion(t *testing.T) {
+	v, err := parseSemVersion("v1.2.3-rc.1+build.5")
+	check(t, err)
+
+	assert.Equal(t, 1, v.major)
+	assert.Equal(t, 2, v.minor)
+	assert.Equal(t, 3, v.patch)
+	assert.Equal(t, []string{"rc", "1"}, v.prerelease)
+	assert.True(t, v.isPrerelease())
+}
+
+func TestParseInvalidSemVersion(t *testing.T) {
+	for _, s := range []string{"", "release", "1.2", "1.2.3.4", "1.a.3", "1.2.3-", "-1.2.3", "+1.2.3", "1.+2.3", "01.2.3", "1.02.3", "1.2.03", "1..3"} {
+		_, err := parseSemVersion(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestSemVersionPrecedence(t *testing.T) {
+	// Ordered by precedence as per the example in semver spec.
+	versions := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.1.0",
+		"2.0.0",
+	}
+
+	for i := 0; i < len(versions)-1; i++ {
+		a, err := parseSemVersion(versions[i])
+		check(t, err)
+		b, err := parseSemVersion(versions[i+1])
+		check(t, err)
+
+		assert.Equal(t, -1, a.compare(b), "%v < %v", versions[i], versions[i+1])
+		assert.Equal(t, 1, b.compare(a), "%v > %v", versions[i+1], versions[i])
+		assert.Equal(t, 0, a.compare(a))
+	}
+}
+
+func TestSemConstraint(t *testing.T) {
+	cases := []struct {
+		constraint string
+		version    string
+		expected   bool
+	}{
+		{"", "1.0.0", true},
+		{"", "1.0.0-rc1", false},
+		{">=0.0.0", "1.2.3", true},
+		{">=0.0.0", "1.2.3-rc1", false},
+		{">=1.0.0, <2.0.0", "1.9.0", true},
+		{">=1.0.0, <2.0.0", "2.0.0", false},
+		{">=1.0.0 <2.0.0", "0.9.0", false},
+		{"1.0.0", "1.0.0", true},
+		{"=1.0.0", "1.0.1", false},
+		{"!=1.0.0", "1.0.1", true},
+		{">1.0.0", "1.0.0", false},
+		{"<=1.0.0", "1.0.0", true},
+		{">=1.0.0-rc1", "1.0.0-rc2", true},
+		{">=1.0.0-rc1", "1.1.0-rc1", false},
+	}
+
+	for _, c := range cases {
+		sc, err := parseSemConstraint(c.constraint)
+		check(t, err)
+		v, err := parseSemVersion(c.version)
+		check(t, err)
+
+		assert.Equal(t, c.expected, sc.check(v), "%v satisfies %v", c.version, c.constraint)
+	}
+}
+
+func TestInvalidSemConstraint(t *testing.T) {
+	_, err := parseSemConstraint(">=1.x")
+
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidVersionConstraint, ">=1.x"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
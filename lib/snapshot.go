@@ -0,0 +1,103 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"encoding/json"
+	"io"
+
+	yaml "github.com/go-yaml/yaml"
+	"github.com/mbtproject/mbt/e"
+)
+
+// Save writes a snapshot of the modules (including their specs,
+// versions and dependencies) to w as a json ModuleGraph document.
+// Modules required by the ones in the list should be in the list as
+// well (e.g. all modules discovered in a commit) so that the graph
+// can be loaded with LoadModules.
+func (l Modules) Save(w io.Writer) error {
+	g := &ModuleGraph{
+		TypeMeta: TypeMeta{APIVersion: APIVersion, Kind: KindModuleGraph},
+		Modules:  make([]*ModuleSnapshot, 0, len(l)),
+	}
+
+	for _, m := range l {
+		spec, err := yaml.Marshal(m.metadata.spec)
+		if err != nil {
+			return e.Wrap(ErrClassInternal, err)
+		}
+
+		g.Modules = append(g.Modules, &ModuleSnapshot{
+			Name:                 m.Name(),
+			Path:                 m.Path(),
+			Hash:                 m.Hash(),
+			Version:              m.Version(),
+			Spec:                 string(spec),
+			RawProperties:        m.metadata.spec.rawProperties,
+			FileDependencyHashes: m.metadata.dependentFileHashes,
+		})
+	}
+
+	if err := json.NewEncoder(w).Encode(g); err != nil {
+		return e.Wrap(ErrClassUser, err)
+	}
+
+	return nil
+}
+
+// LoadModules restores the modules from a snapshot written by
+// Modules.Save. Modules are linked with their requires and requiredBy
+// dependencies and returned in topological order with the versions
+// they had when the snapshot was taken.
+func LoadModules(r io.Reader) (Modules, error) {
+	g := &ModuleGraph{}
+	if err := json.NewDecoder(r).Decode(g); err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgFailedLoadModules)
+	}
+
+	if g.Kind != KindModuleGraph || g.APIVersion != APIVersion {
+		return nil, e.NewErrorf(ErrClassUser, msgUnexpectedDocument, KindModuleGraph, APIVersion, g.Kind, g.APIVersion)
+	}
+
+	set := make(moduleMetadataSet, 0, len(g.Modules))
+	versions := make(map[string]string)
+	for _, s := range g.Modules {
+		spec, err := newSpec([]byte(s.Spec))
+		if err != nil {
+			return nil, e.Wrapf(ErrClassUser, err, msgFailedLoadModules)
+		}
+
+		spec.rawProperties = s.RawProperties
+		if spec.rawProperties == nil {
+			spec.rawProperties = make(map[string]string)
+		}
+
+		set = append(set, newModuleMetadata(s.Path, s.Hash, spec, s.FileDependencyHashes))
+		versions[spec.Name] = s.Version
+	}
+
+	mods, err := linkModules(set, VersionHashSHA1)
+	if err != nil {
+		return nil, err
+	}
+
+	// Versions could be calculated with a different algorithm.
+	for _, m := range mods {
+		m.version = versions[m.Name()]
+	}
+
+	return mods, nil
+}
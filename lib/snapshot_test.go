@@ -0,0 +1,118 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveAndLoadModules(t *testing.T) {
+	specC, err := newSpec([]byte("name: app-c\ndependencies: [lib-a, lib-b]\nproperties:\n  version: 01.10\n  nested:\n    foo: [1, 2]\n"))
+	check(t, err)
+
+	mods, err := toModulesWithVersionHash(moduleMetadataSet{
+		newModuleMetadata("lib-a", "a", &Spec{
+			Name:       "lib-a",
+			Build:      map[string]*Cmd{"default": {Cmd: "make", Args: []string{"build"}, SuccessCodes: []int{0, 3}}},
+			Properties: map[string]interface{}{"team": "core", "port": 8080},
+		}, nil),
+		newModuleMetadata("libs/lib-b", "b", &Spec{
+			Name:             "lib-b",
+			Dependencies:     []string{"lib-a"},
+			FileDependencies: []string{"scripts/build.sh"},
+			Commands:         map[string]*UserCmd{"deploy": {Cmd: "deploy.sh", OS: []string{"linux"}}},
+			Properties:       map[string]interface{}{},
+		}, map[string]string{"scripts/build.sh": "s"}),
+		newModuleMetadata("app-c", "c", specC, nil),
+	}, VersionHashSHA256)
+	check(t, err)
+
+	buff := new(bytes.Buffer)
+	check(t, mods.Save(buff))
+
+	loaded, err := LoadModules(buff)
+	check(t, err)
+
+	assert.Equal(t, mods.names(), loaded.names())
+	l := loaded.indexByName()
+	for _, m := range mods {
+		other := l[m.Name()]
+		assert.True(t, m.Equal(other), m.Name())
+		assert.Equal(t, m.Path(), other.Path())
+		assert.Equal(t, m.Hash(), other.Hash())
+		assert.Equal(t, m.Version(), other.Version())
+		assert.Equal(t, m.Properties(), other.Properties())
+		assert.ElementsMatch(t, m.FileDependencies(), other.FileDependencies())
+		assert.Equal(t, m.Requires().names(), other.Requires().names())
+		assert.Equal(t, m.RequiredBy().names(), other.RequiredBy().names())
+	}
+
+	assert.True(t, strings.HasPrefix(l["app-c"].Version(), "sha256-"))
+	raw, ok := l["app-c"].RawProperty("version")
+	assert.True(t, ok)
+	assert.Equal(t, "01.10", raw)
+	assert.Equal(t, map[string]string{"scripts/build.sh": "s"}, l["lib-b"].metadata.dependentFileHashes)
+}
+
+func TestSaveAndLoadEmptyModules(t *testing.T) {
+	buff := new(bytes.Buffer)
+	check(t, Modules{}.Save(buff))
+
+	loaded, err := LoadModules(buff)
+	check(t, err)
+	assert.Len(t, loaded, 0)
+}
+
+func TestLoadModulesFromUnexpectedDocument(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a"}, nil),
+	})
+	check(t, err)
+
+	buff := new(bytes.Buffer)
+	check(t, mods.WriteManifest(buff, false))
+
+	_, err = LoadModules(buff)
+
+	assert.EqualError(t, err, fmt.Sprintf(msgUnexpectedDocument, KindModuleGraph, APIVersion, KindModuleManifest, APIVersion))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+
+	_, err = LoadModules(strings.NewReader("{"))
+
+	assert.EqualError(t, err, msgFailedLoadModules)
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestLoadModulesWithMissingDependency(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"lib-a"}}, nil),
+		newModuleMetadata("lib-a", "l", &Spec{Name: "lib-a"}, nil),
+	})
+	check(t, err)
+
+	buff := new(bytes.Buffer)
+	check(t, mods.Filter(func(m *Module) bool { return m.Name() == "app-a" }).Save(buff))
+
+	_, err = LoadModules(buff)
+
+	assert.EqualError(t, err, "dependency not found app-a -> lib-a")
+}
@@ -16,8 +16,10 @@ limitations under the License.
 package lib
 
 import (
+	"fmt"
 	"io"
 	"os"
+	"sync"
 )
 
 // This file defines the interfaces and types that make up MBT system.
@@ -49,6 +51,9 @@ type DiffDelta struct {
 // BlobWalkCallback used for discovering blobs in a commit tree.
 type BlobWalkCallback func(Blob) error
 
+// CommitWalkCallback used for visiting the commits in the history.
+type CommitWalkCallback func(Commit) error
+
 // Commit in the repo.
 // All commit based APIs in Repo interface accepts this interface.
 // It gives the implementations the ability to optimise the access to commits.
@@ -56,6 +61,14 @@ type BlobWalkCallback func(Blob) error
 type Commit interface {
 	ID() string
 	String() string
+	// Author returns the author of the commit in "name <email>" form.
+	Author() string
+	// AuthorEmail returns the email address of the author of the commit.
+	AuthorEmail() string
+	// Message returns the commit message.
+	Message() string
+	// ParentCount returns the number of parents of the commit.
+	ParentCount() int
 }
 
 // Reference to a tree in the repository.
@@ -70,6 +83,19 @@ type Reference interface {
 type Repo interface {
 	// GetCommit returns the commit object for the specified SHA.
 	GetCommit(sha string) (Commit, error)
+	// ResolveCommit is similar to GetCommit except it also accepts
+	// abbreviated SHAs (at least 4 characters).
+	ResolveCommit(sha string) (Commit, error)
+	// ResolveRevision returns the commit referred by the specified
+	// revision expression (e.g. a sha, HEAD~2, HEAD^ or master~1).
+	ResolveRevision(rev string) (Commit, error)
+	// LatestTag returns the name and the commit of the highest
+	// semantic version tag reachable from the specified commit
+	// that satisfies the version constraint (e.g. >=1.0.0, <2.0.0).
+	// Tags with prerelease versions (e.g. v1.0.0-rc1) are ignored
+	// unless the constraint refers to a prerelease.
+	// Tags that are not semantic versions are always ignored.
+	LatestTag(commit Commit, constraint string) (string, Commit, error)
 	// Path of the repository.
 	Path() string
 	// Diff gets the diff between two commits.
@@ -77,9 +103,17 @@ type Repo interface {
 	// DiffMergeBase gets the diff between the merge base of from and to and, to.
 	// In other words, diff contains the deltas of changes occurred in 'to' commit tree
 	// since it diverged from 'from' commit tree.
+	// Diff is computed between the two trees rather than by walking the
+	// commits in between, therefore, changes brought in by merge commits
+	// are included exactly once.
 	DiffMergeBase(from, to Commit) ([]*DiffDelta, error)
+	// PatchMergeBase gets the unified diff text of the changes in DiffMergeBase
+	// restricted to the specified file or directory paths. All changes are
+	// included when paths is empty.
+	PatchMergeBase(from, to Commit, paths []string) (string, error)
 	// DiffWorkspace gets the changes in current workspace.
-	// This should include untracked changes.
+	// This should include untracked changes unless RepoOptions.TrackedOnly
+	// is set.
 	DiffWorkspace() ([]*DiffDelta, error)
 	// Changes returns a an array of DiffDelta objects representing the changes
 	// in the specified commit.
@@ -88,10 +122,39 @@ type Repo interface {
 	Changes(c Commit) ([]*DiffDelta, error)
 	// WalkBlobs invokes the callback for each blob reachable from the commit tree.
 	WalkBlobs(a Commit, callback BlobWalkCallback) error
+	// WalkBlobsUnder invokes the callback for each blob reachable from
+	// the commit tree under any of the specified paths.
+	// Trees outside those paths are not visited.
+	// All blobs are visited if paths is empty.
+	WalkBlobsUnder(a Commit, paths []string, callback BlobWalkCallback) error
+	// WalkBlobsExcluding is similar to WalkBlobsUnder except the trees
+	// at excluded paths and the trees nested in more than maxDepth
+	// directories from the root are not visited. Trees at any depth
+	// are visited when maxDepth is UnlimitedDiscoveryDepth.
+	WalkBlobsExcluding(a Commit, paths, excluded []string, maxDepth int, callback BlobWalkCallback) error
+	// WalkGitlinksUnder invokes the callback for each submodule pointer
+	// (gitlink) in the commit tree under any of the specified paths.
+	// ID of the entries passed to the callback is the id of the
+	// submodule commit.
+	WalkGitlinksUnder(a Commit, paths []string, callback BlobWalkCallback) error
+	// WalkCommits invokes the callback for each commit reachable from
+	// the specified commit, including itself.
+	// Parents are visited before their children.
+	WalkCommits(commit Commit, callback CommitWalkCallback) error
+	// WalkCommitsInRange invokes the callback for each commit reachable
+	// from 'to' but not from 'from' (i.e. from..to).
+	// Parents are visited before their children.
+	WalkCommitsInRange(from, to Commit, callback CommitWalkCallback) error
 	// BlobContents of specified blob.
 	BlobContents(blob Blob) ([]byte, error)
+	// BlobSize returns the size of specified blob in bytes.
+	// Blob contents are not loaded.
+	BlobSize(blob Blob) (int64, error)
 	// BlobContentsByPath gets the blob contents from a specific git tree.
 	BlobContentsFromTree(commit Commit, path string) ([]byte, error)
+	// TreeBlobContents gets the contents of the blob at the specified
+	// path relative to the tree object identified by treeID.
+	TreeBlobContents(treeID, path string) ([]byte, error)
 	// EntryID of a git object in path.
 	// ID is resolved from the commit tree of the specified commit.
 	EntryID(commit Commit, path string) (string, error)
@@ -101,9 +164,18 @@ type Repo interface {
 	CurrentBranch() (string, error)
 	// CurrentBranchCommit returns the last commit for the current branch.
 	CurrentBranchCommit() (Commit, error)
+	// HeadCommit returns the commit pointed by HEAD.
+	// HEAD could be either symbolic or detached.
+	HeadCommit() (Commit, error)
 	// IsEmpty informs if the current repository is empty or not.
 	IsEmpty() (bool, error)
-	// FindAllFilesInWorkspace returns all files in repository matching given pathSpec, including untracked files.
+	// IsDetached informs if HEAD is detached.
+	IsDetached() (bool, error)
+	// IsDirty informs if the workspace has uncommitted changes
+	// including untracked files.
+	IsDirty() (bool, error)
+	// FindAllFilesInWorkspace returns all files in repository matching given pathSpec, including untracked files
+	// unless RepoOptions.TrackedOnly is set.
 	FindAllFilesInWorkspace(pathSpec []string) ([]string, error)
 	// EnsureSafeWorkspace returns an error workspace is in a safe state
 	// for operations requiring a checkout.
@@ -116,33 +188,128 @@ type Repo interface {
 	// CheckoutReference checks out the specified reference into workspace.
 	CheckoutReference(Reference) error
 	// MergeBase returns the merge base of two commits.
+	// See RepoOptions.FirstParentMergeBase for the alternative
+	// way of finding the merge base.
 	MergeBase(a, b Commit) (Commit, error)
+	// Note returns the contents of the note attached to the commit
+	// under the specified notes reference.
+	// Returns nil if the commit does not have a note.
+	Note(ref string, commit Commit) ([]byte, error)
+	// WriteNote attaches a note to the commit under the specified notes
+	// reference. Existing note is overwritten.
+	WriteNote(ref string, commit Commit, content []byte) error
 }
 
 /** Module Discovery **/
 
 // Cmd represents the structure of a command appears in .mbt.yml.
+// SuccessCodes and SkipCodes declare the exit codes classified as
+// success and skipped. When SuccessCodes is empty, only 0 is a success.
 type Cmd struct {
-	Cmd  string
-	Args []string `yaml:",flow"`
+	Cmd          string
+	Args         []string `yaml:",flow"`
+	SuccessCodes []int    `yaml:"successCodes,flow,omitempty"`
+	SkipCodes    []int    `yaml:"skipCodes,flow,omitempty"`
 }
 
 // UserCmd represents the structure of a user defined command in .mbt.yml
 type UserCmd struct {
-	Cmd  string
-	Args []string `yaml:",flow"`
-	OS   []string `yaml:"os"`
+	Cmd          string
+	Args         []string `yaml:",flow"`
+	OS           []string `yaml:"os"`
+	SuccessCodes []int    `yaml:"successCodes,flow,omitempty"`
+	SkipCodes    []int    `yaml:"skipCodes,flow,omitempty"`
 }
 
+// CmdResult is an enum to indicate the outcome of a command
+// classified by its exit code.
+type CmdResult = int
+
+const (
+	// CmdResultSuccess is when the command exits with a success code
+	CmdResultSuccess = iota
+
+	// CmdResultSkipped is when the command exits with a skip code
+	CmdResultSkipped
+
+	// CmdResultFailed is when the command exits with any other code
+	// or cannot be started
+	CmdResultFailed
+)
+
 // Spec represents the structure of .mbt.yml contents.
 type Spec struct {
-	Name             string                 `yaml:"name"`
+	Name string `yaml:"name"`
+	// ID is an optional immutable identifier of the module that
+	// survives renames. Defaults to Name.
+	ID               string                 `yaml:"id"`
 	Build            map[string]*Cmd        `yaml:"build"`
 	Commands         map[string]*UserCmd    `yaml:"commands"`
 	Properties       map[string]interface{} `yaml:"properties"`
 	Dependencies     []string               `yaml:"dependencies"`
 	FileDependencies []string               `yaml:"fileDependencies"`
 	PeerDependencies []string               `yaml:"peerDependencies"`
+	Resource         string                 `yaml:"resource"`
+	// Weight is the share of CmdOptions.MaxParallel used by the
+	// module while it is built in parallel. Defaults to 1.
+	Weight int `yaml:"weight"`
+	// VersionExtensions restricts the files contributing to the
+	// module version to the ones with these extensions.
+	VersionExtensions []string `yaml:"versionExtensions"`
+	// EnvFile is loaded into the environment of the commands
+	// executed for the module.
+	EnvFile *EnvFile `yaml:"envFile"`
+	// Deprecated modules are reported with a warning when they
+	// are built or impacted by a diff.
+	Deprecated bool `yaml:"deprecated"`
+	// DeprecationMessage is included in the deprecation warning.
+	DeprecationMessage string `yaml:"deprecationMessage"`
+	// ConflictsWith is a list of modules that must not be impacted
+	// by the same change as this module.
+	ConflictsWith []string `yaml:"conflictsWith"`
+	// Artifacts are the files expected to be produced by the build.
+	Artifacts []*Artifact `yaml:"artifacts"`
+	// EnabledWhen is a boolean expanded with the environment variables
+	// (e.g. ${FEATURE_X}). Module is omitted from discovery when it
+	// expands to false or an empty value.
+	EnabledWhen string `yaml:"enabledWhen"`
+	// ExternalDependencies are the names of the modules in other
+	// repositories required by this module. They are ignored when
+	// discovering a single repository and resolved by MergeGraphs.
+	ExternalDependencies []string `yaml:"externalDependencies"`
+	// DependenciesFile is the path (relative to the module directory)
+	// to a file listing additional dependencies, one per line
+	// (e.g. generated from the lock file of a build system). They are
+	// merged into Dependencies during discovery and the file is treated
+	// as a file dependency.
+	DependenciesFile string `yaml:"dependenciesFile"`
+	// Inherit is the list of property keys inherited from the parent
+	// module when they are not defined in this module.
+	Inherit []string `yaml:"inherit"`
+	// Parent is the dependency to inherit the properties from. It can
+	// be omitted when the module has a single dependency.
+	Parent string `yaml:"parent"`
+
+	// rawProperties are the scalar properties as written in the spec.
+	rawProperties map[string]string
+}
+
+// Artifact represents a build output declared in .mbt.yml.
+type Artifact struct {
+	// Path of the file relative to the module directory.
+	Path string `yaml:"path"`
+	// SHA256 is the expected checksum of the file in hex.
+	// Checksum is not verified when empty.
+	SHA256 string `yaml:"sha256"`
+}
+
+// EnvFile represents an env file declared in .mbt.yml.
+type EnvFile struct {
+	// Path of the file relative to the module directory.
+	// The file must be in the module directory.
+	Path string `yaml:"path"`
+	// Required causes the commands to fail if the file does not exist.
+	Required bool `yaml:"required"`
 }
 
 // Module represents a single module in the repository.
@@ -156,6 +323,12 @@ type Module struct {
 // Modules is an array of Module.
 type Modules []*Module
 
+// StagedModule is a module along with the index of its build stage.
+type StagedModule struct {
+	Module *Module
+	Stage  int
+}
+
 // Discover module metadata for various conditions
 type Discover interface {
 	// ModulesInCommit walks the git tree at a specific commit looking for
@@ -164,6 +337,15 @@ type Discover interface {
 	// ModulesInWorkspace walks current workspace looking for
 	// directories with .mbt.yml file. Returns discovered Modules.
 	ModulesInWorkspace() (Modules, error)
+	// ModuleAtTree returns the module defined by the .mbt.yml file at the
+	// root of the specified tree object. Its version is VersionUnresolved
+	// if it depends on anything outside the tree.
+	ModuleAtTree(treeID string) (*Module, error)
+	// ModulesInCommitSince discovers the modules in the commit reusing
+	// the modules in prior list when their directory is not changed.
+	// Only the directories containing changes are walked. Result is
+	// same as ModulesInCommit.
+	ModulesInCommitSince(commit Commit, prior Modules) (Modules, error)
 }
 
 // Reducer reduces a given modules set to impacted set from a diff delta
@@ -171,11 +353,77 @@ type Reducer interface {
 	Reduce(modules Modules, deltas []*DiffDelta) (Modules, error)
 }
 
+// RepoState describes the state of the repository at the time
+// a manifest is created.
+type RepoState struct {
+	// Sha of the commit pointed by HEAD.
+	Sha string
+	// Branch checked out. Empty when HEAD is detached.
+	Branch string
+	// Detached is true if HEAD is detached.
+	Detached bool
+
+	repo      Repo
+	dirtyOnce sync.Once
+	dirty     bool
+	dirtyErr  error
+}
+
+// IsDirty returns true if the workspace has uncommitted changes.
+// Workspace status is expensive to scan, therefore it is read when
+// this is called for the first time and the result is reused.
+func (s *RepoState) IsDirty() (bool, error) {
+	s.dirtyOnce.Do(func() {
+		if s.repo != nil {
+			s.dirty, s.dirtyErr = s.repo.IsDirty()
+		}
+	})
+
+	return s.dirty, s.dirtyErr
+}
+
+// AffectedWalkCallback receives the modules affected by a commit.
+type AffectedWalkCallback func(commit Commit, mods Modules) error
+
+// CommitInfo describes the commit a manifest is created for.
+type CommitInfo struct {
+	Sha     string
+	Author  string
+	Message string
+}
+
+// ChangeKind describes why a module is impacted by a change.
+type ChangeKind = int
+
+const (
+	// DirectlyChanged modules have changes in their own content
+	// or file dependencies.
+	DirectlyChanged ChangeKind = iota + 1
+	// DependencyChanged modules are impacted only because a module
+	// they depend on is changed.
+	DependencyChanged
+	// CommandChanged modules have changes only in the commands of
+	// their spec, which do not impact the modules requiring them.
+	// See ManifestBuilderOptions.IsolateCommandChanges.
+	CommandChanged
+)
+
 // Manifest represents a collection modules in the repository.
 type Manifest struct {
 	Dir     string
 	Sha     string
 	Modules Modules
+	State   *RepoState
+	// Commit is the commit at the end of the diff for the manifests
+	// created from a diff. Nil otherwise.
+	Commit *CommitInfo
+	// Changes classifies the modules in manifests created from
+	// changes (e.g. diff between two commits) by their name.
+	// Nil for the manifests containing all modules.
+	Changes map[string]ChangeKind
+	// deprecationsReported is true when the deprecated modules in the
+	// manifest are already reported (e.g. for the result of a diff).
+	deprecationsReported bool
 }
 
 // ManifestBuilder builds Manifest for various conditions
@@ -235,7 +483,7 @@ type BuildSummary struct {
 	// host platform.
 	Completed []*BuildResult
 	// Skipped modules due to the unavailability of a build command for
-	// the host platform
+	// the host platform or a build command exiting with a skip code
 	Skipped []*Module
 }
 
@@ -243,6 +491,9 @@ type BuildSummary struct {
 type BuildResult struct {
 	// Module of the build result
 	Module *Module
+	// Artifacts maps the path of each artifact declared by the module
+	// to the SHA256 checksum of the file produced by the build.
+	Artifacts map[string]string
 }
 
 const (
@@ -253,6 +504,7 @@ const (
 	CmdStageAfterBuild
 
 	// CmdStageSkipBuild is when module building is skipped due to lack of matching building command
+	// or the command exiting with a skip code
 	CmdStageSkipBuild
 
 	// CmdStageFailedBuild is when module command is failed
@@ -269,6 +521,11 @@ type FilterOptions struct {
 	Name       string
 	Fuzzy      bool
 	Dependents bool
+	// BuildableOnly excludes the modules that cannot be built in
+	// current operating system (e.g. libraries without a build command).
+	// It is applied after expanding dependents so that a change in a
+	// library is represented by the modules requiring it.
+	BuildableOnly bool
 }
 
 // CmdOptions defines various options required by methods executing
@@ -278,6 +535,18 @@ type CmdOptions struct {
 	Stdout, Stderr io.Writer
 	Callback       CmdStageCallback
 	FailFast       bool
+	// MaxParallel is the maximum total weight (see Module.Weight) of
+	// the modules built concurrently, which is the number of modules
+	// when none of them declares a weight.
+	// Modules are built one at a time when this is less than 2.
+	MaxParallel int
+	// OutputPrefix returns the prefix written at the beginning of each
+	// line of the output of a module. Output is not prefixed when nil.
+	OutputPrefix func(mod *Module) string
+	// ExtraArgs are appended to the resolved build command of each
+	// module. They are invocation time overrides and therefore do not
+	// contribute to the version of a module.
+	ExtraArgs []string
 }
 
 // CmdFailure contains the failures occurred while running a user defined command.
@@ -292,6 +561,8 @@ type RunResult struct {
 	Completed []*Module
 	Skipped   []*Module
 	Failures  []*CmdFailure
+	// Summary of the run suitable for serialisation.
+	Summary *RunSummary
 }
 
 // System is the interface used by users to invoke the core functionality
@@ -324,6 +595,10 @@ type System interface {
 	// Build builds changes between two commits
 	BuildDiff(from, to string, options *CmdOptions) (*BuildSummary, error)
 
+	// BuildSinceTag builds the manifest for diff between the latest tag
+	// satisfying the version constraint and the specified commit.
+	BuildSinceTag(constraint, to string, options *CmdOptions) (*BuildSummary, error)
+
 	// BuildCurrentBranch builds the current branch.
 	// This function accepts FilterOptions to specify which modules to be built
 	// within that branch.
@@ -359,9 +634,61 @@ type System interface {
 	// between M and first and M and second.
 	IntersectionByBranch(first, second string) (Modules, error)
 
-	// ManifestByDiff creates the manifest for diff between two commits
+	// BlastRadius returns the modules that would be built if the
+	// specified file is changed in the commit. That is the module
+	// owning the file (or depending on it via fileDependencies) and
+	// the modules in their requiredBy dependency chain.
+	BlastRadius(commit, filePath string) (Modules, error)
+
+	// ModulesChangedByAuthor returns the modules changed by the commits
+	// authored by the specified email address between from (exclusive)
+	// and to (inclusive) commits. Email addresses are compared case
+	// insensitively. Merge commits are not considered.
+	ModulesChangedByAuthor(from, to, authorEmail string) (Modules, error)
+
+	// ModulesAtCommit returns the modules in the specified commit.
+	// Commit can be specified with an abbreviated SHA.
+	ModulesAtCommit(sha string) (Modules, error)
+
+	// IncrementalDescribe returns the modules in the specified commit.
+	// Modules in prior list (e.g. discovered in a previous commit) are
+	// reused if their directory is not changed. Only the remaining
+	// parts of the tree are searched for modules.
+	IncrementalDescribe(commit string, prior Modules) (Modules, error)
+
+	// ModuleDiff returns the unified diff text of the changes to the
+	// specified module between the merge base of from and to commits
+	// and to. Diff is restricted to the files in the module directory.
+	ModuleDiff(from, to, name string) (string, error)
+
+	// DiffCommits compares the modules in from and to commits by name.
+	// Modules present in from but not in to (e.g. after deleting
+	// their directories) are listed in the Removed set of the delta.
+	DiffCommits(from, to string) (*ModulesDelta, error)
+
+	// ModuleFileStats returns the number of files and their total size
+	// in bytes in the directory of the specified module as of the
+	// specified commit. Files are read from the commit tree without
+	// checking them out.
+	ModuleFileStats(commit, name string) (int, int64, error)
+
+	// WalkAffected invokes the callback for each commit in the specified
+	// branch with the modules affected by that commit relative to its
+	// first parent (including the modules requiring them).
+	// All modules are considered affected by the commits without a parent.
+	// Parents are visited before their children.
+	WalkAffected(branch string, callback AffectedWalkCallback) error
+
+	// ManifestByDiff creates the manifest for diff between two commits.
+	// Commits can be specified with revision expressions such as
+	// HEAD~1 (see Repo.ResolveRevision).
 	ManifestByDiff(from, to string) (*Manifest, error)
 
+	// ManifestSinceTag creates the manifest for diff between the latest
+	// tag satisfying the version constraint (see Repo.LatestTag) and
+	// the specified commit.
+	ManifestSinceTag(constraint, to string) (*Manifest, error)
+
 	// ManifestByPr creates the manifest for diff between two branches
 	ManifestByPr(src, dst string) (*Manifest, error)
 
@@ -383,6 +710,22 @@ type System interface {
 	// ByWorkspaceChanges creates the manifest for the changes in workspace
 	ManifestByWorkspaceChanges() (*Manifest, error)
 
+	// ModulesAtHead returns all modules in the commit pointed by HEAD.
+	// HEAD could be either symbolic or detached.
+	ModulesAtHead() (Modules, error)
+
+	// RepoState returns the current state of the repository.
+	RepoState() (*RepoState, error)
+
+	// ReadBuildNote reads the build note attached to the specified commit
+	// under the given namespace.
+	// Returns nil if the commit does not have a build note.
+	ReadBuildNote(namespace, commit string) (*BuildNote, error)
+
+	// WriteBuildNote records the fingerprint and the module versions of
+	// a manifest as a note attached to the manifest commit.
+	WriteBuildNote(namespace string, manifest *Manifest) error
+
 	// RunInBranch runs a command in a branch.
 	// This function accepts FilterOptions to specify a subset of modules.
 	RunInBranch(command, name string, filterOptions *FilterOptions, options *CmdOptions) (*RunResult, error)
@@ -395,6 +738,10 @@ type System interface {
 	// commit since it diverged from 'to' commit.
 	RunInDiff(command, from, to string, options *CmdOptions) (*RunResult, error)
 
+	// RunInSinceTag runs a command in modules changed between the latest
+	// tag satisfying the version constraint and the specified commit.
+	RunInSinceTag(command, constraint, to string, options *CmdOptions) (*RunResult, error)
+
 	// RunInCurrentBranch runs a command in modules in the current branch.
 	// This function accepts FilterOptions to filter the modules included in this
 	// operation.
@@ -427,16 +774,95 @@ type stdSystem struct {
 	ProcessManager   ProcessManager
 }
 
+// SystemOptions specifies the options used to create a System.
+type SystemOptions struct {
+	// Roots restricts discovery and diff reduction to these
+	// repository relative paths. Entire repository is considered
+	// when Roots is empty.
+	Roots []string
+	// Scope restricts the changes considered in diffs to these
+	// repository relative paths. See ReducerOptions.
+	Scope []string
+	// Excludes is a list of glob patterns for the changes that
+	// should not mark a module as changed. See ReducerOptions.
+	Excludes []string
+	// MaxFanOut is the maximum number of modules a single change can
+	// impact. See ManifestBuilderOptions.
+	MaxFanOut int
+	// StrictFanOut fails when MaxFanOut is exceeded instead of warning.
+	StrictFanOut bool
+	// IsolateCommandChanges does not impact the modules requiring a
+	// module with changes only in its commands. See ManifestBuilderOptions.
+	IsolateCommandChanges bool
+	// MaxImpactDepth limits the number of requiredBy dependencies
+	// followed from a changed module. See ManifestBuilderOptions.
+	MaxImpactDepth *int
+	// VersionHash is the algorithm used to calculate module versions.
+	// See DiscoverOptions.
+	VersionHash string
+	// MaxDiscoveryDepth is the maximum depth of the module directories.
+	// See DiscoverOptions.MaxDepth.
+	MaxDiscoveryDepth *int
+	// FirstParentMergeBase finds merge bases along the first parent
+	// history. See RepoOptions.
+	FirstParentMergeBase bool
+	// RenameThreshold is the similarity required to detect renames.
+	// See RepoOptions.
+	RenameThreshold int
+	// CopyThreshold is the similarity required to detect copies.
+	// See RepoOptions.
+	CopyThreshold int
+	// Transform is invoked for each spec found during discovery.
+	// See DiscoverOptions.
+	Transform SpecTransform
+	// Fetch deepens the history of a shallow clone when a merge base
+	// cannot be found. See RepoOptions.
+	Fetch FetchFunc
+	// TrackedOnly ignores the untracked files when discovering modules
+	// and changes in the workspace. See RepoOptions.
+	TrackedOnly bool
+	// Attribute replaces the default matching of changes to the
+	// modules. See ReducerOptions.
+	Attribute AttributionFunc
+}
+
 // NewSystem creates a new instance of core mbt system
 func NewSystem(path string, logLevel int) (System, error) {
+	return NewSystemWithOptions(path, logLevel, &SystemOptions{})
+}
+
+// NewSystemWithOptions creates a new instance of core mbt system
+// with the specified options.
+func NewSystemWithOptions(path string, logLevel int, options *SystemOptions) (System, error) {
 	log := NewStdLog(logLevel)
-	repo, err := NewLibgitRepo(path, log)
+	repo, err := NewLibgitRepoWithOptions(path, log, &RepoOptions{
+		FirstParentMergeBase: options.FirstParentMergeBase,
+		RenameThreshold:      options.RenameThreshold,
+		CopyThreshold:        options.CopyThreshold,
+		Fetch:                options.Fetch,
+		TrackedOnly:          options.TrackedOnly,
+	})
 	if err != nil {
 		return nil, err
 	}
-	discover := NewDiscover(repo, log)
-	reducer := NewReducer(log)
-	mb := NewManifestBuilder(repo, reducer, discover, log)
+	discover := NewDiscoverWithOptions(repo, log, &DiscoverOptions{
+		Roots:       options.Roots,
+		VersionHash: options.VersionHash,
+		Transform:   options.Transform,
+		MaxDepth:    options.MaxDiscoveryDepth,
+	})
+	reducer := NewReducerWithOptions(log, &ReducerOptions{
+		Roots:     options.Roots,
+		Scope:     options.Scope,
+		Excludes:  options.Excludes,
+		Attribute: options.Attribute,
+	})
+	mb := NewManifestBuilderWithOptions(repo, reducer, discover, log, &ManifestBuilderOptions{
+		MaxFanOut:             options.MaxFanOut,
+		StrictFanOut:          options.StrictFanOut,
+		IsolateCommandChanges: options.IsolateCommandChanges,
+		MaxImpactDepth:        options.MaxImpactDepth,
+	})
 	wm := NewWorkspaceManager(log, repo)
 	pm := NewProcessManager(log)
 	return initSystem(log, repo, mb, discover, reducer, wm, pm), nil
@@ -481,6 +907,12 @@ func (s *stdSystem) ManifestBuilder() ManifestBuilder {
 	return s.MB
 }
 
+// ModuleNamePrefix is an OutputPrefix function that prefixes the
+// output with the name of the module.
+func ModuleNamePrefix(mod *Module) string {
+	return fmt.Sprintf("[%s] ", mod.Name())
+}
+
 // CmdOptionsWithStdIO creates an instance of CmdOptions with
 // its streams pointing to std io streams.
 func CmdOptionsWithStdIO(callback CmdStageCallback) *CmdOptions {
@@ -0,0 +1,192 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	git "github.com/libgit2/git2go/v28"
+	"github.com/mbtproject/mbt/e"
+)
+
+// This file contains the functions to calculate git object ids
+// for the content stored in a file system. They are used to produce
+// module versions compatible with the ones calculated from git trees
+// when the repository history is not available.
+
+type treeEntry struct {
+	mode string
+	name string
+	id   []byte
+}
+
+// hashPath returns the git object id of the file or directory at
+// the specified path.
+func hashPath(p string) (string, error) {
+	fi, err := os.Lstat(p)
+	if err != nil {
+		return "", e.Wrapf(ErrClassUser, err, msgFailedLocalPath, p)
+	}
+
+	var id []byte
+	if fi.IsDir() {
+		id, err = hashTree(p)
+	} else {
+		id, _, err = hashFile(p, fi)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(id), nil
+}
+
+// hashTree returns the id of the git tree object for the
+// specified directory. Returns nil if the directory does not
+// contain any files, because git does not store empty trees.
+func hashTree(dir string) ([]byte, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgFailedLocalPath, dir)
+	}
+
+	entries := make([]*treeEntry, 0, len(infos))
+	for _, fi := range infos {
+		p := filepath.Join(dir, fi.Name())
+		if fi.IsDir() {
+			if fi.Name() == ".git" {
+				continue
+			}
+
+			// Checked out submodules are stored as gitlinks.
+			id, ok, err := hashGitlink(p)
+			if err != nil {
+				return nil, err
+			}
+
+			if ok {
+				entries = append(entries, &treeEntry{mode: "160000", name: fi.Name(), id: id})
+				continue
+			}
+
+			id, err = hashTree(p)
+			if err != nil {
+				return nil, err
+			}
+
+			if id != nil {
+				entries = append(entries, &treeEntry{mode: "40000", name: fi.Name(), id: id})
+			}
+			continue
+		}
+
+		id, mode, err := hashFile(p, fi)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, &treeEntry{mode: mode, name: fi.Name(), id: id})
+	}
+
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	// Git sorts tree entries by name where the names of
+	// sub trees are suffixed with a /.
+	sortKey := func(t *treeEntry) string {
+		if t.mode == "40000" {
+			return t.name + "/"
+		}
+		return t.name
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return sortKey(entries[i]) < sortKey(entries[j])
+	})
+
+	buff := new(bytes.Buffer)
+	for _, t := range entries {
+		fmt.Fprintf(buff, "%s %s\x00", t.mode, t.name)
+		buff.Write(t.id)
+	}
+
+	return hashObject("tree", buff.Bytes()), nil
+}
+
+// hashGitlink returns the id of the commit checked out in the
+// specified directory if it is the working directory of a submodule
+// (i.e. it contains a .git file or directory).
+func hashGitlink(dir string) ([]byte, bool, error) {
+	if _, err := os.Lstat(filepath.Join(dir, ".git")); err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, e.Wrapf(ErrClassUser, err, msgFailedLocalPath, dir)
+	}
+
+	repo, err := git.OpenRepository(dir)
+	if err != nil {
+		return nil, false, e.Wrapf(ErrClassUser, err, msgFailedLocalPath, dir)
+	}
+	defer repo.Free()
+
+	head, err := repo.Head()
+	if err != nil {
+		return nil, false, e.Wrapf(ErrClassUser, err, msgFailedLocalPath, dir)
+	}
+	defer head.Free()
+
+	id := head.Target()
+	return id[:], true, nil
+}
+
+// hashFile returns the id of the git blob object for the
+// specified file along with the mode git would use for it.
+func hashFile(p string, fi os.FileInfo) ([]byte, string, error) {
+	if fi.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(p)
+		if err != nil {
+			return nil, "", e.Wrapf(ErrClassUser, err, msgFailedReadFile, p)
+		}
+		return hashObject("blob", []byte(filepath.ToSlash(target))), "120000", nil
+	}
+
+	content, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, "", e.Wrapf(ErrClassUser, err, msgFailedReadFile, p)
+	}
+
+	mode := "100644"
+	if fi.Mode()&0100 != 0 {
+		mode = "100755"
+	}
+
+	return hashObject("blob", content), mode, nil
+}
+
+func hashObject(kind string, content []byte) []byte {
+	h := sha1.New()
+	fmt.Fprintf(h, "%s %d\x00", kind, len(content))
+	h.Write(content)
+	return h.Sum(nil)
+}
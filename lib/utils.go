@@ -17,11 +17,14 @@ package lib
 
 import (
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 )
 
 // GitRepoRoot returns path to a git repo reachable from
-// the specified directory.
+// the specified directory. The repo could be either a
+// regular or a bare repository.
 // If the specified directory itself is not a git repo,
 // this function searches for it in the parent directory
 // path.
@@ -47,6 +50,10 @@ func GitRepoRoot(dir string) (string, error) {
 			return "", err
 		}
 
+		if isBareRepo(dir) {
+			return dir, nil
+		}
+
 		if dir == root {
 			return dir, nil
 		}
@@ -54,3 +61,120 @@ func GitRepoRoot(dir string) (string, error) {
 		dir = filepath.Dir(dir)
 	}
 }
+
+// isBareRepo returns true if the directory has the layout
+// of a bare git repository.
+func isBareRepo(dir string) bool {
+	head, err := os.Stat(filepath.Join(dir, "HEAD"))
+	if err != nil || head.IsDir() {
+		return false
+	}
+
+	objects, err := os.Stat(filepath.Join(dir, "objects"))
+	if err != nil || !objects.IsDir() {
+		return false
+	}
+
+	refs, err := os.Stat(filepath.Join(dir, "refs"))
+	return err == nil && refs.IsDir()
+}
+
+// normalizeRoots converts the specified paths to the form used
+// in git trees (forward slashes without leading or trailing slashes).
+// Paths referring to the repository root are dropped since they
+// do not restrict anything.
+func normalizeRoots(roots []string) []string {
+	var normalized []string
+	for _, r := range roots {
+		r = strings.Trim(filepath.ToSlash(filepath.Clean(r)), "/")
+		if r == "" || r == "." {
+			return nil
+		}
+		normalized = append(normalized, r)
+	}
+
+	return normalized
+}
+
+// isInRoots returns true if the path is one of the roots or
+// is under one of them.
+// Every path is considered to be in roots if roots is empty.
+func isInRoots(p string, roots []string) bool {
+	if len(roots) == 0 {
+		return true
+	}
+
+	for _, r := range roots {
+		if p == r || strings.HasPrefix(p, r+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isWithinDepth returns true if the directory is nested in at most
+// maxDepth directories from the repository root. Directories at any
+// depth are accepted when maxDepth is UnlimitedDiscoveryDepth.
+func isWithinDepth(dir string, maxDepth int) bool {
+	if maxDepth == UnlimitedDiscoveryDepth || dir == "" {
+		return true
+	}
+
+	return strings.Count(dir, "/")+1 <= maxDepth
+}
+
+// isTreeInRoots returns true if the tree at the path should be
+// visited in order to reach the contents of the roots.
+func isTreeInRoots(p string, roots []string) bool {
+	if isInRoots(p, roots) {
+		return true
+	}
+
+	for _, r := range roots {
+		if strings.HasPrefix(r, p+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stringSlicesEqual returns true if both slices contain the same
+// elements in the same order.
+func stringSlicesEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// containsInt returns true if the slice contains the specified value.
+func containsInt(s []int, v int) bool {
+	for _, i := range s {
+		if i == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cleanModulePath cleans the path of a file declared relative to
+// a module directory.
+// Returns false if the path is outside the module directory.
+func cleanModulePath(p string) (string, bool) {
+	c := path.Clean(filepath.ToSlash(p))
+	if path.IsAbs(c) || filepath.IsAbs(p) || c == ".." || strings.HasPrefix(c, "../") {
+		return "", false
+	}
+
+	return c, true
+}
@@ -90,3 +90,41 @@ func TestGitRepoRootForAbsPath(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, repoDir, path)
 }
+
+func TestRootsMatching(t *testing.T) {
+	roots := normalizeRoots([]string{"/services/", "libs/shared"})
+
+	assert.Equal(t, []string{"services", "libs/shared"}, roots)
+	assert.True(t, isInRoots("services/app-a/.mbt.yml", roots))
+	assert.True(t, isInRoots("libs/shared", roots))
+	assert.False(t, isInRoots("servicesx/app-a", roots))
+	assert.False(t, isInRoots("libs", roots))
+	assert.True(t, isTreeInRoots("libs", roots))
+	assert.False(t, isTreeInRoots("tools", roots))
+	assert.Nil(t, normalizeRoots([]string{"services", "."}))
+}
+
+func TestDepthMatching(t *testing.T) {
+	assert.True(t, isWithinDepth("", 1))
+	assert.True(t, isWithinDepth("app-a", 1))
+	assert.False(t, isWithinDepth("services/app-a", 1))
+	assert.True(t, isWithinDepth("services/app-a", 2))
+	assert.True(t, isWithinDepth("", 0))
+	assert.False(t, isWithinDepth("app-a", 0))
+	assert.True(t, isWithinDepth("services/nested/app-b", -1))
+}
+
+func TestCleanModulePath(t *testing.T) {
+	p, ok := cleanModulePath("dist/./app.tar")
+	assert.True(t, ok)
+	assert.Equal(t, "dist/app.tar", p)
+
+	p, ok = cleanModulePath("nested/../build.env")
+	assert.True(t, ok)
+	assert.Equal(t, "build.env", p)
+
+	for _, p := range []string{"..", "../build.env", "nested/../../build.env", "/etc/build.env"} {
+		_, ok = cleanModulePath(p)
+		assert.False(t, ok, p)
+	}
+}
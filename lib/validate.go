@@ -0,0 +1,171 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// propertyReference matches the references to module property
+// environment variables (e.g. $MBT_MODULE_PROPERTY_FOO, %MBT_MODULE_PROPERTY_FOO%).
+var propertyReference = regexp.MustCompile(`MBT_MODULE_PROPERTY_([A-Za-z0-9_]+)`)
+
+// ValidateOptions specifies the options used to validate modules.
+type ValidateOptions struct {
+	// FailFast stops the validation at the first error.
+	// Otherwise, all errors are collected into ValidationErrors.
+	FailFast bool
+}
+
+// ValidationErrors is a collection of errors found while validating
+// modules.
+type ValidationErrors []error
+
+func (v ValidationErrors) Error() string {
+	if len(v) == 1 {
+		return v[0].Error()
+	}
+
+	lines := make([]string, 0, len(v)+1)
+	lines = append(lines, fmt.Sprintf("%v problems found", len(v)))
+	for _, err := range v {
+		lines = append(lines, "- "+err.Error())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Validate checks that the commands of each module only reference
+// the properties defined in the module.
+// Only the properties with string values are exposed to commands.
+// All errors found are reported as ValidationErrors wrapped in the
+// returned error.
+func (l Modules) Validate() error {
+	return l.ValidateWithOptions(&ValidateOptions{})
+}
+
+// ValidateWithOptions validates modules with the specified options.
+// See Validate for the list of checks.
+func (l Modules) ValidateWithOptions(options *ValidateOptions) error {
+	errs := make(ValidationErrors, 0)
+	for _, m := range l {
+		defined := make(map[string]bool)
+		for k, v := range m.Properties() {
+			if _, ok := v.(string); ok {
+				defined[strings.ToUpper(k)] = true
+			}
+		}
+
+		for _, c := range m.commandLines() {
+			for _, match := range propertyReference.FindAllStringSubmatch(c.line, -1) {
+				if !defined[strings.ToUpper(match[1])] {
+					err := e.NewErrorf(ErrClassUser, msgUndefinedProperty, m.Name(), match[1], c.name)
+					if options.FailFast {
+						return err
+					}
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return e.Wrap(ErrClassUser, errs)
+	}
+
+	return nil
+}
+
+// ValidateProposedSpec checks whether adding the specified spec
+// contents to the repository would produce a valid module graph
+// without committing it. Path is the repository relative directory
+// of the module or the path to its spec file (e.g. app-a/.mbt.json).
+// Existing module in that directory (if any) is replaced by the
+// proposed one. Therefore, existing should contain all the modules
+// in the repository (e.g. modules discovered in the head commit).
+// Errors such as duplicate names, cycles and dependencies on unknown
+// modules are reported as they would be during discovery.
+func ValidateProposedSpec(existing Modules, spec []byte, p string) error {
+	dir, file := normalizeDependencyPath(p), configFileName
+	if isConfigFile(path.Base(dir)) {
+		dir, file = normalizeDependencyPath(path.Dir(dir)), path.Base(dir)
+	}
+
+	s, err := newSpecFromFile(file, spec)
+	if err != nil {
+		return e.Wrapf(ErrClassUser, err, msgInvalidProposedSpec, dir)
+	}
+
+	if s.Name == "" {
+		return e.NewErrorf(ErrClassUser, msgMissingModuleName, dir)
+	}
+
+	set := make(moduleMetadataSet, 0, len(existing)+1)
+	for _, m := range existing {
+		if m.Path() != dir {
+			set = append(set, m.metadata)
+		}
+	}
+	set = append(set, newModuleMetadata(dir, "", s, nil))
+
+	_, err = toModules(set)
+	return err
+}
+
+type commandLine struct {
+	name string
+	line string
+}
+
+// commandLines returns the build and user defined commands of
+// the module along with their arguments in a stable order.
+func (a *Module) commandLines() []*commandLine {
+	lines := make([]*commandLine, 0)
+
+	build := make([]string, 0, len(a.Build()))
+	for k := range a.Build() {
+		build = append(build, k)
+	}
+	sort.Strings(build)
+
+	for _, k := range build {
+		c := a.Build()[k]
+		if c != nil {
+			lines = append(lines, &commandLine{name: "build." + k, line: strings.Join(append([]string{c.Cmd}, c.Args...), " ")})
+		}
+	}
+
+	commands := make([]string, 0, len(a.UserCommands()))
+	for k := range a.UserCommands() {
+		commands = append(commands, k)
+	}
+	sort.Strings(commands)
+
+	for _, k := range commands {
+		c := a.UserCommands()[k]
+		if c != nil {
+			lines = append(lines, &commandLine{name: "commands." + k, line: strings.Join(append([]string{c.Cmd}, c.Args...), " ")})
+		}
+	}
+
+	return lines
+}
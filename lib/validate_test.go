@@ -0,0 +1,161 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateModulesWithDefinedProperties(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{
+			Name:       "app-a",
+			Build:      map[string]*Cmd{"default": {Cmd: "echo", Args: []string{"$MBT_MODULE_PROPERTY_FOO"}}},
+			Commands:   map[string]*UserCmd{"deploy": {Cmd: "deploy.cmd", Args: []string{"%MBT_MODULE_PROPERTY_foo%"}}},
+			Properties: map[string]interface{}{"foo": "bar"},
+		}, nil),
+	})
+	check(t, err)
+
+	assert.NoError(t, mods.Validate())
+}
+
+func TestValidateModulesWithUndefinedProperty(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{
+			Name:       "app-a",
+			Build:      map[string]*Cmd{"default": {Cmd: "echo", Args: []string{"${MBT_MODULE_PROPERTY_FO}"}}},
+			Properties: map[string]interface{}{"foo": "bar"},
+		}, nil),
+	})
+	check(t, err)
+
+	err = mods.Validate()
+
+	assert.EqualError(t, err, fmt.Sprintf(msgUndefinedProperty, "app-a", "FO", "build.default"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestValidateModulesWithNonStringProperty(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{
+			Name:       "app-a",
+			Build:      map[string]*Cmd{"linux": {Cmd: "echo $MBT_MODULE_PROPERTY_PORTS"}},
+			Properties: map[string]interface{}{"ports": []interface{}{80, 443}},
+		}, nil),
+	})
+	check(t, err)
+
+	assert.EqualError(t, mods.Validate(), fmt.Sprintf(msgUndefinedProperty, "app-a", "PORTS", "build.linux"))
+}
+
+func TestValidateCollectsAllErrors(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{
+			Name:  "app-a",
+			Build: map[string]*Cmd{"default": {Cmd: "echo", Args: []string{"$MBT_MODULE_PROPERTY_FOO", "$MBT_MODULE_PROPERTY_BAR"}}},
+		}, nil),
+		newModuleMetadata("app-b", "b", &Spec{
+			Name:  "app-b",
+			Build: map[string]*Cmd{"default": {Cmd: "echo", Args: []string{"$MBT_MODULE_PROPERTY_BAZ"}}},
+		}, nil),
+	})
+	check(t, err)
+
+	err = mods.Validate()
+
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+	errs := (err.(*e.E)).InnerError().(ValidationErrors)
+	assert.Len(t, errs, 3)
+	assert.EqualError(t, errs[0], fmt.Sprintf(msgUndefinedProperty, "app-a", "FOO", "build.default"))
+	assert.EqualError(t, errs[1], fmt.Sprintf(msgUndefinedProperty, "app-a", "BAR", "build.default"))
+	assert.EqualError(t, errs[2], fmt.Sprintf(msgUndefinedProperty, "app-b", "BAZ", "build.default"))
+	assert.Contains(t, err.Error(), "3 problems found")
+
+	err = mods.ValidateWithOptions(&ValidateOptions{FailFast: true})
+
+	assert.EqualError(t, err, fmt.Sprintf(msgUndefinedProperty, "app-a", "FOO", "build.default"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestValidateProposedSpec(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-b"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil),
+	})
+	check(t, err)
+
+	// New module
+	assert.NoError(t, ValidateProposedSpec(mods, []byte("name: app-c\ndependencies: [app-a]"), "app-c"))
+	// Modified module
+	assert.NoError(t, ValidateProposedSpec(mods, []byte("name: app-b\nproperties:\n  foo: bar"), "app-b/"))
+	// Spec file path
+	assert.NoError(t, ValidateProposedSpec(mods, []byte(`{"name": "app-c"}`), "app-c/.mbt.json"))
+	// Renamed module
+	assert.Error(t, ValidateProposedSpec(mods, []byte("name: app-x"), "app-b"))
+}
+
+func TestValidateProposedSpecWithCycle(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-b"}}, nil),
+		newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil),
+	})
+	check(t, err)
+
+	err = ValidateProposedSpec(mods, []byte("name: app-b\ndependencies: [app-a]"), "app-b")
+
+	assert.EqualError(t, err, fmt.Sprintf(msgCyclicDependency, "app-a -> app-b -> app-a"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestValidateProposedSpecWithDuplicateName(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a"}, nil),
+	})
+	check(t, err)
+
+	err = ValidateProposedSpec(mods, []byte("name: app-a"), "app-b")
+
+	assert.EqualError(t, err, fmt.Sprintf(msgDuplicateModuleName, "app-a", "app-b", "app-a"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestValidateProposedSpecWithUnknownDependency(t *testing.T) {
+	mods, err := toModules(moduleMetadataSet{
+		newModuleMetadata("app-a", "a", &Spec{Name: "app-a"}, nil),
+	})
+	check(t, err)
+
+	err = ValidateProposedSpec(mods, []byte("name: app-b\ndependencies: [app-x]"), "app-b")
+
+	assert.EqualError(t, err, "dependency not found app-b -> app-x")
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestValidateInvalidProposedSpec(t *testing.T) {
+	err := ValidateProposedSpec(Modules{}, []byte("name: [app-a"), "app-a")
+
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidProposedSpec, "app-a"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+
+	err = ValidateProposedSpec(Modules{}, []byte("dependencies: [app-b]"), "app-a")
+
+	assert.EqualError(t, err, fmt.Sprintf(msgMissingModuleName, "app-a"))
+}
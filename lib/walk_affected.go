@@ -0,0 +1,54 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+func (s *stdSystem) WalkAffected(branch string, callback AffectedWalkCallback) error {
+	head, err := s.Repo.BranchCommit(branch)
+	if err != nil {
+		return err
+	}
+
+	// Consecutive commits share most of their trees, therefore the
+	// modules discovered in the previous commit are reused.
+	var prior Modules
+	return s.Repo.WalkCommits(head, func(commit Commit) error {
+		mods, err := s.Discover.ModulesInCommitSince(commit, prior)
+		if err != nil {
+			return err
+		}
+		prior = mods
+
+		if commit.ParentCount() > 0 {
+			// Changes are calculated against the first parent.
+			deltas, err := s.Repo.Changes(commit)
+			if err != nil {
+				return err
+			}
+
+			mods, err = s.Reducer.Reduce(mods, deltas)
+			if err != nil {
+				return err
+			}
+
+			mods, err = mods.expandRequiredByDependencies()
+			if err != nil {
+				return err
+			}
+		}
+
+		return callback(commit, mods)
+	})
+}
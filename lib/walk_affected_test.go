@@ -0,0 +1,84 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWalkAffected(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{Name: "app-a", Dependencies: []string{"lib-a"}}))
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.InitModule("lib-a"))
+	check(t, repo.Commit("first"))
+
+	check(t, repo.SwitchToBranch("feature"))
+	check(t, repo.WriteContent("lib-a/foo", "hello"))
+	check(t, repo.Commit("second"))
+
+	check(t, repo.SwitchToBranch("master"))
+	check(t, repo.WriteContent("app-b/foo", "hello"))
+	check(t, repo.Commit("third"))
+
+	_, err := repo.SimpleMerge("feature", "master")
+	check(t, err)
+
+	messages := []string{}
+	affected := make(map[string][]string)
+	err = NewWorld(t, ".tmp/repo").System.WalkAffected("master", func(commit Commit, mods Modules) error {
+		message := strings.TrimSpace(commit.Message())
+		messages = append(messages, message)
+		for _, m := range mods {
+			affected[message] = append(affected[message], m.Name())
+		}
+		return nil
+	})
+	check(t, err)
+
+	assert.Len(t, messages, 4)
+	assert.Equal(t, "first", messages[0])
+	assert.Equal(t, "Merged", messages[3])
+	assert.ElementsMatch(t, []string{"app-a", "app-b", "lib-a"}, affected["first"])
+	assert.Equal(t, []string{"lib-a", "app-a"}, affected["second"])
+	assert.Equal(t, []string{"app-b"}, affected["third"])
+	assert.Equal(t, []string{"lib-a", "app-a"}, affected["Merged"])
+}
+
+func TestWalkAffectedStopsOnCallbackError(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+	check(t, repo.WriteContent("app-a/foo", "hello"))
+	check(t, repo.Commit("second"))
+
+	visited := 0
+	err := NewWorld(t, ".tmp/repo").System.WalkAffected("master", func(commit Commit, mods Modules) error {
+		visited++
+		return errors.New("stop")
+	})
+
+	assert.EqualError(t, err, "stop")
+	assert.Equal(t, 1, visited)
+}